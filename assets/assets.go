@@ -0,0 +1,9 @@
+// Package assets embeds static files into the binary so they don't depend on the working directory
+package assets
+
+import _ "embed"
+
+// SapuranLogo is the Sapuran Berperan logo (PNG), the default QR code logo
+//
+//go:embed logo_sapuran.png
+var SapuranLogo []byte
@@ -1,24 +1,41 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"expvar"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/auth"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/branding"
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/config"
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/database"
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/handler"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/mailer"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/markerimage"
 	appMiddleware "github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/middleware"
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/storage"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/util"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/joho/godotenv"
 )
 
+// shutdownTimeout is how long in-flight requests get to finish on shutdown
+const shutdownTimeout = 30 * time.Second
+
 func main() {
+	// Cancelled on SIGINT or SIGTERM to shut down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Load .env file if exists
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
@@ -52,14 +69,15 @@ func main() {
 	jwtManager := auth.NewJWTManager(cfg)
 
 	// Initialize Google Drive service (optional - only if credentials are configured)
-	var gdriveService *storage.GDriveService
+	// imageStorage stays a nil interface when unavailable, never a typed nil pointer
+	var imageStorage storage.Storage
 	if cfg.GDriveCredentialsPath != "" && cfg.GDriveTokenPath != "" && cfg.GDriveFolderID != "" {
-		var err error
-		gdriveService, err = storage.NewGDriveService(cfg.GDriveCredentialsPath, cfg.GDriveTokenPath, cfg.GDriveFolderID)
+		gdriveService, err := storage.NewGDriveService(cfg.GDriveCredentialsPath, cfg.GDriveTokenPath, cfg.GDriveFolderID)
 		if err != nil {
 			log.Printf("Warning: Failed to initialize Google Drive service: %v", err)
 			log.Println("Image uploads will be disabled")
 		} else {
+			imageStorage = gdriveService
 			log.Println("Google Drive service initialized successfully")
 		}
 	} else {
@@ -68,21 +86,137 @@ func main() {
 
 	// Initialize repository and handlers
 	queries := repository.New(db)
-	authHandler := handler.NewAuthHandler(queries, jwtManager)
-	markerHandler := handler.NewMarkerHandler(queries, gdriveService, cfg.DeepLinkBaseURL)
+
+	// Initialize background image processing (only with storage configured)
+	var imageQueue *markerimage.Queue
+	if imageStorage != nil {
+		imageQueue, err = markerimage.NewQueue(queries, imageStorage, markerimage.Config{
+			SpoolDir:    cfg.ImageSpoolDir,
+			Workers:     cfg.ImageWorkers,
+			MaxAttempts: cfg.ImageMaxAttempts,
+			RetryDelay:  cfg.ImageRetryDelay,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize image queue: %v", err)
+		}
+		if err := imageQueue.Start(ctx); err != nil {
+			log.Fatalf("Failed to start image queue: %v", err)
+		}
+	}
+
+	// Load QR branding once; a bad logo or colour should stop startup rather than every QR request
+	brandings, err := branding.Load(cfg.QRBrandingFile, branding.Settings{
+		Logo:       cfg.QRLogo,
+		Foreground: cfg.QRForeground,
+		Background: cfg.QRBackground,
+		Caption:    cfg.QRCaption,
+	})
+	if err != nil {
+		log.Fatalf("Failed to load QR branding: %v", err)
+	}
+
+	if cfg.ShortCodeLength < util.MinShortCodeLength || cfg.ShortCodeLength > util.MaxShortCodeLength {
+		log.Fatalf("SHORT_CODE_LENGTH must be between %d and %d", util.MinShortCodeLength, util.MaxShortCodeLength)
+	}
+
+	appLinks := handler.AppLinksConfig{
+		AndroidPackage:    cfg.AndroidAppPackage,
+		AndroidCertSHA256: cfg.AndroidCertSHA256,
+		AppleAppIDs:       cfg.AppleAppIDs,
+		AppleAppStoreID:   cfg.AppleAppStoreID,
+	}
+
+	// Account emails go through SMTP when configured, otherwise they are only logged
+	var accountMailer mailer.Mailer
+	if cfg.SMTPHost != "" {
+		if cfg.SMTPFrom == "" {
+			log.Fatal("SMTP_FROM is required when SMTP_HOST is set")
+		}
+		accountMailer = mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		})
+	} else {
+		log.Println("SMTP not configured, account emails will be logged")
+	}
+
+	authHandler := handler.NewAuthHandler(db, jwtManager, handler.AuthHandlerConfig{
+		Mailer:                  accountMailer,
+		PasswordResetURL:        cfg.PasswordResetURL,
+		PasswordResetExpiry:     cfg.PasswordResetExpiry,
+		EmailVerificationURL:    cfg.EmailVerificationURL,
+		EmailVerificationExpiry: cfg.EmailVerificationExpiry,
+		AccountLockout: auth.LockoutPolicy{
+			MaxFailures: cfg.LoginMaxFailures,
+			BaseDelay:   cfg.LoginLockoutBase,
+			MaxDelay:    cfg.LoginLockoutMax,
+		},
+		IPLockout: auth.LockoutPolicy{
+			MaxFailures: cfg.LoginIPMaxFailures,
+			BaseDelay:   cfg.LoginLockoutBase,
+			MaxDelay:    cfg.LoginLockoutMax,
+		},
+		LoginFailureWindow: cfg.LoginFailureWindow,
+	})
+	adminHandler := handler.NewAdminHandler(queries)
+
+	// Promote the configured users, so a fresh install can get its first admin
+	promoted, err := adminHandler.PromoteAdmins(ctx, cfg.AdminEmails)
+	if err != nil {
+		log.Fatalf("Failed to promote ADMIN_EMAILS: %v", err)
+	}
+	for _, email := range promoted {
+		log.Printf("Promoted %s to admin (ADMIN_EMAILS)", email)
+	}
+
+	appLinksHandler := handler.NewAppLinksHandler(appLinks)
+	markerHandler := handler.NewMarkerHandler(queries, handler.MarkerHandlerConfig{
+		Storage:         imageStorage,
+		ImageQueue:      imageQueue,
+		URLSigner:       auth.NewURLSigner(cfg.URLSigningSecret, cfg.SignedURLExpiry),
+		DeepLinkBaseURL: cfg.DeepLinkBaseURL,
+		APIBaseURL:      cfg.APIBaseURL,
+		Brandings:       brandings,
+		AppLinks:        appLinks,
+		ScanHashKey:     []byte(cfg.ScanIPHashSecret),
+		ShortCodeLength: cfg.ShortCodeLength,
+	})
+
+	// Unverified users are read-only unless REQUIRE_EMAIL_VERIFICATION=false
+	requireVerifiedEmail := appMiddleware.RequireVerifiedEmail(cfg.RequireEmailVerification)
+
+	// Forwarding headers are only believed from the configured proxies
+	trustedProxies, err := util.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	// Rate limits per route group, each with its own buckets. A limit counts per user
+	// when it runs after authentication and per client IP for anonymous callers.
+	rateLimit := func(requests int) func(http.Handler) http.Handler {
+		return appMiddleware.RateLimiter(appMiddleware.RateLimit{Requests: requests, Window: cfg.RateLimitWindow})
+	}
+	globalRateLimit := rateLimit(cfg.RateLimitGlobal)
+	authRateLimit := rateLimit(cfg.RateLimitAuth)
+	shortCodeRateLimit := rateLimit(cfg.RateLimitShortCode)
+	qrRateLimit := rateLimit(cfg.RateLimitQR)
 
 	// Initialize router
 	r := chi.NewRouter()
 
 	// Middleware
+	r.Use(appMiddleware.RealIP(trustedProxies))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -93,39 +227,110 @@ func main() {
 		w.Write([]byte("OK"))
 	})
 
+	// Runtime metrics (expvar), such as short code collisions
+	r.With(appMiddleware.JWTAuth(jwtManager), appMiddleware.RequireRole(auth.RoleAdmin)).Get("/debug/vars", expvar.Handler().ServeHTTP)
+
+	// Marker pages - QR codes link here, opening the app when installed
+	r.With(shortCodeRateLimit).Get("/marker/{shortCode}", markerHandler.ShowPage)
+	r.With(shortCodeRateLimit).Get("/m/{shortCode}", markerHandler.ShowPage)
+
+	// App link association files
+	r.Get("/.well-known/assetlinks.json", appLinksHandler.AssetLinks)
+	r.Get("/.well-known/apple-app-site-association", appLinksHandler.AppleAppSiteAssociation)
+	r.Get("/apple-app-site-association", appLinksHandler.AppleAppSiteAssociation)
+
 	// API routes
 	r.Route("/v1", func(r chi.Router) {
+		// Optional authentication for every route, so the global limit counts per user
+		r.Use(appMiddleware.OptionalJWTAuth(jwtManager), globalRateLimit)
+
 		r.Route("/auth", func(r chi.Router) {
-			// Public routes
-			r.Post("/register", authHandler.Register)
-			r.Post("/login", authHandler.Login)
-			r.Post("/refresh", authHandler.Refresh)
+			// Public routes, limited per client IP against guessing and mail flooding
+			r.Group(func(r chi.Router) {
+				r.Use(authRateLimit)
+				r.Post("/register", authHandler.Register)
+				r.Post("/login", authHandler.Login)
+				r.Post("/refresh", authHandler.Refresh)
+				r.Post("/forgot-password", authHandler.ForgotPassword)
+				r.Post("/reset-password", authHandler.ResetPassword)
+				r.Post("/verify-email", authHandler.VerifyEmail)
+				r.Post("/logout/device", authHandler.LogoutDevice)
+			})
 
 			// Protected routes
 			r.Group(func(r chi.Router) {
 				r.Use(appMiddleware.JWTAuth(jwtManager))
 				r.Get("/me", authHandler.GetMe)
+				r.Patch("/me", authHandler.UpdateMe)
+				r.Post("/change-password", authHandler.ChangePassword)
 				r.Post("/logout", authHandler.Logout)
+				r.Post("/resend-verification", authHandler.ResendVerification)
+				r.Get("/sessions", authHandler.ListSessions)
+				r.Delete("/sessions/{id}", authHandler.RevokeSession)
 			})
 		})
 
 		// Marker routes
 		r.Route("/markers", func(r chi.Router) {
-			// Public route - for QR code scanning
-			r.Get("/code/{shortCode}", markerHandler.GetByShortCode)
+			// Public route - for QR code scanning, full details with a bearer token
+			r.With(shortCodeRateLimit).Get("/code/{shortCode}", markerHandler.GetByShortCode)
+
+			// Image route - bearer token or signed link from a marker response
+			r.Get("/{id}/image", markerHandler.GetImage)
 
 			// Protected routes
 			r.Group(func(r chi.Router) {
 				r.Use(appMiddleware.JWTAuth(jwtManager))
-				r.Get("/", markerHandler.List)
-				r.Get("/paginated", markerHandler.ListPaginated)
-				r.Post("/", markerHandler.Create)
-				r.Get("/{id}", markerHandler.GetByID)
-				r.Get("/{id}/qr", markerHandler.GenerateQR)
-				r.Put("/{id}", markerHandler.Update)
-				r.Delete("/{id}", markerHandler.Delete)
+
+				// Read-only routes, open to every role
+				r.Group(func(r chi.Router) {
+					r.Use(appMiddleware.RequirePermission(auth.PermMarkersRead))
+					r.Get("/", markerHandler.List)
+					r.Get("/paginated", markerHandler.ListPaginated)
+					r.With(qrRateLimit).Post("/labels", markerHandler.GenerateLabels)
+					r.With(qrRateLimit).Get("/qr-archive", markerHandler.GenerateQRArchive)
+					r.Get("/scans/stats", markerHandler.ScanStats)
+					r.Get("/{id}", markerHandler.GetByID)
+					r.With(qrRateLimit).Get("/{id}/qr", markerHandler.GenerateQR)
+					r.Get("/{id}/scans/stats", markerHandler.MarkerScanStats)
+				})
+
+				// Write routes, closed to users who haven't verified their email
+				r.Group(func(r chi.Router) {
+					r.Use(requireVerifiedEmail)
+					r.With(appMiddleware.RequirePermission(auth.PermMarkersCreate)).Post("/", markerHandler.Create)
+
+					// Surveyors may only change their own markers, checked by the handlers
+					r.Group(func(r chi.Router) {
+						r.Use(appMiddleware.RequirePermission(auth.PermMarkersEditOwn))
+						r.Put("/{id}", markerHandler.Update)
+						r.Delete("/{id}", markerHandler.Delete)
+						r.Post("/{id}/short-code/rotate", markerHandler.RotateShortCode)
+					})
+				})
+			})
+		})
+
+		// Admin routes
+		r.Route("/admin/users", func(r chi.Router) {
+			r.Use(appMiddleware.JWTAuth(jwtManager))
+			r.Use(appMiddleware.RequirePermission(auth.PermUsersManage))
+			r.Get("/", adminHandler.ListUsers)
+			r.Get("/{id}", adminHandler.GetUser)
+			r.Group(func(r chi.Router) {
+				r.Use(requireVerifiedEmail)
+				r.Put("/{id}/role", adminHandler.UpdateUserRole)
+				r.Post("/{id}/disable", adminHandler.DisableUser)
+				r.Post("/{id}/enable", adminHandler.EnableUser)
+				r.Post("/{id}/logout", adminHandler.LogoutUser)
 			})
 		})
+		r.Route("/admin/lockouts", func(r chi.Router) {
+			r.Use(appMiddleware.JWTAuth(jwtManager))
+			r.Use(appMiddleware.RequirePermission(auth.PermUsersManage))
+			r.Get("/", adminHandler.ListLoginLockouts)
+			r.With(requireVerifiedEmail).Delete("/{scope}/{subject}", adminHandler.ClearLoginLockout)
+		})
 	})
 
 	// Start server
@@ -134,9 +339,28 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s (env: %s)", port, cfg.Environment)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
-		os.Exit(1)
+	server := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		log.Printf("Server starting on port %s (env: %s)", port, cfg.Environment)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed to start: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown failed: %v", err)
+	}
+
+	// Let account emails and started image jobs finish; waiting jobs stay spooled
+	authHandler.Wait()
+	if imageQueue != nil {
+		imageQueue.Wait()
 	}
+	log.Println("Server stopped")
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/config"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/database"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/reconcile"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/storage"
+	"github.com/joho/godotenv"
+)
+
+// reconcile-images finds Drive files no marker refers to, marker image URLs
+// whose file is gone and files still shared with anyone who has the link. It
+// only reports by default; pass -dry-run=false to delete orphaned files, clear
+// dangling references and make the shared files private.
+func main() {
+	dryRun := flag.Bool("dry-run", true, "report orphans and dangling references without changing anything")
+	minAge := flag.Duration("min-age", 24*time.Hour, "ignore unreferenced files younger than this")
+	flag.Parse()
+
+	// Load .env file if exists
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg := config.Load()
+
+	if cfg.GDriveCredentialsPath == "" || cfg.GDriveTokenPath == "" || cfg.GDriveFolderID == "" {
+		log.Fatal("Google Drive credentials are not configured")
+	}
+
+	db, err := database.Connect(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	gdriveService, err := storage.NewGDriveService(cfg.GDriveCredentialsPath, cfg.GDriveTokenPath, cfg.GDriveFolderID)
+	if err != nil {
+		log.Fatalf("Failed to initialize Google Drive service: %v", err)
+	}
+
+	report, err := reconcile.Run(context.Background(), gdriveService, repository.New(db), reconcile.Options{
+		DryRun: *dryRun,
+		MinAge: *minAge,
+	})
+	if err != nil {
+		log.Fatalf("Reconciliation failed: %v", err)
+	}
+
+	log.Printf("Scanned %d files and %d image references", report.FilesScanned, report.RefsScanned)
+	for _, f := range report.Orphans {
+		log.Printf("Orphaned file: %s (%s, created %s)", f.ID, f.Name, f.CreatedTime.Format(time.RFC3339))
+	}
+	for _, ref := range report.Dangling {
+		log.Printf("Dangling reference: marker %s -> %s", ref.MarkerID, ref.URL)
+	}
+	for _, f := range report.Public {
+		log.Printf("Public file: %s (%s)", f.ID, f.Name)
+	}
+	log.Printf("%d orphaned files, %d dangling references, %d public files, %d recent files skipped",
+		len(report.Orphans), len(report.Dangling), len(report.Public), report.SkippedRecent)
+
+	if *dryRun {
+		log.Println("Dry run: no changes made (pass -dry-run=false to apply)")
+	} else {
+		log.Printf("Deleted %d files, cleared %d references, made %d files private",
+			report.Deleted, report.Cleared, report.MadePrivate)
+	}
+
+	for _, err := range report.Errors {
+		log.Printf("Error: %v", err)
+	}
+	if len(report.Errors) > 0 {
+		os.Exit(1)
+	}
+}
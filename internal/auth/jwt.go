@@ -23,6 +23,12 @@ type Claims struct {
 	UserID uuid.UUID `json:"user_id"`
 	Email  string    `json:"email"`
 	Role   string    `json:"role"`
+	// EmailUnverified marks users who haven't verified their email yet. It is only set
+	// when true, so tokens issued before verification existed count as verified.
+	EmailUnverified bool `json:"email_unverified,omitempty"`
+	// SessionID is the ID of the refresh token the access token was issued with.
+	// It is uuid.Nil in tokens issued before sessions were tracked.
+	SessionID uuid.UUID `json:"sid"`
 	jwt.RegisteredClaims
 }
 
@@ -42,13 +48,16 @@ func NewJWTManager(cfg *config.Config) *JWTManager {
 	}
 }
 
-// GenerateAccessToken creates a new access token for a user
-func (m *JWTManager) GenerateAccessToken(userID uuid.UUID, email, role string) (string, error) {
+// GenerateAccessToken creates a new access token for a user, for the session of the
+// refresh token with ID sessionID
+func (m *JWTManager) GenerateAccessToken(userID uuid.UUID, email, role string, emailVerified bool, sessionID uuid.UUID) (string, error) {
 	now := time.Now()
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:          userID,
+		Email:           email,
+		Role:            role,
+		EmailUnverified: !emailVerified,
+		SessionID:       sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(m.accessTokenExpiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -65,6 +74,13 @@ func (m *JWTManager) GenerateAccessToken(userID uuid.UUID, email, role string) (
 // GenerateRefreshToken creates a cryptographically secure random refresh token
 // Returns: (raw_token, token_hash, expires_at, error)
 func (m *JWTManager) GenerateRefreshToken() (string, string, time.Time, error) {
+	return GenerateOneTimeToken(m.refreshTokenExpiry)
+}
+
+// GenerateOneTimeToken creates a cryptographically secure random token that is stored
+// only as its hash, such as refresh tokens and password reset tokens
+// Returns: (raw_token, token_hash, expires_at, error)
+func GenerateOneTimeToken(expiry time.Duration) (string, string, time.Time, error) {
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
 		return "", "", time.Time{}, err
@@ -74,13 +90,18 @@ func (m *JWTManager) GenerateRefreshToken() (string, string, time.Time, error) {
 	hash := sha256.Sum256([]byte(rawToken))
 	tokenHash := hex.EncodeToString(hash[:])
 
-	expiresAt := time.Now().Add(m.refreshTokenExpiry)
+	expiresAt := time.Now().Add(expiry)
 
 	return rawToken, tokenHash, expiresAt, nil
 }
 
 // HashRefreshToken creates a SHA-256 hash of a refresh token
 func HashRefreshToken(rawToken string) string {
+	return HashOneTimeToken(rawToken)
+}
+
+// HashOneTimeToken creates a SHA-256 hash of a token from GenerateOneTimeToken
+func HashOneTimeToken(rawToken string) string {
 	hash := sha256.Sum256([]byte(rawToken))
 	return hex.EncodeToString(hash[:])
 }
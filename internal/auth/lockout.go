@@ -0,0 +1,35 @@
+package auth
+
+import "time"
+
+// LockoutPolicy decides how long logins are blocked after consecutive failures
+type LockoutPolicy struct {
+	// MaxFailures is how many failures are allowed before the first lockout, zero disables lockouts
+	MaxFailures int
+	// BaseDelay is the first lockout, doubling with every further failure
+	BaseDelay time.Duration
+	// MaxDelay caps the lockout, zero keeps every lockout at BaseDelay
+	MaxDelay time.Duration
+}
+
+// Enabled reports whether the policy locks logins at all
+func (p LockoutPolicy) Enabled() bool {
+	return p.MaxFailures > 0 && p.BaseDelay > 0
+}
+
+// Lockout returns how long logins are blocked after the given number of consecutive
+// failures, zero while below MaxFailures
+func (p LockoutPolicy) Lockout(failures int) time.Duration {
+	if !p.Enabled() || failures < p.MaxFailures {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for range failures - p.MaxFailures {
+		if delay >= p.MaxDelay {
+			break
+		}
+		delay *= 2
+	}
+	return max(min(delay, p.MaxDelay), p.BaseDelay)
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicy_Lockout(t *testing.T) {
+	policy := LockoutPolicy{MaxFailures: 3, BaseDelay: time.Minute, MaxDelay: 10 * time.Minute}
+
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{failures: 0, expected: 0},
+		{failures: 2, expected: 0},
+		{failures: 3, expected: time.Minute},
+		{failures: 4, expected: 2 * time.Minute},
+		{failures: 6, expected: 8 * time.Minute},
+		{failures: 7, expected: 10 * time.Minute},
+		{failures: 1000, expected: 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := policy.Lockout(tt.failures); got != tt.expected {
+			t.Errorf("Lockout(%d) = %v, expected %v", tt.failures, got, tt.expected)
+		}
+	}
+}
+
+func TestLockoutPolicy_Disabled(t *testing.T) {
+	if (LockoutPolicy{}).Enabled() {
+		t.Error("expected zero policy to be disabled")
+	}
+	if got := (LockoutPolicy{BaseDelay: time.Minute}).Lockout(100); got != 0 {
+		t.Errorf("expected no lockout without MaxFailures, got %v", got)
+	}
+	if got := (LockoutPolicy{MaxFailures: 1, BaseDelay: time.Minute}).Lockout(1000); got != time.Minute {
+		t.Errorf("expected BaseDelay without MaxDelay, got %v", got)
+	}
+}
@@ -0,0 +1,67 @@
+package auth
+
+// User roles, stored in users.role and carried in the access token
+const (
+	RoleAdmin    = "admin"
+	RoleEditor   = "editor"
+	RoleSurveyor = "surveyor"
+	RoleViewer   = "viewer"
+)
+
+// DefaultRole is the role of newly registered users
+const DefaultRole = RoleSurveyor
+
+// legacyRoleUser is the role every user had before roles were introduced.
+// It had surveyor rights, so tokens issued before the migration keep working.
+const legacyRoleUser = "user"
+
+// Permission is an action a role may be allowed to perform
+type Permission string
+
+const (
+	// PermMarkersRead allows listing and viewing markers, QR codes, labels and scan statistics
+	PermMarkersRead Permission = "markers:read"
+	// PermMarkersCreate allows creating markers
+	PermMarkersCreate Permission = "markers:create"
+	// PermMarkersEditOwn allows updating, deleting and rotating the short code of markers the user created
+	PermMarkersEditOwn Permission = "markers:edit_own"
+	// PermMarkersEditAll allows updating, deleting and rotating the short code of any marker
+	PermMarkersEditAll Permission = "markers:edit_all"
+	// PermUsersManage allows managing users and viewing runtime metrics
+	PermUsersManage Permission = "users:manage"
+)
+
+// rolePermissions is the permission matrix. Roles not listed have no permissions.
+var rolePermissions = map[string][]Permission{
+	RoleAdmin:    {PermMarkersRead, PermMarkersCreate, PermMarkersEditOwn, PermMarkersEditAll, PermUsersManage},
+	RoleEditor:   {PermMarkersRead, PermMarkersCreate, PermMarkersEditOwn, PermMarkersEditAll},
+	RoleSurveyor: {PermMarkersRead, PermMarkersCreate, PermMarkersEditOwn},
+	RoleViewer:   {PermMarkersRead},
+}
+
+// Roles lists every role, from most to least privileged
+var Roles = []string{RoleAdmin, RoleEditor, RoleSurveyor, RoleViewer}
+
+// ValidRole reports whether role is one of Roles
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether role grants the permission
+func HasPermission(role string, permission Permission) bool {
+	if role == legacyRoleUser {
+		role = RoleSurveyor
+	}
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether the token's role grants the permission
+func (c *Claims) HasPermission(permission Permission) bool {
+	return HasPermission(c.Role, permission)
+}
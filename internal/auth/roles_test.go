@@ -0,0 +1,46 @@
+package auth
+
+import "testing"
+
+func TestHasPermission(t *testing.T) {
+	tests := []struct {
+		role       string
+		permission Permission
+		expected   bool
+	}{
+		{RoleAdmin, PermUsersManage, true},
+		{RoleAdmin, PermMarkersEditAll, true},
+		{RoleEditor, PermMarkersEditAll, true},
+		{RoleEditor, PermUsersManage, false},
+		{RoleSurveyor, PermMarkersCreate, true},
+		{RoleSurveyor, PermMarkersEditOwn, true},
+		{RoleSurveyor, PermMarkersEditAll, false},
+		{RoleViewer, PermMarkersRead, true},
+		{RoleViewer, PermMarkersCreate, false},
+		{RoleViewer, PermMarkersEditOwn, false},
+		// Tokens issued before roles were introduced have surveyor rights
+		{"user", PermMarkersEditOwn, true},
+		{"user", PermMarkersEditAll, false},
+		{"", PermMarkersRead, false},
+		{"superuser", PermMarkersRead, false},
+	}
+
+	for _, tt := range tests {
+		if got := HasPermission(tt.role, tt.permission); got != tt.expected {
+			t.Errorf("HasPermission(%q, %q) = %v, want %v", tt.role, tt.permission, got, tt.expected)
+		}
+	}
+}
+
+func TestValidRole(t *testing.T) {
+	for _, role := range Roles {
+		if !ValidRole(role) {
+			t.Errorf("expected %q to be valid", role)
+		}
+	}
+	for _, role := range []string{"", "user", "Admin", "owner"} {
+		if ValidRole(role) {
+			t.Errorf("expected %q to be invalid", role)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestURLSigner_SignAndVerify(t *testing.T) {
+	signer := NewURLSigner("test-secret", time.Hour)
+
+	expires, sig := signer.Sign("marker-image:abc:thumbnail")
+	expiresParam := strconv.FormatInt(expires, 10)
+
+	if !signer.Verify("marker-image:abc:thumbnail", expiresParam, sig) {
+		t.Error("expected signature to be valid")
+	}
+	if signer.Verify("marker-image:abc:original", expiresParam, sig) {
+		t.Error("expected signature for a different resource to be rejected")
+	}
+	if signer.Verify("marker-image:abc:thumbnail", strconv.FormatInt(expires+60, 10), sig) {
+		t.Error("expected tampered expiry to be rejected")
+	}
+	if signer.Verify("marker-image:abc:thumbnail", "not-a-number", sig) {
+		t.Error("expected malformed expiry to be rejected")
+	}
+
+	other := NewURLSigner("other-secret", time.Hour)
+	if other.Verify("marker-image:abc:thumbnail", expiresParam, sig) {
+		t.Error("expected signature from another secret to be rejected")
+	}
+}
+
+func TestURLSigner_RoundsExpiry(t *testing.T) {
+	signer := NewURLSigner("test-secret", time.Hour)
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	first, firstSig := signer.signAt("marker-image:abc:thumbnail", start.Add(time.Second))
+	second, secondSig := signer.signAt("marker-image:abc:thumbnail", start.Add(29*time.Minute))
+	if first != second || firstSig != secondSig {
+		t.Errorf("expected links signed in the same half hour to match, got %d and %d", first, second)
+	}
+	if want := start.Add(90 * time.Minute).Unix(); first != want {
+		t.Errorf("expected expiry rounded up to %d, got %d", want, first)
+	}
+
+	// The next period gets a later expiry
+	next, _ := signer.signAt("marker-image:abc:thumbnail", start.Add(31*time.Minute))
+	if next != first+int64((30*time.Minute).Seconds()) {
+		t.Errorf("expected the next period to expire half an hour later, got %d", next)
+	}
+}
+
+func TestURLSigner_Expired(t *testing.T) {
+	signer := NewURLSigner("test-secret", -time.Minute)
+
+	expires, sig := signer.Sign("marker-image:abc:thumbnail")
+	if signer.Verify("marker-image:abc:thumbnail", strconv.FormatInt(expires, 10), sig) {
+		t.Error("expected expired signature to be rejected")
+	}
+}
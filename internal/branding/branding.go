@@ -0,0 +1,218 @@
+// Package branding loads the logo, colours and caption placed on QR codes.
+// Brandings are loaded once at startup, either from a JSON file holding several
+// named brandings or from environment settings describing a single one.
+package branding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/assets"
+)
+
+// DefaultName names the branding built from environment settings
+const DefaultName = "default"
+
+// BuiltinLogo selects the embedded Sapuran Berperan logo
+const BuiltinLogo = "builtin"
+
+// NoLogo disables the logo
+const NoLogo = "none"
+
+// minContrast is the lowest foreground/background contrast ratio accepted, so codes stay scannable
+const minContrast = 3.0
+
+// Branding is the styling applied to QR codes
+type Branding struct {
+	Name       string
+	Logo       image.Image // nil for no logo
+	Foreground color.Color
+	Background color.Color
+	Caption    string // rendered under the code, empty for none
+}
+
+// Set is the collection of configured brandings
+type Set struct {
+	defaultName string
+	brandings   map[string]*Branding
+}
+
+// Settings describe a branding before its logo and colours are resolved.
+// Logo is BuiltinLogo, NoLogo, or the path to a PNG or JPEG file.
+type Settings struct {
+	Name       string `json:"name"`
+	Logo       string `json:"logo"`
+	Foreground string `json:"foreground"`
+	Background string `json:"background"`
+	Caption    string `json:"caption"`
+}
+
+// file is the layout of a branding file
+type file struct {
+	Default   string     `json:"default"`
+	Brandings []Settings `json:"brandings"`
+}
+
+// Load reads brandings from a JSON file, or builds a single default branding from
+// fallback when path is empty. Relative logo paths in the file are resolved
+// against the file's directory.
+func Load(path string, fallback Settings) (*Set, error) {
+	if path == "" {
+		fallback.Name = DefaultName
+		b, err := resolve(fallback, "")
+		if err != nil {
+			return nil, err
+		}
+		return &Set{defaultName: DefaultName, brandings: map[string]*Branding{DefaultName: b}}, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read branding file: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse branding file: %w", err)
+	}
+	if len(f.Brandings) == 0 {
+		return nil, errors.New("branding file defines no brandings")
+	}
+
+	set := &Set{defaultName: f.Default, brandings: make(map[string]*Branding, len(f.Brandings))}
+	for _, s := range f.Brandings {
+		if s.Name == "" {
+			return nil, errors.New("branding without a name")
+		}
+		if _, exists := set.brandings[s.Name]; exists {
+			return nil, fmt.Errorf("duplicate branding %q", s.Name)
+		}
+		b, err := resolve(s, filepath.Dir(path))
+		if err != nil {
+			return nil, err
+		}
+		set.brandings[s.Name] = b
+	}
+
+	if set.defaultName == "" {
+		set.defaultName = f.Brandings[0].Name
+	}
+	if _, ok := set.brandings[set.defaultName]; !ok {
+		return nil, fmt.Errorf("default branding %q is not defined", set.defaultName)
+	}
+	return set, nil
+}
+
+// Get returns a branding by name; an empty name returns the default branding
+func (s *Set) Get(name string) (*Branding, bool) {
+	if name == "" {
+		name = s.defaultName
+	}
+	b, ok := s.brandings[name]
+	return b, ok
+}
+
+// Names returns the names of all brandings, sorted
+func (s *Set) Names() []string {
+	names := make([]string, 0, len(s.brandings))
+	for name := range s.brandings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolve loads the logo and parses the colours of a branding
+func resolve(s Settings, dir string) (*Branding, error) {
+	b := &Branding{Name: s.Name, Caption: strings.TrimSpace(s.Caption)}
+
+	var err error
+	if b.Foreground, err = ParseColor(s.Foreground, color.Black); err != nil {
+		return nil, fmt.Errorf("branding %q: foreground: %w", s.Name, err)
+	}
+	if b.Background, err = ParseColor(s.Background, color.White); err != nil {
+		return nil, fmt.Errorf("branding %q: background: %w", s.Name, err)
+	}
+	if luminance(b.Foreground) >= luminance(b.Background) || contrast(b.Foreground, b.Background) < minContrast {
+		return nil, fmt.Errorf("branding %q: foreground must be darker than the background with a contrast ratio of at least %.0f:1", s.Name, minContrast)
+	}
+
+	switch s.Logo {
+	case "", BuiltinLogo:
+		b.Logo, err = decodeLogo(assets.SapuranLogo)
+	case NoLogo:
+	default:
+		path := s.Logo
+		if dir != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		var raw []byte
+		if raw, err = os.ReadFile(path); err == nil {
+			b.Logo, err = decodeLogo(raw)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("branding %q: failed to load logo: %w", s.Name, err)
+	}
+
+	return b, nil
+}
+
+// decodeLogo decodes a PNG or JPEG logo
+func decodeLogo(raw []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	return img, err
+}
+
+// ParseColor parses a hex colour (#rgb or #rrggbb), returning def for an empty string
+func ParseColor(s string, def color.Color) (color.Color, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def, nil
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return nil, fmt.Errorf("invalid colour %q, expected #rrggbb", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid colour %q, expected #rrggbb", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xFF}, nil
+}
+
+// luminance returns the relative luminance of a colour (WCAG 2)
+func luminance(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+	channel := func(v uint32) float64 {
+		s := float64(v) / 0xFFFF
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(r) + 0.7152*channel(g) + 0.0722*channel(b)
+}
+
+// contrast returns the contrast ratio between two colours (WCAG 2)
+func contrast(a, b color.Color) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
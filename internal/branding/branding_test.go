@@ -0,0 +1,126 @@
+package branding
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad_Fallback(t *testing.T) {
+	set, err := Load("", Settings{Foreground: "#1b5e20", Caption: "  Sapuran Berperan  "})
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+
+	b, ok := set.Get("")
+	if !ok || b.Name != DefaultName {
+		t.Fatalf("expected the default branding, got %v", b)
+	}
+	if b.Logo == nil {
+		t.Error("expected the builtin logo")
+	}
+	if b.Foreground != (color.RGBA{R: 0x1b, G: 0x5e, B: 0x20, A: 0xFF}) {
+		t.Errorf("unexpected foreground %v", b.Foreground)
+	}
+	if b.Background != color.White {
+		t.Errorf("expected a white background, got %v", b.Background)
+	}
+	if b.Caption != "Sapuran Berperan" {
+		t.Errorf("expected a trimmed caption, got %q", b.Caption)
+	}
+}
+
+func TestLoad_File(t *testing.T) {
+	dir := t.TempDir()
+
+	logo, err := os.Create(filepath.Join(dir, "partner.png"))
+	if err != nil {
+		t.Fatalf("failed to create logo: %v", err)
+	}
+	png.Encode(logo, image.NewRGBA(image.Rect(0, 0, 8, 8)))
+	logo.Close()
+
+	path := filepath.Join(dir, "branding.json")
+	os.WriteFile(path, []byte(`{"default": "partner", "brandings": [
+		{"name": "sapuran"},
+		{"name": "partner", "logo": "partner.png", "foreground": "#003"},
+		{"name": "plain", "logo": "none"}
+	]}`), 0o644)
+
+	set, err := Load(path, Settings{})
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+
+	if names := set.Names(); len(names) != 3 || names[0] != "partner" || names[2] != "sapuran" {
+		t.Errorf("unexpected names %v", names)
+	}
+	b, _ := set.Get("")
+	if b.Name != "partner" || b.Logo == nil || b.Logo.Bounds().Dx() != 8 {
+		t.Errorf("expected the partner logo relative to the file, got %+v", b)
+	}
+	if b, _ := set.Get("plain"); b.Logo != nil {
+		t.Error("expected no logo")
+	}
+	if _, ok := set.Get("unknown"); ok {
+		t.Error("expected unknown branding to be missing")
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+	}{
+		{"bad colour", Settings{Foreground: "green"}},
+		{"inverted", Settings{Foreground: "#ffffff", Background: "#000000"}},
+		{"low contrast", Settings{Foreground: "#999999", Background: "#bbbbbb"}},
+		{"missing logo", Settings{Logo: "missing.png"}},
+	}
+
+	for _, tt := range tests {
+		if _, err := Load("", tt.settings); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"empty.json":     `{"brandings": []}`,
+		"duplicate.json": `{"brandings": [{"name": "a"}, {"name": "a"}]}`,
+		"default.json":   `{"default": "b", "brandings": [{"name": "a"}]}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0o644)
+		if _, err := Load(path, Settings{}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		input string
+		want  color.Color
+	}{
+		{"", color.Black},
+		{"#fff", color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}},
+		{"1B5E20", color.RGBA{R: 0x1b, G: 0x5e, B: 0x20, A: 0xFF}},
+	}
+	for _, tt := range tests {
+		got, err := ParseColor(tt.input, color.Black)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseColor(%q) = %v, %v; want %v", tt.input, got, err, tt.want)
+		}
+	}
+
+	for _, input := range []string{"#ff", "#gggggg", "#1234567"} {
+		if _, err := ParseColor(input, color.Black); err == nil {
+			t.Errorf("ParseColor(%q): expected an error", input)
+		}
+	}
+}
@@ -1,21 +1,66 @@
 package config
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	Environment           string
-	Port                  string
-	DatabaseURL           string
-	JWTSecret             string
-	AccessTokenExpiry     time.Duration
-	RefreshTokenExpiry    time.Duration
-	GDriveCredentialsPath string
-	GDriveTokenPath       string
-	GDriveFolderID        string
-	DeepLinkBaseURL       string
+	Environment              string
+	Port                     string
+	DatabaseURL              string
+	JWTSecret                string
+	AccessTokenExpiry        time.Duration
+	RefreshTokenExpiry       time.Duration
+	GDriveCredentialsPath    string
+	GDriveTokenPath          string
+	GDriveFolderID           string
+	DeepLinkBaseURL          string
+	APIBaseURL               string
+	URLSigningSecret         string
+	SignedURLExpiry          time.Duration
+	ImageSpoolDir            string
+	ImageWorkers             int
+	ImageMaxAttempts         int
+	ImageRetryDelay          time.Duration
+	QRBrandingFile           string
+	QRLogo                   string
+	QRForeground             string
+	QRBackground             string
+	QRCaption                string
+	AndroidAppPackage        string
+	AndroidCertSHA256        []string
+	AppleAppIDs              []string
+	AppleAppStoreID          string
+	ScanIPHashSecret         string
+	ShortCodeLength          int
+	SMTPHost                 string
+	SMTPPort                 int
+	SMTPUsername             string
+	SMTPPassword             string
+	SMTPFrom                 string
+	PasswordResetURL         string
+	PasswordResetExpiry      time.Duration
+	RequireEmailVerification bool
+	EmailVerificationURL     string
+	EmailVerificationExpiry  time.Duration
+	LoginMaxFailures         int
+	LoginIPMaxFailures       int
+	LoginLockoutBase         time.Duration
+	LoginLockoutMax          time.Duration
+	LoginFailureWindow       time.Duration
+	RateLimitWindow          time.Duration
+	RateLimitGlobal          int
+	RateLimitAuth            int
+	RateLimitShortCode       int
+	RateLimitQR              int
+	TrustedProxies           []string
+	AdminEmails              []string
 }
 
 func Load() *Config {
@@ -30,19 +75,85 @@ func Load() *Config {
 
 	accessExpiry := parseDuration(getEnv("ACCESS_TOKEN_EXPIRY", "15m"), 15*time.Minute)
 	refreshExpiry := parseDuration(getEnv("REFRESH_TOKEN_EXPIRY", "168h"), 7*24*time.Hour)
+	signedURLExpiry := parseDuration(getEnv("SIGNED_URL_EXPIRY", "1h"), time.Hour)
+
+	// Without dedicated secrets, image links and scan hashes use keys derived from the
+	// JWT secret, so no two purposes share a key
+	jwtSecret := getEnv("JWT_SECRET", "")
+	urlSigningSecret := getEnv("URL_SIGNING_SECRET", "")
+	if urlSigningSecret == "" {
+		urlSigningSecret = deriveSecret(jwtSecret, "url-signing")
+	}
+	scanHashSecret := getEnv("SCAN_IP_HASH_SECRET", "")
+	if scanHashSecret == "" {
+		scanHashSecret = deriveSecret(jwtSecret, "scan-ip-hash")
+	}
+
+	deepLinkBaseURL := getEnv("DEEP_LINK_BASE_URL", "https://bamboomapper.com")
 
 	return &Config{
-		Environment:           env,
-		Port:                  getEnv("PORT", "8080"),
-		DatabaseURL:           dbURL,
-		JWTSecret:             getEnv("JWT_SECRET", ""),
-		AccessTokenExpiry:     accessExpiry,
-		RefreshTokenExpiry:    refreshExpiry,
-		GDriveCredentialsPath: getEnv("GDRIVE_CREDENTIALS_PATH", ""),
-		GDriveTokenPath:       getEnv("GDRIVE_TOKEN_PATH", ""),
-		GDriveFolderID:        getEnv("GDRIVE_FOLDER_ID", ""),
-		DeepLinkBaseURL:       getEnv("DEEP_LINK_BASE_URL", "https://bamboomapper.com"),
+		Environment:              env,
+		Port:                     getEnv("PORT", "8080"),
+		DatabaseURL:              dbURL,
+		JWTSecret:                jwtSecret,
+		AccessTokenExpiry:        accessExpiry,
+		RefreshTokenExpiry:       refreshExpiry,
+		GDriveCredentialsPath:    getEnv("GDRIVE_CREDENTIALS_PATH", ""),
+		GDriveTokenPath:          getEnv("GDRIVE_TOKEN_PATH", ""),
+		GDriveFolderID:           getEnv("GDRIVE_FOLDER_ID", ""),
+		DeepLinkBaseURL:          deepLinkBaseURL,
+		APIBaseURL:               strings.TrimSuffix(getEnv("API_BASE_URL", ""), "/"),
+		URLSigningSecret:         urlSigningSecret,
+		SignedURLExpiry:          signedURLExpiry,
+		ImageSpoolDir:            getEnv("IMAGE_SPOOL_DIR", "./data/image-spool"),
+		ImageWorkers:             parseInt(getEnv("IMAGE_WORKERS", "2"), 2),
+		ImageMaxAttempts:         parseInt(getEnv("IMAGE_MAX_ATTEMPTS", "5"), 5),
+		ImageRetryDelay:          parseDuration(getEnv("IMAGE_RETRY_DELAY", "5s"), 5*time.Second),
+		QRBrandingFile:           getEnv("QR_BRANDING_FILE", ""),
+		QRLogo:                   getEnv("QR_LOGO", "builtin"),
+		QRForeground:             getEnv("QR_FOREGROUND", "#000000"),
+		QRBackground:             getEnv("QR_BACKGROUND", "#ffffff"),
+		QRCaption:                getEnv("QR_CAPTION", ""),
+		AndroidAppPackage:        getEnv("ANDROID_APP_PACKAGE", ""),
+		AndroidCertSHA256:        parseList(getEnv("ANDROID_CERT_SHA256", "")),
+		AppleAppIDs:              parseList(getEnv("APPLE_APP_IDS", "")),
+		AppleAppStoreID:          getEnv("APPLE_APP_STORE_ID", ""),
+		ScanIPHashSecret:         scanHashSecret,
+		ShortCodeLength:          parseInt(getEnv("SHORT_CODE_LENGTH", "8"), 8),
+		SMTPHost:                 getEnv("SMTP_HOST", ""),
+		SMTPPort:                 parseInt(getEnv("SMTP_PORT", "587"), 587),
+		SMTPUsername:             getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:             getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:                 getEnv("SMTP_FROM", ""),
+		PasswordResetURL:         getEnv("PASSWORD_RESET_URL", strings.TrimSuffix(deepLinkBaseURL, "/")+"/reset-password"),
+		PasswordResetExpiry:      parseDuration(getEnv("PASSWORD_RESET_EXPIRY", "1h"), time.Hour),
+		RequireEmailVerification: parseBool(getEnv("REQUIRE_EMAIL_VERIFICATION", "true"), true),
+		EmailVerificationURL:     getEnv("EMAIL_VERIFICATION_URL", strings.TrimSuffix(deepLinkBaseURL, "/")+"/verify-email"),
+		EmailVerificationExpiry:  parseDuration(getEnv("EMAIL_VERIFICATION_EXPIRY", "48h"), 48*time.Hour),
+		LoginMaxFailures:         parseInt(getEnv("LOGIN_MAX_FAILURES", "5"), 5),
+		LoginIPMaxFailures:       parseInt(getEnv("LOGIN_IP_MAX_FAILURES", "20"), 20),
+		LoginLockoutBase:         parseDuration(getEnv("LOGIN_LOCKOUT_BASE", "1m"), time.Minute),
+		LoginLockoutMax:          parseDuration(getEnv("LOGIN_LOCKOUT_MAX", "30m"), 30*time.Minute),
+		LoginFailureWindow:       parseDuration(getEnv("LOGIN_FAILURE_WINDOW", "1h"), time.Hour),
+		RateLimitWindow:          parseDuration(getEnv("RATE_LIMIT_WINDOW", "1m"), time.Minute),
+		RateLimitGlobal:          parseInt(getEnv("RATE_LIMIT_GLOBAL", "600"), 600),
+		RateLimitAuth:            parseInt(getEnv("RATE_LIMIT_AUTH", "20"), 20),
+		RateLimitShortCode:       parseInt(getEnv("RATE_LIMIT_SHORT_CODE", "60"), 60),
+		RateLimitQR:              parseInt(getEnv("RATE_LIMIT_QR", "30"), 30),
+		TrustedProxies:           parseList(getEnv("TRUSTED_PROXIES", "")),
+		AdminEmails:              parseList(getEnv("ADMIN_EMAILS", "")),
+	}
+}
+
+// deriveSecret derives the key for one purpose from a shared secret, empty when the
+// secret is empty
+func deriveSecret(secret, purpose string) string {
+	if secret == "" {
+		return ""
 	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return hex.EncodeToString(mac.Sum(nil))
 }
 
 func getEnv(key, defaultValue string) string {
@@ -59,3 +170,30 @@ func parseDuration(s string, defaultValue time.Duration) time.Duration {
 	}
 	return d
 }
+
+func parseInt(s string, defaultValue int) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return defaultValue
+	}
+	return n
+}
+
+func parseBool(s string, defaultValue bool) bool {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return defaultValue
+	}
+	return b
+}
+
+// parseList splits a comma-separated value, dropping empty entries
+func parseList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,36 @@
+package config
+
+import "testing"
+
+func TestLoad_DerivesSecretsFromJWTSecret(t *testing.T) {
+	t.Setenv("JWT_SECRET", "jwt-secret")
+	t.Setenv("URL_SIGNING_SECRET", "")
+	t.Setenv("SCAN_IP_HASH_SECRET", "")
+
+	cfg := Load()
+	if cfg.URLSigningSecret == "" || cfg.ScanIPHashSecret == "" {
+		t.Fatal("expected derived secrets")
+	}
+	for name, secret := range map[string]string{"URL signing": cfg.URLSigningSecret, "scan hash": cfg.ScanIPHashSecret} {
+		if secret == cfg.JWTSecret {
+			t.Errorf("expected the %s secret to differ from the JWT secret", name)
+		}
+	}
+	if cfg.URLSigningSecret == cfg.ScanIPHashSecret {
+		t.Error("expected every purpose to get its own secret")
+	}
+	if again := Load(); again.URLSigningSecret != cfg.URLSigningSecret {
+		t.Error("expected derived secrets to be stable across loads")
+	}
+}
+
+func TestLoad_DedicatedSecretsWin(t *testing.T) {
+	t.Setenv("JWT_SECRET", "jwt-secret")
+	t.Setenv("URL_SIGNING_SECRET", "url-secret")
+	t.Setenv("SCAN_IP_HASH_SECRET", "scan-secret")
+
+	cfg := Load()
+	if cfg.URLSigningSecret != "url-secret" || cfg.ScanIPHashSecret != "scan-secret" {
+		t.Errorf("expected the configured secrets, got %q and %q", cfg.URLSigningSecret, cfg.ScanIPHashSecret)
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
+	"github.com/go-chi/chi/v5"
+)
+
+// ListLoginLockouts returns the emails and client IPs currently locked out of login
+func (h *AdminHandler) ListLoginLockouts(w http.ResponseWriter, r *http.Request) {
+	lockouts, err := h.queries.ListLoginLockouts(r.Context())
+	if err != nil {
+		log.Printf("Failed to fetch login lockouts: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch lockouts", nil)
+		return
+	}
+
+	response := make([]model.LoginLockoutResponse, len(lockouts))
+	for i, lockout := range lockouts {
+		response[i] = model.LoginLockoutResponse{
+			Scope:         lockout.Scope,
+			Subject:       lockout.Subject,
+			Failures:      lockout.Failures,
+			LastFailureAt: lockout.LastFailureAt,
+			LockedUntil:   lockout.LockedUntil.Time,
+		}
+	}
+
+	respondSuccess(w, http.StatusOK, "Lockouts retrieved successfully", response)
+}
+
+// ClearLoginLockout lifts the lockout of an email or client IP and forgets its failed logins
+func (h *AdminHandler) ClearLoginLockout(w http.ResponseWriter, r *http.Request) {
+	scope := chi.URLParam(r, "scope")
+	if scope != throttleScopeEmail && scope != throttleScopeIP {
+		respondError(w, http.StatusBadRequest, "Invalid lockout scope", map[string]string{
+			"scope": "scope must be one of: email, ip",
+		})
+		return
+	}
+
+	// Emails and IPv6 addresses may arrive percent-encoded
+	subject, err := url.PathUnescape(chi.URLParam(r, "subject"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid lockout subject", nil)
+		return
+	}
+
+	if _, err := h.queries.DeleteLoginThrottle(r.Context(), repository.DeleteLoginThrottleParams{
+		Scope:   scope,
+		Subject: subject,
+	}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "Lockout not found", nil)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to clear lockout", nil)
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, "Lockout cleared successfully", nil)
+}
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/auth"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/middleware"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// AdminHandler handles user management requests of admins.
+// Routes must be restricted to auth.PermUsersManage.
+type AdminHandler struct {
+	queries *repository.Queries
+}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler(queries *repository.Queries) *AdminHandler {
+	return &AdminHandler{queries: queries}
+}
+
+// ListUsers returns users with pagination, search by email or name, and role and status filters
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	params, details := ParseListUsersParams(r)
+	if len(details) > 0 {
+		respondError(w, http.StatusBadRequest, "Invalid query parameters", details)
+		return
+	}
+
+	result, err := h.queries.ListUsersPaginated(r.Context(), params)
+	if err != nil {
+		log.Printf("Failed to fetch paginated users: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch users", nil)
+		return
+	}
+
+	response := make([]model.UserResponse, len(result.Users))
+	for i, u := range result.Users {
+		response[i] = userToResponse(u)
+	}
+
+	pagination := model.PaginationMeta{
+		CurrentPage: params.Page,
+		PerPage:     params.PerPage,
+		TotalItems:  result.TotalCount,
+		TotalPages:  CalculateTotalPages(result.TotalCount, params.PerPage),
+	}
+
+	respondPaginated(w, http.StatusOK, "Users retrieved successfully", response, pagination)
+}
+
+// GetUser returns a single user
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	user, err := h.queries.GetUserByID(r.Context(), id)
+	if err != nil {
+		respondUserError(w, err, "Failed to fetch user")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, "User retrieved successfully", userToResponse(user))
+}
+
+// UpdateUserRole changes a user's role. The new role applies to access tokens issued
+// from the next login or refresh; admins can't change their own role.
+func (h *AdminHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.otherUserID(w, r, "You cannot change your own role")
+	if !ok {
+		return
+	}
+
+	var req model.UpdateUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+	if validationErrors := req.Validate(auth.Roles); len(validationErrors) > 0 {
+		respondError(w, http.StatusBadRequest, "Validation failed", validationErrors)
+		return
+	}
+
+	user, err := h.queries.UpdateUserRole(r.Context(), repository.UpdateUserRoleParams{
+		ID:   id,
+		Role: sql.NullString{String: req.Role, Valid: true},
+	})
+	if err != nil {
+		respondUserError(w, err, "Failed to update user")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, "User role updated successfully", userToResponse(repository.GetUserByIDRow(user)))
+}
+
+// DisableUser disables an account and revokes its sessions. Access tokens already
+// issued stay valid until they expire. Admins can't disable their own account.
+func (h *AdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.otherUserID(w, r, "You cannot disable your own account")
+	if !ok {
+		return
+	}
+
+	user, err := h.queries.SetUserDisabled(r.Context(), repository.SetUserDisabledParams{Disabled: true, ID: id})
+	if err != nil {
+		respondUserError(w, err, "Failed to disable user")
+		return
+	}
+
+	if err := h.queries.RevokeAllUserRefreshTokens(r.Context(), id); err != nil {
+		log.Printf("Failed to revoke sessions of disabled user %s: %v", id, err)
+		respondError(w, http.StatusInternalServerError, "Failed to revoke sessions", nil)
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, "User disabled successfully", userToResponse(repository.GetUserByIDRow(user)))
+}
+
+// EnableUser enables a disabled account again
+func (h *AdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	user, err := h.queries.SetUserDisabled(r.Context(), repository.SetUserDisabledParams{Disabled: false, ID: id})
+	if err != nil {
+		respondUserError(w, err, "Failed to enable user")
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, "User enabled successfully", userToResponse(repository.GetUserByIDRow(user)))
+}
+
+// LogoutUser revokes every refresh token of a user, logging them out on all devices
+// once their access tokens expire
+func (h *AdminHandler) LogoutUser(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	if _, err := h.queries.GetUserByID(r.Context(), id); err != nil {
+		respondUserError(w, err, "Failed to fetch user")
+		return
+	}
+
+	if err := h.queries.RevokeAllUserRefreshTokens(r.Context(), id); err != nil {
+		log.Printf("Failed to revoke sessions of user %s: %v", id, err)
+		respondError(w, http.StatusInternalServerError, "Failed to revoke sessions", nil)
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, "User logged out successfully", nil)
+}
+
+// otherUserID parses the user ID from the URL, rejecting the caller's own ID with message,
+// so admins can't lock themselves out
+func (h *AdminHandler) otherUserID(w http.ResponseWriter, r *http.Request, message string) (uuid.UUID, bool) {
+	claims, ok := middleware.GetClaims(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized", nil)
+		return uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return uuid.Nil, false
+	}
+	if id == claims.UserID {
+		respondError(w, http.StatusBadRequest, message, nil)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// respondUserError reports a failed user query, as not found when there's no such user
+func respondUserError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusNotFound, "User not found", nil)
+		return
+	}
+	log.Printf("%s: %v", message, err)
+	respondError(w, http.StatusInternalServerError, message, nil)
+}
+
+// PromoteAdmins makes the registered users with the given emails admins, matching
+// emails case-insensitively, and returns the emails promoted. Only verified accounts
+// count: anyone can register or switch to an unclaimed address, so an unverified one
+// proves nothing.
+func (h *AdminHandler) PromoteAdmins(ctx context.Context, emails []string) ([]string, error) {
+	if len(emails) == 0 {
+		return nil, nil
+	}
+
+	normalized := make([]string, len(emails))
+	for i, email := range emails {
+		normalized[i] = strings.ToLower(strings.TrimSpace(email))
+	}
+	return h.queries.PromoteUsersToAdmin(ctx, normalized)
+}
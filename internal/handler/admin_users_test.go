@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// createTestAdminRouter creates a router with the admin user routes
+func createTestAdminRouter(handler *AdminHandler) *chi.Mux {
+	r := chi.NewRouter()
+	r.Get("/admin/users", handler.ListUsers)
+	r.Get("/admin/users/{id}", handler.GetUser)
+	r.Put("/admin/users/{id}/role", handler.UpdateUserRole)
+	r.Post("/admin/users/{id}/disable", handler.DisableUser)
+	r.Post("/admin/users/{id}/enable", handler.EnableUser)
+	r.Post("/admin/users/{id}/logout", handler.LogoutUser)
+	r.Get("/admin/lockouts", handler.ListLoginLockouts)
+	r.Delete("/admin/lockouts/{scope}/{subject}", handler.ClearLoginLockout)
+	return r
+}
+
+// createTestUserWithRole creates a user and returns their ID
+func createTestUserWithRole(t *testing.T, email, name, role string) uuid.UUID {
+	var userID uuid.UUID
+	err := testDB.QueryRow(`
+		INSERT INTO users (email, password_hash, name, role)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, email, "$2a$12$test", name, role).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	return userID
+}
+
+// serveAdmin sends a request as the given admin
+func serveAdmin(router *chi.Mux, adminID uuid.UUID, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = addRoleClaimsToContext(req, adminID, auth.RoleAdmin)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAdminHandler_ListUsers_SearchAndFilter(t *testing.T) {
+	cleanupUsers(t)
+
+	adminID := createTestUserWithRole(t, "admin@example.com", "Admin", auth.RoleAdmin)
+	createTestUserWithRole(t, "budi@example.com", "Budi Surveyor", auth.RoleSurveyor)
+	createTestUserWithRole(t, "sari@example.com", "Sari Viewer", auth.RoleViewer)
+
+	router := createTestAdminRouter(NewAdminHandler(testQueries))
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCount int
+	}{
+		{"all users", "", 3},
+		{"search by name", "?search=budi", 1},
+		{"search by email", "?search=sari@", 1},
+		{"filter by role", "?role=viewer", 1},
+		{"filter by status", "?status=disabled", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := serveAdmin(router, adminID, http.MethodGet, "/admin/users"+tt.query, "")
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+			}
+
+			var response PaginatedResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if response.Meta.Pagination.TotalItems != int64(tt.expectedCount) {
+				t.Errorf("expected %d users, got %d", tt.expectedCount, response.Meta.Pagination.TotalItems)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_ListUsers_InvalidFilter(t *testing.T) {
+	router := createTestAdminRouter(NewAdminHandler(testQueries))
+
+	rr := serveAdmin(router, uuid.New(), http.MethodGet, "/admin/users?role=owner&status=gone", "")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+}
+
+func TestAdminHandler_UpdateUserRole(t *testing.T) {
+	cleanupUsers(t)
+
+	adminID := createTestUserWithRole(t, "admin@example.com", "Admin", auth.RoleAdmin)
+	userID := createTestUserWithRole(t, "budi@example.com", "Budi Surveyor", auth.RoleSurveyor)
+
+	router := createTestAdminRouter(NewAdminHandler(testQueries))
+
+	rr := serveAdmin(router, adminID, http.MethodPut, "/admin/users/"+userID.String()+"/role", `{"role": "editor"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	user, err := testQueries.GetUserByID(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("failed to fetch user: %v", err)
+	}
+	if user.Role.String != auth.RoleEditor {
+		t.Errorf("expected role %q, got %q", auth.RoleEditor, user.Role.String)
+	}
+
+	// Unknown roles are rejected
+	rr = serveAdmin(router, adminID, http.MethodPut, "/admin/users/"+userID.String()+"/role", `{"role": "owner"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+}
+
+func TestAdminHandler_SelfLockoutRejected(t *testing.T) {
+	cleanupUsers(t)
+
+	adminID := createTestUserWithRole(t, "admin@example.com", "Admin", auth.RoleAdmin)
+	router := createTestAdminRouter(NewAdminHandler(testQueries))
+
+	rr := serveAdmin(router, adminID, http.MethodPut, "/admin/users/"+adminID.String()+"/role", `{"role": "viewer"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for own role change, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+
+	rr = serveAdmin(router, adminID, http.MethodPost, "/admin/users/"+adminID.String()+"/disable", "")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for disabling self, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+}
+
+func TestAdminHandler_DisableUser_BlocksLoginAndRefresh(t *testing.T) {
+	cleanupUsers(t)
+
+	authHandler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
+	createTestUser(t, authHandler)
+	user, err := testQueries.GetUserByEmail(context.Background(), "login@example.com")
+	if err != nil {
+		t.Fatalf("failed to fetch user: %v", err)
+	}
+
+	// Log in before the account is disabled to hold a refresh token
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewBufferString(`{"email": "login@example.com", "password": "password123"}`))
+	rr := httptest.NewRecorder()
+	authHandler.Login(rr, req)
+	var loginResponse Response
+	json.Unmarshal(rr.Body.Bytes(), &loginResponse)
+	refreshToken := loginResponse.Data.(map[string]interface{})["refresh_token"].(string)
+
+	adminID := createTestUserWithRole(t, "admin@example.com", "Admin", auth.RoleAdmin)
+	router := createTestAdminRouter(NewAdminHandler(testQueries))
+
+	rr = serveAdmin(router, adminID, http.MethodPost, "/admin/users/"+user.ID.String()+"/disable", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewBufferString(`{"email": "login@example.com", "password": "password123"}`))
+	rr = httptest.NewRecorder()
+	authHandler.Login(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected login status %d, got %d: %s", http.StatusForbidden, rr.Code, rr.Body.String())
+	}
+
+	// Disabling revoked the sessions
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", bytes.NewBufferString(`{"refresh_token": "`+refreshToken+`"}`))
+	rr = httptest.NewRecorder()
+	authHandler.Refresh(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected refresh status %d, got %d: %s", http.StatusUnauthorized, rr.Code, rr.Body.String())
+	}
+
+	// Enabled accounts can log in again
+	rr = serveAdmin(router, adminID, http.MethodPost, "/admin/users/"+user.ID.String()+"/enable", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewBufferString(`{"email": "login@example.com", "password": "password123"}`))
+	rr = httptest.NewRecorder()
+	authHandler.Login(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected login status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+}
+
+func TestAdminHandler_LogoutUser(t *testing.T) {
+	cleanupUsers(t)
+
+	authHandler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
+	createTestUser(t, authHandler)
+	loginAndGetToken(t, authHandler)
+	user, err := testQueries.GetUserByEmail(context.Background(), "login@example.com")
+	if err != nil {
+		t.Fatalf("failed to fetch user: %v", err)
+	}
+
+	adminID := createTestUserWithRole(t, "admin@example.com", "Admin", auth.RoleAdmin)
+	router := createTestAdminRouter(NewAdminHandler(testQueries))
+
+	rr := serveAdmin(router, adminID, http.MethodPost, "/admin/users/"+user.ID.String()+"/logout", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var active int
+	testDB.QueryRow("SELECT COUNT(*) FROM refresh_tokens WHERE user_id = $1 AND revoked_at IS NULL", user.ID).Scan(&active)
+	if active != 0 {
+		t.Errorf("expected no active refresh tokens, got %d", active)
+	}
+
+	rr = serveAdmin(router, adminID, http.MethodPost, "/admin/users/"+uuid.New().String()+"/logout", "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d: %s", http.StatusNotFound, rr.Code, rr.Body.String())
+	}
+}
+
+func TestAdminHandler_PromoteAdmins(t *testing.T) {
+	cleanupUsers(t)
+
+	verifiedID := createTestUserWithRole(t, "owner@example.com", "Owner", auth.RoleSurveyor)
+	unverifiedID := createTestUserWithRole(t, "claimed@example.com", "Claimed", auth.RoleSurveyor)
+	unlistedID := createTestUserWithRole(t, "other@example.com", "Other", auth.RoleSurveyor)
+	if _, err := testDB.Exec(`UPDATE users SET email_verified_at = NOW() WHERE id = ANY($1::uuid[])`,
+		pq.Array([]uuid.UUID{verifiedID, unlistedID})); err != nil {
+		t.Fatalf("failed to verify test users: %v", err)
+	}
+
+	handler := NewAdminHandler(testQueries)
+	promoted, err := handler.PromoteAdmins(context.Background(), []string{" Owner@Example.COM", "claimed@example.com", "nobody@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(promoted) != 1 || promoted[0] != "owner@example.com" {
+		t.Errorf("expected only owner@example.com to be promoted, got %v", promoted)
+	}
+
+	for id, want := range map[uuid.UUID]string{
+		verifiedID:   auth.RoleAdmin,
+		unverifiedID: auth.RoleSurveyor,
+		unlistedID:   auth.RoleSurveyor,
+	} {
+		user, err := testQueries.GetUserByID(context.Background(), id)
+		if err != nil {
+			t.Fatalf("failed to get user: %v", err)
+		}
+		if user.Role.String != want {
+			t.Errorf("%s: expected role %s, got %s", user.Email, want, user.Role.String)
+		}
+	}
+
+	// Admins already promoted are not reported again
+	promoted, err = handler.PromoteAdmins(context.Background(), []string{"owner@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(promoted) != 0 {
+		t.Errorf("expected no promotions on the second run, got %v", promoted)
+	}
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"net/http"
+)
+
+// appLinkPaths are the web paths the mobile app opens instead of the browser
+var appLinkPaths = []string{"/marker/*", "/m/*"}
+
+// AppLinksConfig identifies the mobile apps allowed to open marker links
+type AppLinksConfig struct {
+	// AndroidPackage is the Android application ID, empty disables Android App Links
+	AndroidPackage string
+	// AndroidCertSHA256 are the SHA-256 fingerprints of the app signing certificates
+	AndroidCertSHA256 []string
+	// AppleAppIDs are "<team ID>.<bundle ID>" identifiers, empty disables universal links
+	AppleAppIDs []string
+	// AppleAppStoreID is the numeric App Store ID, used for the Smart App Banner on marker pages
+	AppleAppStoreID string
+}
+
+// AppLinksHandler serves the site association files that let the mobile apps
+// open marker links directly (Android App Links and iOS universal links)
+type AppLinksHandler struct {
+	cfg AppLinksConfig
+}
+
+// NewAppLinksHandler creates a new AppLinksHandler
+func NewAppLinksHandler(cfg AppLinksConfig) *AppLinksHandler {
+	return &AppLinksHandler{cfg: cfg}
+}
+
+// assetLink is a statement in /.well-known/assetlinks.json
+type assetLink struct {
+	Relation []string        `json:"relation"`
+	Target   assetLinkTarget `json:"target"`
+}
+
+type assetLinkTarget struct {
+	Namespace    string   `json:"namespace"`
+	PackageName  string   `json:"package_name"`
+	Fingerprints []string `json:"sha256_cert_fingerprints"`
+}
+
+// AssetLinks serves the Digital Asset Links statement for Android App Links
+func (h *AppLinksHandler) AssetLinks(w http.ResponseWriter, r *http.Request) {
+	if h.cfg.AndroidPackage == "" || len(h.cfg.AndroidCertSHA256) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, []assetLink{{
+		Relation: []string{"delegate_permission/common.handle_all_urls"},
+		Target: assetLinkTarget{
+			Namespace:    "android_app",
+			PackageName:  h.cfg.AndroidPackage,
+			Fingerprints: h.cfg.AndroidCertSHA256,
+		},
+	}})
+}
+
+// appleAppSiteAssociation is the apple-app-site-association document.
+// Paths is the format read before iOS 13, Components the current one.
+type appleAppSiteAssociation struct {
+	AppLinks struct {
+		Apps    []string            `json:"apps"`
+		Details []appleAppLinkEntry `json:"details"`
+	} `json:"applinks"`
+}
+
+type appleAppLinkEntry struct {
+	AppID      string              `json:"appID"`
+	AppIDs     []string            `json:"appIDs"`
+	Paths      []string            `json:"paths"`
+	Components []map[string]string `json:"components"`
+}
+
+// AppleAppSiteAssociation serves the association file for iOS universal links
+func (h *AppLinksHandler) AppleAppSiteAssociation(w http.ResponseWriter, r *http.Request) {
+	if len(h.cfg.AppleAppIDs) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	components := make([]map[string]string, len(appLinkPaths))
+	for i, path := range appLinkPaths {
+		components[i] = map[string]string{"/": path}
+	}
+
+	var doc appleAppSiteAssociation
+	doc.AppLinks.Apps = []string{}
+	for _, id := range h.cfg.AppleAppIDs {
+		doc.AppLinks.Details = append(doc.AppLinks.Details, appleAppLinkEntry{
+			AppID:      id,
+			AppIDs:     []string{id},
+			Paths:      appLinkPaths,
+			Components: components,
+		})
+	}
+	respondJSON(w, http.StatusOK, doc)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAppLinksHandler_AssetLinks(t *testing.T) {
+	handler := NewAppLinksHandler(AppLinksConfig{
+		AndroidPackage:    "com.sapuran.bamboomapper",
+		AndroidCertSHA256: []string{"AB:CD:EF"},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/assetlinks.json", nil)
+	rr := httptest.NewRecorder()
+	handler.AssetLinks(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %s", got)
+	}
+
+	var statements []assetLink
+	if err := json.Unmarshal(rr.Body.Bytes(), &statements); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(statements) != 1 || statements[0].Target.PackageName != "com.sapuran.bamboomapper" {
+		t.Fatalf("unexpected statements: %+v", statements)
+	}
+	if statements[0].Relation[0] != "delegate_permission/common.handle_all_urls" {
+		t.Errorf("unexpected relation: %v", statements[0].Relation)
+	}
+	if fp := statements[0].Target.Fingerprints; len(fp) != 1 || fp[0] != "AB:CD:EF" {
+		t.Errorf("unexpected fingerprints: %v", fp)
+	}
+}
+
+func TestAppLinksHandler_AppleAppSiteAssociation(t *testing.T) {
+	handler := NewAppLinksHandler(AppLinksConfig{AppleAppIDs: []string{"TEAM123.com.sapuran.bamboomapper"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/apple-app-site-association", nil)
+	rr := httptest.NewRecorder()
+	handler.AppleAppSiteAssociation(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var doc appleAppSiteAssociation
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(doc.AppLinks.Details) != 1 {
+		t.Fatalf("expected one app, got %+v", doc.AppLinks.Details)
+	}
+	entry := doc.AppLinks.Details[0]
+	if entry.AppIDs[0] != "TEAM123.com.sapuran.bamboomapper" {
+		t.Errorf("unexpected app IDs: %v", entry.AppIDs)
+	}
+	if len(entry.Components) != len(appLinkPaths) || entry.Components[0]["/"] != "/marker/*" {
+		t.Errorf("unexpected components: %v", entry.Components)
+	}
+}
+
+func TestAppLinksHandler_NotConfigured(t *testing.T) {
+	handler := NewAppLinksHandler(AppLinksConfig{})
+
+	for path, serve := range map[string]http.HandlerFunc{
+		"/.well-known/assetlinks.json":            handler.AssetLinks,
+		"/.well-known/apple-app-site-association": handler.AppleAppSiteAssociation,
+	} {
+		rr := httptest.NewRecorder()
+		serve(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status %d, got %d", path, http.StatusNotFound, rr.Code)
+		}
+	}
+}
@@ -4,33 +4,107 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/auth"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/mailer"
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/middleware"
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/util"
+	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
 const bcryptCost = 12
 
+// Lifetimes of emailed links when not configured
+const (
+	defaultPasswordResetExpiry     = time.Hour
+	defaultEmailVerificationExpiry = 48 * time.Hour
+	defaultLoginFailureWindow      = time.Hour
+)
+
+// AuthHandlerConfig holds the optional dependencies and settings of AuthHandler
+type AuthHandlerConfig struct {
+	// Mailer delivers account emails such as password reset links, nil logs them instead
+	Mailer mailer.Mailer
+	// PasswordResetURL is the page linked from password reset emails, receiving the token as ?token=
+	PasswordResetURL string
+	// PasswordResetExpiry is how long reset links stay valid, zero uses one hour
+	PasswordResetExpiry time.Duration
+	// EmailVerificationURL is the page linked from verification emails, receiving the token as ?token=
+	EmailVerificationURL string
+	// EmailVerificationExpiry is how long verification links stay valid, zero uses 48 hours
+	EmailVerificationExpiry time.Duration
+	// AccountLockout blocks logins to an email after failed attempts, the zero value never does
+	AccountLockout auth.LockoutPolicy
+	// IPLockout blocks logins from a client IP after failed attempts, the zero value never does
+	IPLockout auth.LockoutPolicy
+	// LoginFailureWindow is how long failed logins are remembered, zero uses one hour
+	LoginFailureWindow time.Duration
+}
+
 // AuthHandler handles authentication-related requests
 type AuthHandler struct {
-	queries    *repository.Queries
-	jwtManager *auth.JWTManager
+	db                  *sql.DB
+	queries             *repository.Queries
+	jwtManager          *auth.JWTManager
+	mailer              mailer.Mailer
+	passwordResetURL    string
+	passwordResetExpiry time.Duration
+	verificationURL     string
+	verificationExpiry  time.Duration
+	accountLockout      auth.LockoutPolicy
+	ipLockout           auth.LockoutPolicy
+	loginFailureWindow  time.Duration
+
+	// lastThrottleCleanup is when expired login throttles were last deleted, in Unix nanoseconds
+	lastThrottleCleanup atomic.Int64
+	// background tracks the emails still being sent after their response
+	background sync.WaitGroup
 }
 
-// NewAuthHandler creates a new AuthHandler
-func NewAuthHandler(queries *repository.Queries, jwtManager *auth.JWTManager) *AuthHandler {
+// NewAuthHandler creates a new AuthHandler. db runs the transactions of token rotation.
+func NewAuthHandler(db *sql.DB, jwtManager *auth.JWTManager, cfg AuthHandlerConfig) *AuthHandler {
+	if cfg.Mailer == nil {
+		cfg.Mailer = mailer.LogMailer{}
+	}
+	if cfg.PasswordResetExpiry == 0 {
+		cfg.PasswordResetExpiry = defaultPasswordResetExpiry
+	}
+	if cfg.EmailVerificationExpiry == 0 {
+		cfg.EmailVerificationExpiry = defaultEmailVerificationExpiry
+	}
+	if cfg.LoginFailureWindow == 0 {
+		cfg.LoginFailureWindow = defaultLoginFailureWindow
+	}
 	return &AuthHandler{
-		queries:    queries,
-		jwtManager: jwtManager,
+		db:                  db,
+		queries:             repository.New(db),
+		jwtManager:          jwtManager,
+		mailer:              cfg.Mailer,
+		passwordResetURL:    cfg.PasswordResetURL,
+		passwordResetExpiry: cfg.PasswordResetExpiry,
+		verificationURL:     cfg.EmailVerificationURL,
+		verificationExpiry:  cfg.EmailVerificationExpiry,
+		accountLockout:      cfg.AccountLockout,
+		ipLockout:           cfg.IPLockout,
+		loginFailureWindow:  cfg.LoginFailureWindow,
 	}
 }
 
+// Wait blocks until the emails still being sent after their response are out
+func (h *AuthHandler) Wait() {
+	h.background.Wait()
+}
+
 // Register handles user registration
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
@@ -58,7 +132,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		Email:        strings.ToLower(req.Email),
 		PasswordHash: string(hashedPassword),
 		Name:         req.Name,
-		Role:         sql.NullString{String: "user", Valid: true},
+		Role:         sql.NullString{String: auth.DefaultRole, Valid: true},
 	})
 	if err != nil {
 		// Check for duplicate email (PostgreSQL unique violation)
@@ -71,6 +145,11 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The account is read-only until the emailed link is opened
+	if err := h.sendEmailVerification(r.Context(), user.ID, user.Email, user.Name); err != nil {
+		log.Printf("Failed to send email verification to user %s: %v", user.ID, err)
+	}
+
 	// Return created user (without password)
 	response := model.UserResponse{
 		ID:        user.ID,
@@ -99,11 +178,19 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Refuse locked out emails and client IPs before checking the password
+	email := strings.ToLower(req.Email)
+	clientIP := util.ClientIP(r)
+	if lockedUntil := h.loginLockedUntil(r.Context(), email, clientIP); !lockedUntil.IsZero() {
+		respondLockedOut(w, lockedUntil)
+		return
+	}
+
 	// Fetch user by email
-	user, err := h.queries.GetUserByEmail(r.Context(), strings.ToLower(req.Email))
+	user, err := h.queries.GetUserByEmail(r.Context(), email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			respondError(w, http.StatusUnauthorized, "Invalid email or password", nil)
+			h.respondLoginFailed(w, r, email, clientIP)
 			return
 		}
 		respondError(w, http.StatusInternalServerError, "Failed to process login", nil)
@@ -112,14 +199,14 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		respondError(w, http.StatusUnauthorized, "Invalid email or password", nil)
+		h.respondLoginFailed(w, r, email, clientIP)
 		return
 	}
+	h.clearLoginFailures(r.Context(), email)
 
-	// Generate access token
-	accessToken, err := h.jwtManager.GenerateAccessToken(user.ID, user.Email, user.Role.String)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to generate token", nil)
+	// Disabled accounts are only reported after the password matched, so it can't be probed
+	if user.DisabledAt.Valid {
+		respondError(w, http.StatusForbidden, "Account is disabled", nil)
 		return
 	}
 
@@ -130,18 +217,26 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.queries.CreateRefreshToken(r.Context(), repository.CreateRefreshTokenParams{
+	session, err := h.queries.CreateRefreshToken(r.Context(), repository.CreateRefreshTokenParams{
 		UserID:    user.ID,
+		FamilyID:  uuid.New(),
 		TokenHash: tokenHash,
 		ExpiresAt: expiresAt,
 		UserAgent: sql.NullString{String: r.UserAgent(), Valid: r.UserAgent() != ""},
-		IpAddress: sql.NullString{String: getClientIP(r), Valid: true},
+		IpAddress: sql.NullString{String: clientIP, Valid: true},
 	})
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to create session", nil)
 		return
 	}
 
+	// Generate access token for the new session
+	accessToken, err := h.jwtManager.GenerateAccessToken(user.ID, user.Email, user.Role.String, user.EmailVerifiedAt.Valid, session.ID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to generate token", nil)
+		return
+	}
+
 	// Return response
 	response := model.LoginResponse{
 		AccessToken:  accessToken,
@@ -157,6 +252,9 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 			UpdatedAt: user.UpdatedAt.Time,
 		},
 	}
+	if user.EmailVerifiedAt.Valid {
+		response.User.EmailVerifiedAt = &user.EmailVerifiedAt.Time
+	}
 
 	respondSuccess(w, http.StatusOK, "Login successful", response)
 }
@@ -176,10 +274,19 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Hash the provided token and look it up
+	// Look up, revoke and replace the token in one transaction, so concurrent refreshes
+	// with the same token can't both succeed
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to process refresh", nil)
+		return
+	}
+	defer tx.Rollback()
+	queries := h.queries.WithTx(tx)
+
 	tokenHash := auth.HashRefreshToken(req.RefreshToken)
 
-	storedToken, err := h.queries.GetRefreshTokenByHash(r.Context(), tokenHash)
+	storedToken, err := queries.GetRefreshTokenForRotation(r.Context(), tokenHash)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			respondError(w, http.StatusUnauthorized, "Invalid or expired refresh token", nil)
@@ -189,45 +296,64 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if storedToken.RevokedAt.Valid {
+		h.revokeReusedFamily(w, r, tx, queries, storedToken)
+		return
+	}
+	if !storedToken.ExpiresAt.After(time.Now()) {
+		respondError(w, http.StatusUnauthorized, "Invalid or expired refresh token", nil)
+		return
+	}
+
 	// Revoke the old refresh token (rotation)
-	if err := h.queries.RevokeRefreshToken(r.Context(), tokenHash); err != nil {
+	if err := queries.RevokeRefreshToken(r.Context(), tokenHash); err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to process refresh", nil)
 		return
 	}
 
 	// Fetch user data for new access token
-	user, err := h.queries.GetUserByID(r.Context(), storedToken.UserID)
+	user, err := queries.GetUserByID(r.Context(), storedToken.UserID)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to fetch user", nil)
 		return
 	}
-
-	// Generate new access token
-	accessToken, err := h.jwtManager.GenerateAccessToken(user.ID, user.Email, user.Role.String)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to generate token", nil)
+	if user.DisabledAt.Valid {
+		respondError(w, http.StatusForbidden, "Account is disabled", nil)
 		return
 	}
 
-	// Generate new refresh token
+	// Generate new refresh token in the same family
 	rawRefreshToken, newTokenHash, expiresAt, err := h.jwtManager.GenerateRefreshToken()
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to generate token", nil)
 		return
 	}
 
-	_, err = h.queries.CreateRefreshToken(r.Context(), repository.CreateRefreshTokenParams{
+	session, err := queries.CreateRefreshToken(r.Context(), repository.CreateRefreshTokenParams{
 		UserID:    storedToken.UserID,
+		FamilyID:  storedToken.FamilyID,
 		TokenHash: newTokenHash,
 		ExpiresAt: expiresAt,
 		UserAgent: sql.NullString{String: r.UserAgent(), Valid: r.UserAgent() != ""},
-		IpAddress: sql.NullString{String: getClientIP(r), Valid: true},
+		IpAddress: sql.NullString{String: util.ClientIP(r), Valid: true},
 	})
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to create session", nil)
 		return
 	}
 
+	// Generate new access token for the new session
+	accessToken, err := h.jwtManager.GenerateAccessToken(user.ID, user.Email, user.Role.String, user.EmailVerifiedAt.Valid, session.ID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to generate token", nil)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to process refresh", nil)
+		return
+	}
+
 	// Return response
 	response := model.RefreshResponse{
 		AccessToken:  accessToken,
@@ -239,6 +365,29 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	respondSuccess(w, http.StatusOK, "Token refreshed successfully", response)
 }
 
+// revokeReusedFamily handles a revoked refresh token presented again. Rotated tokens are
+// only replayed when they were copied, and either holder may be the thief, so every
+// token rotated from the same login is revoked.
+func (h *AuthHandler) revokeReusedFamily(w http.ResponseWriter, r *http.Request, tx *sql.Tx, queries *repository.Queries, token repository.RefreshToken) {
+	revoked, err := queries.RevokeRefreshTokenFamily(r.Context(), token.FamilyID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to validate token", nil)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to validate token", nil)
+		return
+	}
+
+	// A family without active tokens was logged out, so replaying it is no attack
+	if len(revoked) > 0 {
+		log.Printf("SECURITY: revoked refresh token %s of user %s reused from %s (%s), revoked %d session(s) of family %s",
+			token.ID, token.UserID, util.ClientIP(r), r.UserAgent(), len(revoked), token.FamilyID)
+	}
+
+	respondError(w, http.StatusUnauthorized, "Invalid or expired refresh token", nil)
+}
+
 // Logout revokes all refresh tokens for the authenticated user
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	// Get claims from context (set by JWT middleware)
@@ -277,37 +426,24 @@ func (h *AuthHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := model.UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		Name:      user.Name,
-		Role:      user.Role.String,
-		CreatedAt: user.CreatedAt.Time,
-		UpdatedAt: user.UpdatedAt.Time,
-	}
-
-	respondSuccess(w, http.StatusOK, "User retrieved successfully", response)
+	respondSuccess(w, http.StatusOK, "User retrieved successfully", userToResponse(user))
 }
 
-// getClientIP extracts the client IP address from the request
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header first (for reverse proxies)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		if idx := strings.Index(xff, ","); idx != -1 {
-			return strings.TrimSpace(xff[:idx])
-		}
-		return strings.TrimSpace(xff)
+// userToResponse converts a user row to model.UserResponse
+func userToResponse(u repository.GetUserByIDRow) model.UserResponse {
+	response := model.UserResponse{
+		ID:        u.ID,
+		Email:     u.Email,
+		Name:      u.Name,
+		Role:      u.Role.String,
+		CreatedAt: u.CreatedAt.Time,
+		UpdatedAt: u.UpdatedAt.Time,
 	}
-
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
+	if u.DisabledAt.Valid {
+		response.DisabledAt = &u.DisabledAt.Time
 	}
-
-	// Fall back to RemoteAddr
-	ip := r.RemoteAddr
-	if idx := strings.LastIndex(ip, ":"); idx != -1 {
-		return ip[:idx]
+	if u.EmailVerifiedAt.Valid {
+		response.EmailVerifiedAt = &u.EmailVerifiedAt.Time
 	}
-	return ip
+	return response
 }
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/auth"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/mailer"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// mailTimeout bounds the delivery of a single email
+const mailTimeout = 30 * time.Second
+
+// ForgotPassword emails a password reset link. The response is the same whether or not
+// the email is registered, so it can't be used to find accounts.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req model.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	if validationErrors := req.Validate(); len(validationErrors) > 0 {
+		respondError(w, http.StatusBadRequest, "Validation failed", validationErrors)
+		return
+	}
+
+	user, err := h.queries.GetUserByEmail(r.Context(), strings.ToLower(req.Email))
+	switch {
+	case err == nil && !user.DisabledAt.Valid:
+		// Send after responding, so registered emails answer as fast as unknown ones
+		ctx := context.WithoutCancel(r.Context())
+		h.background.Add(1)
+		go func() {
+			defer h.background.Done()
+			if err := h.sendPasswordReset(ctx, user); err != nil {
+				log.Printf("Failed to send password reset to user %s: %v", user.ID, err)
+			}
+		}()
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		respondError(w, http.StatusInternalServerError, "Failed to process request", nil)
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, "If the email is registered, a password reset link has been sent", nil)
+}
+
+// sendPasswordReset issues a new reset token, replacing earlier ones, and emails its link.
+// It runs after the response, so ctx must not be cancelled with the request.
+func (h *AuthHandler) sendPasswordReset(ctx context.Context, user repository.User) error {
+	rawToken, tokenHash, expiresAt, err := auth.GenerateOneTimeToken(h.passwordResetExpiry)
+	if err != nil {
+		return err
+	}
+
+	if err := h.queries.InvalidateUserPasswordResetTokens(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := h.queries.CreatePasswordResetToken(ctx, repository.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return err
+	}
+
+	link, err := tokenLink(h.passwordResetURL, rawToken)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, mailTimeout)
+	defer cancel()
+
+	return h.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Bamboo Mapper password",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Someone asked to reset the password of your Bamboo Mapper account. "+
+			"Open this link to choose a new password:\n\n%s\n\n"+
+			"The link can be used once and expires in %s. "+
+			"If you didn't ask for this, you can ignore this email.\n",
+			user.Name, link, formatExpiry(h.passwordResetExpiry)),
+	})
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword.
+// The token is used up and every session of the user is revoked.
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req model.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	if validationErrors := req.Validate(); len(validationErrors) > 0 {
+		respondError(w, http.StatusBadRequest, "Validation failed", validationErrors)
+		return
+	}
+
+	// Use the token in the same transaction as the new password, so a failed reset
+	// leaves the link working
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to reset password", nil)
+		return
+	}
+	defer tx.Rollback()
+	queries := h.queries.WithTx(tx)
+
+	userID, err := queries.UsePasswordResetToken(r.Context(), auth.HashOneTimeToken(req.Token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusBadRequest, "Invalid or expired reset token", nil)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to reset password", nil)
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcryptCost)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to reset password", nil)
+		return
+	}
+
+	if err := queries.UpdateUserPassword(r.Context(), repository.UpdateUserPasswordParams{
+		ID:           userID,
+		PasswordHash: string(hashedPassword),
+	}); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to reset password", nil)
+		return
+	}
+
+	// Whoever knew the old password may hold a session
+	if err := queries.RevokeAllUserRefreshTokens(r.Context(), userID); err != nil {
+		log.Printf("Failed to revoke sessions after password reset of user %s: %v", userID, err)
+		respondError(w, http.StatusInternalServerError, "Failed to revoke sessions", nil)
+		return
+	}
+	if err := queries.InvalidateUserPasswordResetTokens(r.Context(), userID); err != nil {
+		log.Printf("Failed to invalidate reset tokens of user %s: %v", userID, err)
+		respondError(w, http.StatusInternalServerError, "Failed to reset password", nil)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to reset password", nil)
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, "Password reset successfully", nil)
+}
+
+// tokenLink adds a token to the query of a link, keeping any query it already has
+func tokenLink(base, token string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid link URL %q: %w", base, err)
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// formatExpiry describes a token lifetime for emails, such as "1 hour" or "30 minutes"
+func formatExpiry(d time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	if d >= time.Hour && d%time.Hour == 0 {
+		return plural(int(d/time.Hour), "hour")
+	}
+	return plural(int(d.Round(time.Minute)/time.Minute), "minute")
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/mailer"
+)
+
+// recordingMailer keeps sent messages instead of delivering them
+type recordingMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (m *recordingMailer) Send(_ context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+var tokenLinkPattern = regexp.MustCompile(`https://app\.example\.com/\S+\?token=\S+`)
+
+// tokenFromMail returns the token of the link in the last sent message
+func tokenFromMail(t *testing.T, m *recordingMailer) string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.messages) == 0 {
+		t.Fatal("expected an email")
+	}
+	link := tokenLinkPattern.FindString(m.messages[len(m.messages)-1].Body)
+	u, err := url.Parse(link)
+	if err != nil || u.Query().Get("token") == "" {
+		t.Fatalf("no token link in email: %q", m.messages[len(m.messages)-1].Body)
+	}
+	return u.Query().Get("token")
+}
+
+// newMailingAuthHandler creates an AuthHandler that records its emails
+func newMailingAuthHandler() (*AuthHandler, *recordingMailer) {
+	m := &recordingMailer{}
+	return NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{
+		Mailer:               m,
+		PasswordResetURL:     "https://app.example.com/reset",
+		EmailVerificationURL: "https://app.example.com/verify",
+	}), m
+}
+
+func postAuth(handlerFunc http.HandlerFunc, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handlerFunc(rr, req)
+	return rr
+}
+
+func TestAuthHandler_ResetPassword_Success(t *testing.T) {
+	cleanupUsers(t)
+
+	handler, m := newMailingAuthHandler()
+	createTestUser(t, handler)
+
+	// A session from before the reset
+	login := postAuth(handler.Login, "/api/v1/auth/login", `{"email": "login@example.com", "password": "password123"}`)
+	var loginResponse Response
+	json.Unmarshal(login.Body.Bytes(), &loginResponse)
+	refreshToken := loginResponse.Data.(map[string]interface{})["refresh_token"].(string)
+
+	rr := postAuth(handler.ForgotPassword, "/api/v1/auth/forgot-password", `{"email": "Login@Example.com"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	handler.Wait()
+	token := tokenFromMail(t, m)
+
+	rr = postAuth(handler.ResetPassword, "/api/v1/auth/reset-password", `{"token": "`+token+`", "password": "newpassword456"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	// The new password works, the old one doesn't
+	if rr := postAuth(handler.Login, "/api/v1/auth/login", `{"email": "login@example.com", "password": "newpassword456"}`); rr.Code != http.StatusOK {
+		t.Errorf("expected login with new password to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := postAuth(handler.Login, "/api/v1/auth/login", `{"email": "login@example.com", "password": "password123"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected login with old password to fail, got %d", rr.Code)
+	}
+
+	// Sessions from before the reset are revoked
+	if rr := postAuth(handler.Refresh, "/api/v1/auth/refresh", `{"refresh_token": "`+refreshToken+`"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected old refresh token to be revoked, got %d", rr.Code)
+	}
+
+	// The token is single-use
+	rr = postAuth(handler.ResetPassword, "/api/v1/auth/reset-password", `{"token": "`+token+`", "password": "anotherpassword"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected reused token to be rejected with %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestAuthHandler_ForgotPassword_UnknownEmail(t *testing.T) {
+	cleanupUsers(t)
+
+	handler, m := newMailingAuthHandler()
+
+	rr := postAuth(handler.ForgotPassword, "/api/v1/auth/forgot-password", `{"email": "nobody@example.com"}`)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	handler.Wait()
+	if len(m.messages) != 0 {
+		t.Errorf("expected no email for an unknown address, got %d", len(m.messages))
+	}
+}
+
+// blockingMailer holds every message until released
+type blockingMailer struct {
+	recordingMailer
+	release chan struct{}
+}
+
+func (m *blockingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	<-m.release
+	return m.recordingMailer.Send(ctx, msg)
+}
+
+func TestAuthHandler_ForgotPassword_DoesNotWaitForMail(t *testing.T) {
+	cleanupUsers(t)
+
+	m := &blockingMailer{release: make(chan struct{})}
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{
+		Mailer:           m,
+		PasswordResetURL: "https://app.example.com/reset",
+	})
+	createTestUser(t, handler)
+	handler.Wait()
+
+	// Registered and unknown emails both answer while the mailer is stuck
+	for _, email := range []string{"login@example.com", "nobody@example.com"} {
+		done := make(chan int)
+		go func() {
+			done <- postAuth(handler.ForgotPassword, "/api/v1/auth/forgot-password", `{"email": "`+email+`"}`).Code
+		}()
+
+		select {
+		case code := <-done:
+			if code != http.StatusOK {
+				t.Errorf("%s: expected status %d, got %d", email, http.StatusOK, code)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: response waited for the mailer", email)
+		}
+	}
+
+	close(m.release)
+	handler.Wait()
+	if len(m.messages) != 1 || m.messages[0].To != "login@example.com" {
+		t.Errorf("expected one reset email to the registered address, got %v", m.messages)
+	}
+}
+
+func TestAuthHandler_ResetPassword_ReplacedToken(t *testing.T) {
+	cleanupUsers(t)
+
+	handler, m := newMailingAuthHandler()
+	createTestUser(t, handler)
+
+	postAuth(handler.ForgotPassword, "/api/v1/auth/forgot-password", `{"email": "login@example.com"}`)
+	handler.Wait()
+	first := tokenFromMail(t, m)
+	postAuth(handler.ForgotPassword, "/api/v1/auth/forgot-password", `{"email": "login@example.com"}`)
+	handler.Wait()
+
+	rr := postAuth(handler.ResetPassword, "/api/v1/auth/reset-password", `{"token": "`+first+`", "password": "newpassword456"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected replaced token to be rejected with %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestAuthHandler_ResetPassword_FailureKeepsToken(t *testing.T) {
+	cleanupUsers(t)
+
+	handler, m := newMailingAuthHandler()
+	createTestUser(t, handler)
+
+	postAuth(handler.ForgotPassword, "/api/v1/auth/forgot-password", `{"email": "login@example.com"}`)
+	handler.Wait()
+	token := tokenFromMail(t, m)
+
+	// bcrypt refuses passwords over 72 bytes, failing the reset after the token was used
+	rr := postAuth(handler.ResetPassword, "/api/v1/auth/reset-password", `{"token": "`+token+`", "password": "`+strings.Repeat("x", 80)+`"}`)
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusInternalServerError, rr.Code, rr.Body.String())
+	}
+
+	// The failed attempt was rolled back, so the link still works
+	rr = postAuth(handler.ResetPassword, "/api/v1/auth/reset-password", `{"token": "`+token+`", "password": "newpassword456"}`)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected the token to still work, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestAuthHandler_ResetPassword_Validation(t *testing.T) {
+	handler, _ := newMailingAuthHandler()
+
+	rr := postAuth(handler.ResetPassword, "/api/v1/auth/reset-password", `{"token": "", "password": "short"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	var response Response
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if response.Meta.Details["token"] == "" || response.Meta.Details["password"] == "" {
+		t.Errorf("expected token and password errors, got %v", response.Meta.Details)
+	}
+}
+
+func TestTokenLink(t *testing.T) {
+	tests := []struct {
+		base     string
+		expected string
+	}{
+		{"https://app.example.com/reset", "https://app.example.com/reset?token=a%2Bb"},
+		{"https://app.example.com/reset?lang=id", "https://app.example.com/reset?lang=id&token=a%2Bb"},
+	}
+
+	for _, tt := range tests {
+		got, err := tokenLink(tt.base, "a+b")
+		if err != nil {
+			t.Fatalf("tokenLink(%q) failed: %v", tt.base, err)
+		}
+		if got != tt.expected {
+			t.Errorf("tokenLink(%q) = %q, expected %q", tt.base, got, tt.expected)
+		}
+	}
+}
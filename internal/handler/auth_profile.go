@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/middleware"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// UpdateMe changes the name and email of the authenticated user. Changing the email
+// needs the current password, and the new email has to be verified again.
+func (h *AuthHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetClaims(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var req model.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	if validationErrors := req.Validate(); len(validationErrors) > 0 {
+		respondError(w, http.StatusBadRequest, "Validation failed", validationErrors)
+		return
+	}
+
+	current, err := h.queries.GetUserByID(r.Context(), claims.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusUnauthorized, "User not found", nil)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch user", nil)
+		return
+	}
+
+	params := repository.UpdateUserProfileParams{ID: current.ID}
+	if req.Name != nil {
+		params.Name = sql.NullString{String: *req.Name, Valid: true}
+	}
+	emailChanged := req.Email != nil && *req.Email != current.Email
+	if emailChanged {
+		// A stolen access token must not be enough to take over the account by email
+		if req.CurrentPassword == "" {
+			respondError(w, http.StatusBadRequest, "Validation failed", map[string]string{
+				"current_password": "current_password is required to change the email",
+			})
+			return
+		}
+		if !h.checkPassword(w, r, current.ID, req.CurrentPassword) {
+			return
+		}
+		params.Email = sql.NullString{String: *req.Email, Valid: true}
+	}
+
+	user, err := h.queries.UpdateUserProfile(r.Context(), params)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			respondError(w, http.StatusConflict, "Email already registered", nil)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to update profile", nil)
+		return
+	}
+
+	if emailChanged {
+		if err := h.sendEmailVerification(r.Context(), user.ID, user.Email, user.Name); err != nil {
+			log.Printf("Failed to send email verification to user %s: %v", user.ID, err)
+		}
+	}
+
+	respondSuccess(w, http.StatusOK, "Profile updated successfully", userToResponse(repository.GetUserByIDRow(user)))
+}
+
+// ChangePassword replaces the password of the authenticated user after confirming the
+// current one. Every other session of the user is revoked; the calling one stays.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetClaims(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var req model.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	if validationErrors := req.Validate(); len(validationErrors) > 0 {
+		respondError(w, http.StatusBadRequest, "Validation failed", validationErrors)
+		return
+	}
+
+	if !h.checkPassword(w, r, claims.UserID, req.CurrentPassword) {
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcryptCost)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to change password", nil)
+		return
+	}
+
+	if err := h.queries.UpdateUserPassword(r.Context(), repository.UpdateUserPasswordParams{
+		ID:           claims.UserID,
+		PasswordHash: string(hashedPassword),
+	}); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to change password", nil)
+		return
+	}
+
+	// Tokens issued before sessions were tracked have no session ID, so every session is revoked
+	if err := h.queries.RevokeOtherUserRefreshTokens(r.Context(), repository.RevokeOtherUserRefreshTokensParams{
+		UserID: claims.UserID,
+		ID:     claims.SessionID,
+	}); err != nil {
+		log.Printf("Failed to revoke sessions after password change of user %s: %v", claims.UserID, err)
+		respondError(w, http.StatusInternalServerError, "Failed to revoke sessions", nil)
+		return
+	}
+	if err := h.queries.InvalidateUserPasswordResetTokens(r.Context(), claims.UserID); err != nil {
+		log.Printf("Failed to invalidate reset tokens of user %s: %v", claims.UserID, err)
+	}
+
+	respondSuccess(w, http.StatusOK, "Password changed successfully", nil)
+}
+
+// checkPassword reports whether password is the current password of the user,
+// responding with an error when it isn't
+func (h *AuthHandler) checkPassword(w http.ResponseWriter, r *http.Request, userID uuid.UUID, password string) bool {
+	passwordHash, err := h.queries.GetUserPasswordHash(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusUnauthorized, "User not found", nil)
+			return false
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch user", nil)
+		return false
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)); err != nil {
+		respondError(w, http.StatusBadRequest, "Validation failed", map[string]string{
+			"current_password": "current password is incorrect",
+		})
+		return false
+	}
+	return true
+}
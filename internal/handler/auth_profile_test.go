@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appMiddleware "github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/middleware"
+)
+
+// serveAsUser sends a request with the claims of an access token
+func serveAsUser(t *testing.T, handlerFunc http.HandlerFunc, accessToken, method, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	claims, err := testJWTManager.ValidateAccessToken(accessToken)
+	if err != nil {
+		t.Fatalf("invalid access token: %v", err)
+	}
+	req := httptest.NewRequest(method, "/api/v1/auth", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), appMiddleware.ClaimsKey, claims))
+	rr := httptest.NewRecorder()
+	handlerFunc(rr, req)
+	return rr
+}
+
+func TestAuthHandler_UpdateMe_Name(t *testing.T) {
+	cleanupUsers(t)
+
+	handler, _ := newMailingAuthHandler()
+	createTestUser(t, handler)
+	accessToken, _ := loginTokens(t, handler)
+
+	rr := serveAsUser(t, handler.UpdateMe, accessToken, http.MethodPatch, `{"name": "  New Name "}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response Response
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	data := response.Data.(map[string]interface{})
+	if data["name"] != "New Name" {
+		t.Errorf("expected name 'New Name', got %v", data["name"])
+	}
+	if data["email"] != "login@example.com" {
+		t.Errorf("expected email to be kept, got %v", data["email"])
+	}
+}
+
+func TestAuthHandler_UpdateMe_EmailNeedsReverification(t *testing.T) {
+	cleanupUsers(t)
+
+	handler, m := newMailingAuthHandler()
+	createTestUser(t, handler)
+	postAuth(handler.VerifyEmail, "/api/v1/auth/verify-email", `{"token": "`+tokenFromMail(t, m)+`"}`)
+	accessToken, _ := loginTokens(t, handler)
+
+	// The current password is required
+	rr := serveAsUser(t, handler.UpdateMe, accessToken, http.MethodPatch, `{"email": "new@example.com"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d without current password, got %d", http.StatusBadRequest, rr.Code)
+	}
+	rr = serveAsUser(t, handler.UpdateMe, accessToken, http.MethodPatch, `{"email": "new@example.com", "current_password": "wrong-password"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d with a wrong password, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	rr = serveAsUser(t, handler.UpdateMe, accessToken, http.MethodPatch, `{"email": "New@Example.com", "current_password": "password123"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response Response
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	data := response.Data.(map[string]interface{})
+	if data["email"] != "new@example.com" {
+		t.Errorf("expected email 'new@example.com', got %v", data["email"])
+	}
+	if data["email_verified_at"] != nil {
+		t.Errorf("expected the new email to be unverified, got %v", data["email_verified_at"])
+	}
+
+	if last := m.messages[len(m.messages)-1]; last.To != "new@example.com" {
+		t.Errorf("expected verification email to the new address, got %q", last.To)
+	}
+	if rr := postAuth(handler.VerifyEmail, "/api/v1/auth/verify-email", `{"token": "`+tokenFromMail(t, m)+`"}`); rr.Code != http.StatusOK {
+		t.Errorf("expected new email to verify, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestAuthHandler_UpdateMe_DuplicateEmail(t *testing.T) {
+	cleanupUsers(t)
+
+	handler, _ := newMailingAuthHandler()
+	createTestUser(t, handler)
+	createTestUserWithRole(t, "taken@example.com", "Other", "surveyor")
+	accessToken, _ := loginTokens(t, handler)
+
+	rr := serveAsUser(t, handler.UpdateMe, accessToken, http.MethodPatch, `{"email": "taken@example.com", "current_password": "password123"}`)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d: %s", http.StatusConflict, rr.Code, rr.Body.String())
+	}
+}
+
+func TestAuthHandler_ChangePassword_KeepsCurrentSession(t *testing.T) {
+	cleanupUsers(t)
+
+	handler, _ := newMailingAuthHandler()
+	createTestUser(t, handler)
+	_, otherRefresh := loginTokens(t, handler)
+	accessToken, currentRefresh := loginTokens(t, handler)
+
+	rr := serveAsUser(t, handler.ChangePassword, accessToken, http.MethodPost, `{"current_password": "wrong-password", "new_password": "newpassword456"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d with a wrong password, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	rr = serveAsUser(t, handler.ChangePassword, accessToken, http.MethodPost, `{"current_password": "password123", "new_password": "newpassword456"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	if rr := postAuth(handler.Refresh, "/api/v1/auth/refresh", `{"refresh_token": "`+otherRefresh+`"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected other session to be revoked, got %d", rr.Code)
+	}
+	if rr := postAuth(handler.Refresh, "/api/v1/auth/refresh", `{"refresh_token": "`+currentRefresh+`"}`); rr.Code != http.StatusOK {
+		t.Errorf("expected current session to stay, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := postAuth(handler.Login, "/api/v1/auth/login", `{"email": "login@example.com", "password": "newpassword456"}`); rr.Code != http.StatusOK {
+		t.Errorf("expected login with new password to succeed, got %d", rr.Code)
+	}
+}
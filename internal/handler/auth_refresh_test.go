@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// refreshWith refreshes a token, returning the status and the rotated refresh token
+func refreshWith(handler *AuthHandler, refreshToken string) (int, string) {
+	rr := postAuth(handler.Refresh, "/api/v1/auth/refresh", `{"refresh_token": "`+refreshToken+`"}`)
+	if rr.Code != http.StatusOK {
+		return rr.Code, ""
+	}
+	var response Response
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	return rr.Code, response.Data.(map[string]interface{})["refresh_token"].(string)
+}
+
+func TestAuthHandler_Refresh_ReuseRevokesFamily(t *testing.T) {
+	cleanupUsers(t)
+
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
+	createTestUser(t, handler)
+	_, otherDevice := loginTokens(t, handler)
+	_, first := loginTokens(t, handler)
+
+	code, second := refreshWith(handler, first)
+	if code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+
+	// Replaying the rotated token revokes the token rotated from it
+	if code, _ := refreshWith(handler, first); code != http.StatusUnauthorized {
+		t.Errorf("expected replay to fail with %d, got %d", http.StatusUnauthorized, code)
+	}
+	if code, _ := refreshWith(handler, second); code != http.StatusUnauthorized {
+		t.Errorf("expected family to be revoked, got %d", code)
+	}
+
+	// Other logins are separate families
+	if code, _ := refreshWith(handler, otherDevice); code != http.StatusOK {
+		t.Errorf("expected other device to keep its session, got %d", code)
+	}
+}
+
+func TestAuthHandler_Refresh_ConcurrentRotation(t *testing.T) {
+	cleanupUsers(t)
+
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
+	createTestUser(t, handler)
+	_, refreshToken := loginTokens(t, handler)
+
+	const attempts = 5
+	codes := make(chan int, attempts)
+	var wg sync.WaitGroup
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, _ := refreshWith(handler, refreshToken)
+			codes <- code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	succeeded := 0
+	for code := range codes {
+		if code == http.StatusOK {
+			succeeded++
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("expected exactly one refresh to succeed, got %d", succeeded)
+	}
+}
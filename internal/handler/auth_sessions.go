@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/auth"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/middleware"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ListSessions returns the devices the authenticated user is signed in on
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetClaims(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	sessions, err := h.queries.ListUserSessions(r.Context(), claims.UserID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch sessions", nil)
+		return
+	}
+
+	response := make([]model.SessionResponse, len(sessions))
+	for i, session := range sessions {
+		response[i] = sessionToResponse(session, claims.SessionID)
+	}
+
+	respondSuccess(w, http.StatusOK, "Sessions retrieved successfully", response)
+}
+
+// RevokeSession signs the authenticated user out on one device. Its access token stays
+// valid until it expires.
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetClaims(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid session ID", nil)
+		return
+	}
+
+	// Sessions of other users are reported as missing, so their IDs can't be probed
+	if _, err := h.queries.RevokeUserRefreshToken(r.Context(), repository.RevokeUserRefreshTokenParams{
+		ID:     id,
+		UserID: claims.UserID,
+	}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "Session not found", nil)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to revoke session", nil)
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, "Session revoked successfully", nil)
+}
+
+// LogoutDevice revokes only the presented refresh token, keeping the other sessions of
+// the user. The refresh token is the credential, so an expired access token can still
+// sign out.
+func (h *AuthHandler) LogoutDevice(w http.ResponseWriter, r *http.Request) {
+	var req model.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	if validationErrors := req.Validate(); len(validationErrors) > 0 {
+		respondError(w, http.StatusBadRequest, "Validation failed", validationErrors)
+		return
+	}
+
+	tokenHash := auth.HashRefreshToken(req.RefreshToken)
+	if _, err := h.queries.GetRefreshTokenByHash(r.Context(), tokenHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusUnauthorized, "Invalid or expired refresh token", nil)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to validate token", nil)
+		return
+	}
+
+	if err := h.queries.RevokeRefreshToken(r.Context(), tokenHash); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to logout", nil)
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, "Logged out successfully", nil)
+}
+
+// sessionToResponse converts a session row, marking it current when it belongs to currentID
+func sessionToResponse(session repository.ListUserSessionsRow, currentID uuid.UUID) model.SessionResponse {
+	response := model.SessionResponse{
+		ID:         session.ID,
+		LastUsedAt: session.CreatedAt.Time,
+		ExpiresAt:  session.ExpiresAt,
+		Current:    currentID != uuid.Nil && session.ID == currentID,
+	}
+	if session.UserAgent.Valid {
+		response.UserAgent = &session.UserAgent.String
+	}
+	if session.IpAddress.Valid {
+		response.IPAddress = &session.IpAddress.String
+	}
+	return response
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appMiddleware "github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// revokeSession sends DELETE /sessions/{id} with an access token
+func revokeSession(handler *AuthHandler, accessToken, id string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.With(appMiddleware.JWTAuth(testJWTManager)).Delete("/sessions/{id}", handler.RevokeSession)
+
+	req := httptest.NewRequest(http.MethodDelete, "/sessions/"+id, nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+// listSessions returns the sessions seen with an access token
+func listSessions(t *testing.T, handler *AuthHandler, accessToken string) []map[string]interface{} {
+	t.Helper()
+	rr := serveAsUser(t, handler.ListSessions, accessToken, http.MethodGet, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	return response.Data
+}
+
+func TestAuthHandler_ListSessions(t *testing.T) {
+	cleanupUsers(t)
+
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
+	createTestUser(t, handler)
+	loginTokens(t, handler)
+	accessToken, _ := loginTokens(t, handler)
+
+	sessions := listSessions(t, handler, accessToken)
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(sessions))
+	}
+
+	current := 0
+	for _, session := range sessions {
+		if session["current"] == true {
+			current++
+		}
+		if session["ip_address"] == nil || session["last_used_at"] == nil {
+			t.Errorf("expected device info, got %v", session)
+		}
+	}
+	if current != 1 {
+		t.Errorf("expected exactly one current session, got %d", current)
+	}
+}
+
+func TestAuthHandler_RevokeSession(t *testing.T) {
+	cleanupUsers(t)
+
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
+	createTestUser(t, handler)
+	_, otherRefresh := loginTokens(t, handler)
+	accessToken, currentRefresh := loginTokens(t, handler)
+
+	var otherID string
+	for _, session := range listSessions(t, handler, accessToken) {
+		if session["current"] != true {
+			otherID = session["id"].(string)
+		}
+	}
+
+	rr := revokeSession(handler, accessToken, otherID)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if rr := revokeSession(handler, accessToken, otherID); rr.Code != http.StatusNotFound {
+		t.Errorf("expected revoked session to be gone, got %d", rr.Code)
+	}
+	if rr := revokeSession(handler, accessToken, "not-a-uuid"); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an invalid ID, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	if rr := postAuth(handler.Refresh, "/api/v1/auth/refresh", `{"refresh_token": "`+otherRefresh+`"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected revoked session to fail refresh, got %d", rr.Code)
+	}
+	if rr := postAuth(handler.Refresh, "/api/v1/auth/refresh", `{"refresh_token": "`+currentRefresh+`"}`); rr.Code != http.StatusOK {
+		t.Errorf("expected current session to stay, got %d", rr.Code)
+	}
+}
+
+func TestAuthHandler_RevokeSession_OtherUser(t *testing.T) {
+	cleanupUsers(t)
+
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
+	createTestUser(t, handler)
+	victimToken, _ := loginTokens(t, handler)
+	victimSessions := listSessions(t, handler, victimToken)
+
+	postAuth(handler.Register, "/api/v1/auth/register", `{"email": "other@example.com", "name": "Other", "password": "password123"}`)
+	rr := postAuth(handler.Login, "/api/v1/auth/login", `{"email": "other@example.com", "password": "password123"}`)
+	var response Response
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	otherToken := response.Data.(map[string]interface{})["access_token"].(string)
+
+	if rr := revokeSession(handler, otherToken, victimSessions[0]["id"].(string)); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestAuthHandler_LogoutDevice(t *testing.T) {
+	cleanupUsers(t)
+
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
+	createTestUser(t, handler)
+	_, otherRefresh := loginTokens(t, handler)
+	_, refreshToken := loginTokens(t, handler)
+
+	rr := postAuth(handler.LogoutDevice, "/api/v1/auth/logout/device", `{"refresh_token": "`+refreshToken+`"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if rr := postAuth(handler.LogoutDevice, "/api/v1/auth/logout/device", `{"refresh_token": "`+refreshToken+`"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected second logout to fail, got %d", rr.Code)
+	}
+
+	if rr := postAuth(handler.Refresh, "/api/v1/auth/refresh", `{"refresh_token": "`+refreshToken+`"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected logged out token to fail refresh, got %d", rr.Code)
+	}
+	if rr := postAuth(handler.Refresh, "/api/v1/auth/refresh", `{"refresh_token": "`+otherRefresh+`"}`); rr.Code != http.StatusOK {
+		t.Errorf("expected other session to stay, got %d", rr.Code)
+	}
+}
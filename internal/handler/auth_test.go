@@ -18,6 +18,7 @@ import (
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -74,6 +75,10 @@ func cleanupUsers(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to cleanup refresh_tokens table: %v", err)
 	}
+	_, err = testDB.Exec("DELETE FROM login_throttles")
+	if err != nil {
+		t.Fatalf("failed to cleanup login_throttles table: %v", err)
+	}
 	_, err = testDB.Exec("DELETE FROM users")
 	if err != nil {
 		t.Fatalf("failed to cleanup users table: %v", err)
@@ -83,7 +88,7 @@ func cleanupUsers(t *testing.T) {
 func TestAuthHandler_Register_Success(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 
 	reqBody := `{"email": "test@example.com", "name": "Test User", "password": "password123"}`
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewBufferString(reqBody))
@@ -123,8 +128,8 @@ func TestAuthHandler_Register_Success(t *testing.T) {
 		t.Errorf("expected name 'Test User', got %v", data["name"])
 	}
 
-	if data["role"] != "user" {
-		t.Errorf("expected role 'user', got %v", data["role"])
+	if data["role"] != "surveyor" {
+		t.Errorf("expected role 'surveyor', got %v", data["role"])
 	}
 
 	// Verify password is not in response
@@ -149,7 +154,7 @@ func TestAuthHandler_Register_Success(t *testing.T) {
 func TestAuthHandler_Register_DuplicateEmail(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 
 	// First registration
 	reqBody := `{"email": "duplicate@example.com", "name": "First User", "password": "password123"}`
@@ -190,7 +195,7 @@ func TestAuthHandler_Register_DuplicateEmail(t *testing.T) {
 }
 
 func TestAuthHandler_Register_ValidationErrors(t *testing.T) {
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 
 	tests := []struct {
 		name           string
@@ -259,7 +264,7 @@ func TestAuthHandler_Register_ValidationErrors(t *testing.T) {
 }
 
 func TestAuthHandler_Register_InvalidJSON(t *testing.T) {
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewBufferString("not json"))
 	req.Header.Set("Content-Type", "application/json")
@@ -288,7 +293,7 @@ func TestAuthHandler_Register_InvalidJSON(t *testing.T) {
 func TestAuthHandler_Register_EmailNormalization(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 
 	// Register with uppercase email
 	reqBody := `{"email": "TEST@EXAMPLE.COM", "name": "Test User", "password": "password123"}`
@@ -358,7 +363,7 @@ func createTestUser(t *testing.T, handler *AuthHandler) {
 func TestAuthHandler_Login_Success(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 	createTestUser(t, handler)
 
 	reqBody := `{"email": "login@example.com", "password": "password123"}`
@@ -421,7 +426,7 @@ func TestAuthHandler_Login_Success(t *testing.T) {
 func TestAuthHandler_Login_InvalidEmail(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 	createTestUser(t, handler)
 
 	reqBody := `{"email": "nonexistent@example.com", "password": "password123"}`
@@ -453,7 +458,7 @@ func TestAuthHandler_Login_InvalidEmail(t *testing.T) {
 func TestAuthHandler_Login_InvalidPassword(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 	createTestUser(t, handler)
 
 	reqBody := `{"email": "login@example.com", "password": "wrongpassword"}`
@@ -485,7 +490,7 @@ func TestAuthHandler_Login_InvalidPassword(t *testing.T) {
 func TestAuthHandler_Refresh_Success(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 	createTestUser(t, handler)
 
 	// First, login to get tokens
@@ -552,7 +557,7 @@ func TestAuthHandler_Refresh_Success(t *testing.T) {
 func TestAuthHandler_Refresh_InvalidToken(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 
 	reqBody := `{"refresh_token": "invalid-token"}`
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", bytes.NewBufferString(reqBody))
@@ -582,7 +587,7 @@ func TestAuthHandler_Refresh_InvalidToken(t *testing.T) {
 func TestAuthHandler_Refresh_TokenRotation(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 	createTestUser(t, handler)
 
 	// Login to get tokens
@@ -656,7 +661,7 @@ func loginAndGetToken(t *testing.T, handler *AuthHandler) string {
 func TestAuthHandler_GetMe_Success(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 	createTestUser(t, handler)
 	accessToken := loginAndGetToken(t, handler)
 
@@ -702,7 +707,7 @@ func TestAuthHandler_GetMe_Success(t *testing.T) {
 func TestAuthHandler_GetMe_NoToken(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 	router := createTestRouter(handler)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
@@ -732,7 +737,7 @@ func TestAuthHandler_GetMe_NoToken(t *testing.T) {
 func TestAuthHandler_GetMe_InvalidToken(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 	router := createTestRouter(handler)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
@@ -762,7 +767,7 @@ func TestAuthHandler_GetMe_InvalidToken(t *testing.T) {
 func TestAuthHandler_GetMe_ExpiredToken(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 	createTestUser(t, handler)
 
 	// Create an expired JWT manager
@@ -775,7 +780,7 @@ func TestAuthHandler_GetMe_ExpiredToken(t *testing.T) {
 
 	// Get user to generate expired token
 	user, _ := testQueries.GetUserByEmail(context.Background(), "login@example.com")
-	expiredToken, _ := expiredJWTManager.GenerateAccessToken(user.ID, user.Email, user.Role.String)
+	expiredToken, _ := expiredJWTManager.GenerateAccessToken(user.ID, user.Email, user.Role.String, true, uuid.Nil)
 
 	router := createTestRouter(handler)
 
@@ -819,7 +824,7 @@ func createTestRouterWithLogout(handler *AuthHandler) *chi.Mux {
 func TestAuthHandler_Logout_Success(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 	createTestUser(t, handler)
 
 	// Login to get tokens
@@ -878,7 +883,7 @@ func TestAuthHandler_Logout_Success(t *testing.T) {
 func TestAuthHandler_Logout_NoToken(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 	router := createTestRouterWithLogout(handler)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil)
@@ -908,7 +913,7 @@ func TestAuthHandler_Logout_NoToken(t *testing.T) {
 func TestAuthHandler_Logout_RevokesAllSessions(t *testing.T) {
 	cleanupUsers(t)
 
-	handler := NewAuthHandler(testQueries, testJWTManager)
+	handler := NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{})
 	createTestUser(t, handler)
 
 	// Login twice to create two sessions
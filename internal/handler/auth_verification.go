@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/auth"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/mailer"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/middleware"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// sendEmailVerification issues a new verification token for email, replacing earlier
+// ones, and emails its link
+func (h *AuthHandler) sendEmailVerification(ctx context.Context, userID uuid.UUID, email, name string) error {
+	rawToken, tokenHash, expiresAt, err := auth.GenerateOneTimeToken(h.verificationExpiry)
+	if err != nil {
+		return err
+	}
+
+	if err := h.queries.InvalidateUserEmailVerificationTokens(ctx, userID); err != nil {
+		return err
+	}
+	if err := h.queries.CreateEmailVerificationToken(ctx, repository.CreateEmailVerificationTokenParams{
+		UserID:    userID,
+		Email:     email,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return err
+	}
+
+	link, err := tokenLink(h.verificationURL, rawToken)
+	if err != nil {
+		return err
+	}
+
+	// Finish sending even when the client disconnects, the token is already stored
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), mailTimeout)
+	defer cancel()
+
+	return h.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Verify your Bamboo Mapper email",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Open this link to verify the email of your Bamboo Mapper account:\n\n%s\n\n"+
+			"Until then you can view markers but not add or change them. "+
+			"The link can be used once and expires in %s. "+
+			"If you didn't create an account, you can ignore this email.\n",
+			name, link, formatExpiry(h.verificationExpiry)),
+	})
+}
+
+// VerifyEmail verifies the email of a user with a token from a verification email.
+// Access tokens issued before keep the unverified restriction until the next refresh.
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req model.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	if validationErrors := req.Validate(); len(validationErrors) > 0 {
+		respondError(w, http.StatusBadRequest, "Validation failed", validationErrors)
+		return
+	}
+
+	token, err := h.queries.UseEmailVerificationToken(r.Context(), auth.HashOneTimeToken(req.Token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusBadRequest, "Invalid or expired verification token", nil)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to verify email", nil)
+		return
+	}
+
+	// Matches no row when the email changed after the token was sent
+	user, err := h.queries.VerifyUserEmail(r.Context(), repository.VerifyUserEmailParams{
+		ID:    token.UserID,
+		Email: token.Email,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusBadRequest, "Invalid or expired verification token", nil)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to verify email", nil)
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, "Email verified successfully", userToResponse(repository.GetUserByIDRow(user)))
+}
+
+// ResendVerification emails a new verification link to the authenticated user
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetClaims(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	user, err := h.queries.GetUserByID(r.Context(), claims.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusUnauthorized, "User not found", nil)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch user", nil)
+		return
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		respondError(w, http.StatusConflict, "Email is already verified", nil)
+		return
+	}
+
+	if err := h.sendEmailVerification(r.Context(), user.ID, user.Email, user.Name); err != nil {
+		log.Printf("Failed to send email verification to user %s: %v", user.ID, err)
+		respondError(w, http.StatusInternalServerError, "Failed to send verification email", nil)
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, "Verification email sent", nil)
+}
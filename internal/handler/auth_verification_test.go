@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/auth"
+	appMiddleware "github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/middleware"
+)
+
+// loginTokens logs in the test user and returns the access and refresh tokens
+func loginTokens(t *testing.T, handler *AuthHandler) (string, string) {
+	t.Helper()
+	rr := postAuth(handler.Login, "/api/v1/auth/login", `{"email": "login@example.com", "password": "password123"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("login failed: %s", rr.Body.String())
+	}
+	var response Response
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	data := response.Data.(map[string]interface{})
+	return data["access_token"].(string), data["refresh_token"].(string)
+}
+
+// serveWithVerifiedEmail runs a request with an access token through RequireVerifiedEmail
+func serveWithVerifiedEmail(accessToken string) int {
+	r := http.NewServeMux()
+	r.Handle("/", appMiddleware.JWTAuth(testJWTManager)(appMiddleware.RequireVerifiedEmail(true)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }))))
+
+	req := httptest.NewRequest(http.MethodPost, "/markers", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr.Code
+}
+
+func TestAuthHandler_VerifyEmail_Success(t *testing.T) {
+	cleanupUsers(t)
+
+	handler, m := newMailingAuthHandler()
+	createTestUser(t, handler)
+	token := tokenFromMail(t, m)
+
+	// Unverified users are read-only
+	accessToken, refreshToken := loginTokens(t, handler)
+	claims, err := testJWTManager.ValidateAccessToken(accessToken)
+	if err != nil {
+		t.Fatalf("invalid access token: %v", err)
+	}
+	if !claims.EmailUnverified {
+		t.Error("expected access token of an unverified user to be marked unverified")
+	}
+	if code := serveWithVerifiedEmail(accessToken); code != http.StatusForbidden {
+		t.Errorf("expected unverified user to get %d, got %d", http.StatusForbidden, code)
+	}
+
+	rr := postAuth(handler.VerifyEmail, "/api/v1/auth/verify-email", `{"token": "`+token+`"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var response Response
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if response.Data.(map[string]interface{})["email_verified_at"] == nil {
+		t.Error("expected email_verified_at to be set")
+	}
+
+	// The next refresh issues an unrestricted access token
+	rr = postAuth(handler.Refresh, "/api/v1/auth/refresh", `{"refresh_token": "`+refreshToken+`"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("refresh failed: %s", rr.Body.String())
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	accessToken = response.Data.(map[string]interface{})["access_token"].(string)
+	if code := serveWithVerifiedEmail(accessToken); code != http.StatusNoContent {
+		t.Errorf("expected verified user to get %d, got %d", http.StatusNoContent, code)
+	}
+
+	// The token is single-use
+	rr = postAuth(handler.VerifyEmail, "/api/v1/auth/verify-email", `{"token": "`+token+`"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected reused token to be rejected with %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestAuthHandler_VerifyEmail_InvalidToken(t *testing.T) {
+	handler, _ := newMailingAuthHandler()
+
+	rr := postAuth(handler.VerifyEmail, "/api/v1/auth/verify-email", `{"token": "not-a-token"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+}
+
+func TestAuthHandler_ResendVerification(t *testing.T) {
+	cleanupUsers(t)
+
+	handler, m := newMailingAuthHandler()
+	createTestUser(t, handler)
+	first := tokenFromMail(t, m)
+
+	accessToken, _ := loginTokens(t, handler)
+	claims, _ := testJWTManager.ValidateAccessToken(accessToken)
+
+	resend := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/resend-verification", nil)
+		req = addRoleClaimsToContext(req, claims.UserID, auth.RoleSurveyor)
+		rr := httptest.NewRecorder()
+		handler.ResendVerification(rr, req)
+		return rr.Code
+	}
+
+	if code := resend(); code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+
+	// The resent link replaces the first one
+	if rr := postAuth(handler.VerifyEmail, "/api/v1/auth/verify-email", `{"token": "`+first+`"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected replaced token to be rejected with %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+	if rr := postAuth(handler.VerifyEmail, "/api/v1/auth/verify-email", `{"token": "`+tokenFromMail(t, m)+`"}`); rr.Code != http.StatusOK {
+		t.Fatalf("expected resent token to verify, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if code := resend(); code != http.StatusConflict {
+		t.Errorf("expected status %d for a verified email, got %d", http.StatusConflict, code)
+	}
+}
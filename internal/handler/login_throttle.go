@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/auth"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
+)
+
+// Scopes of login throttles, failed logins are counted per account email and per client IP
+const (
+	throttleScopeEmail = "email"
+	throttleScopeIP    = "ip"
+)
+
+// loginThrottleCleanupInterval is how often failed logins delete the expired throttles
+const loginThrottleCleanupInterval = 10 * time.Minute
+
+// loginLockedUntil returns when the lockout of an email or client IP ends, or the zero
+// time when neither is locked out. Lookup failures let the login through.
+func (h *AuthHandler) loginLockedUntil(ctx context.Context, email, clientIP string) time.Time {
+	if !h.accountLockout.Enabled() && !h.ipLockout.Enabled() {
+		return time.Time{}
+	}
+
+	lockouts, err := h.queries.GetLoginLockouts(ctx, repository.GetLoginLockoutsParams{
+		Email: email,
+		Ip:    clientIP,
+	})
+	if err != nil {
+		log.Printf("Failed to check login lockout of %s from %s: %v", email, clientIP, err)
+		return time.Time{}
+	}
+
+	var lockedUntil time.Time
+	for _, lockout := range lockouts {
+		if lockout.LockedUntil.Time.After(lockedUntil) {
+			lockedUntil = lockout.LockedUntil.Time
+		}
+	}
+	return lockedUntil
+}
+
+// respondLoginFailed counts a failed login against the email and client IP, responding
+// with 429 when that started a lockout and with 401 otherwise
+func (h *AuthHandler) respondLoginFailed(w http.ResponseWriter, r *http.Request, email, clientIP string) {
+	h.deleteExpiredLoginThrottles(r.Context())
+
+	lockedUntil := h.recordLoginFailure(r.Context(), throttleScopeEmail, email, h.accountLockout)
+	if ipLockedUntil := h.recordLoginFailure(r.Context(), throttleScopeIP, clientIP, h.ipLockout); ipLockedUntil.After(lockedUntil) {
+		lockedUntil = ipLockedUntil
+	}
+
+	if !lockedUntil.IsZero() {
+		log.Printf("SECURITY: login locked out for email %s from %s until %s", email, clientIP, lockedUntil.Format(time.RFC3339))
+		respondLockedOut(w, lockedUntil)
+		return
+	}
+	respondError(w, http.StatusUnauthorized, "Invalid email or password", nil)
+}
+
+// recordLoginFailure counts a failed login for one scope, locking it out once the policy
+// says so. It returns the end of the new lockout, or the zero time.
+func (h *AuthHandler) recordLoginFailure(ctx context.Context, scope, subject string, policy auth.LockoutPolicy) time.Time {
+	if !policy.Enabled() {
+		return time.Time{}
+	}
+
+	failures, err := h.queries.RecordLoginFailure(ctx, repository.RecordLoginFailureParams{
+		Scope:       scope,
+		Subject:     subject,
+		ResetBefore: time.Now().Add(-h.loginFailureWindow),
+	})
+	if err != nil {
+		log.Printf("Failed to record login failure of %s %s: %v", scope, subject, err)
+		return time.Time{}
+	}
+
+	lockout := policy.Lockout(int(failures))
+	if lockout == 0 {
+		return time.Time{}
+	}
+
+	lockedUntil := time.Now().Add(lockout)
+	if err := h.queries.LockLogin(ctx, repository.LockLoginParams{
+		Scope:       scope,
+		Subject:     subject,
+		LockedUntil: sql.NullTime{Time: lockedUntil, Valid: true},
+	}); err != nil {
+		log.Printf("Failed to lock out login of %s %s: %v", scope, subject, err)
+		return time.Time{}
+	}
+	return lockedUntil
+}
+
+// deleteExpiredLoginThrottles drops the throttles that no longer count, at most once per
+// loginThrottleCleanupInterval per process, so the table doesn't grow with every IP seen
+func (h *AuthHandler) deleteExpiredLoginThrottles(ctx context.Context) {
+	last := h.lastThrottleCleanup.Load()
+	now := time.Now()
+	if now.Sub(time.Unix(0, last)) < loginThrottleCleanupInterval || !h.lastThrottleCleanup.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+
+	if err := h.queries.DeleteExpiredLoginThrottles(ctx, now.Add(-h.loginFailureWindow)); err != nil {
+		log.Printf("Failed to delete expired login throttles: %v", err)
+	}
+}
+
+// clearLoginFailures forgets the failed logins of an email after a successful login.
+// Failures of the client IP are kept, so logging into an own account can't reset them.
+func (h *AuthHandler) clearLoginFailures(ctx context.Context, email string) {
+	if !h.accountLockout.Enabled() {
+		return
+	}
+
+	_, err := h.queries.DeleteLoginThrottle(ctx, repository.DeleteLoginThrottleParams{
+		Scope:   throttleScopeEmail,
+		Subject: email,
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Failed to clear login failures of %s: %v", email, err)
+	}
+}
+
+// respondLockedOut responds with 429 and a Retry-After header in whole seconds
+func respondLockedOut(w http.ResponseWriter, lockedUntil time.Time) {
+	retryAfter := int(math.Ceil(time.Until(lockedUntil).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+	respondError(w, http.StatusTooManyRequests, "Too many failed login attempts, try again later", nil)
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/auth"
+)
+
+// newThrottledAuthHandler creates an AuthHandler locking emails after 3 and IPs after 5 failures
+func newThrottledAuthHandler() *AuthHandler {
+	return NewAuthHandler(testDB, testJWTManager, AuthHandlerConfig{
+		AccountLockout: auth.LockoutPolicy{MaxFailures: 3, BaseDelay: time.Minute, MaxDelay: time.Hour},
+		IPLockout:      auth.LockoutPolicy{MaxFailures: 5, BaseDelay: time.Minute, MaxDelay: time.Hour},
+	})
+}
+
+// loginAs attempts a login from the default test client IP
+func loginAs(handler *AuthHandler, email, password string) *httptest.ResponseRecorder {
+	return postAuth(handler.Login, "/api/v1/auth/login", `{"email": "`+email+`", "password": "`+password+`"}`)
+}
+
+func TestAuthHandler_Login_AccountLockout(t *testing.T) {
+	cleanupUsers(t)
+
+	handler := newThrottledAuthHandler()
+	createTestUser(t, handler)
+
+	for i := 0; i < 2; i++ {
+		if rr := loginAs(handler, "login@example.com", "wrong-password"); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected status %d, got %d", i+1, http.StatusUnauthorized, rr.Code)
+		}
+	}
+
+	rr := loginAs(handler, "Login@Example.com", "wrong-password")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+	}
+	retryAfter, err := strconv.Atoi(rr.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 || retryAfter > 60 {
+		t.Errorf("expected Retry-After of up to 60 seconds, got %q", rr.Header().Get("Retry-After"))
+	}
+
+	// The right password doesn't get through a lockout
+	if rr := loginAs(handler, "login@example.com", "password123"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("expected locked out login to fail with %d, got %d", http.StatusTooManyRequests, rr.Code)
+	}
+}
+
+func TestAuthHandler_Login_UnknownEmailIsThrottled(t *testing.T) {
+	cleanupUsers(t)
+
+	handler := newThrottledAuthHandler()
+
+	var rr *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		rr = loginAs(handler, "nobody@example.com", "password123")
+	}
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+	}
+}
+
+func TestAuthHandler_Login_IPLockout(t *testing.T) {
+	cleanupUsers(t)
+
+	handler := newThrottledAuthHandler()
+	createTestUser(t, handler)
+
+	// Spread over emails, so only the client IP reaches its limit
+	emails := []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"}
+	var rr *httptest.ResponseRecorder
+	for _, email := range emails {
+		rr = loginAs(handler, email, "password123")
+	}
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+	}
+
+	if rr := loginAs(handler, "login@example.com", "password123"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("expected other accounts to be blocked from the IP, got %d", rr.Code)
+	}
+}
+
+func TestAuthHandler_Login_SuccessResetsAccountFailures(t *testing.T) {
+	cleanupUsers(t)
+
+	handler := newThrottledAuthHandler()
+	createTestUser(t, handler)
+
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 2; i++ {
+			loginAs(handler, "login@example.com", "wrong-password")
+		}
+		if rr := loginAs(handler, "login@example.com", "password123"); rr.Code != http.StatusOK {
+			t.Fatalf("round %d: expected status %d, got %d", round+1, http.StatusOK, rr.Code)
+		}
+	}
+}
+
+func TestAdminHandler_LoginLockouts(t *testing.T) {
+	cleanupUsers(t)
+
+	handler := newThrottledAuthHandler()
+	createTestUser(t, handler)
+	for i := 0; i < 3; i++ {
+		loginAs(handler, "login@example.com", "wrong-password")
+	}
+
+	adminID := createTestUserWithRole(t, "admin@example.com", "Admin", auth.RoleAdmin)
+	router := createTestAdminRouter(NewAdminHandler(testQueries))
+
+	rr := serveAdmin(router, adminID, http.MethodGet, "/admin/lockouts", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var response struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if len(response.Data) != 1 || response.Data[0]["scope"] != "email" || response.Data[0]["subject"] != "login@example.com" {
+		t.Fatalf("expected the email lockout, got %v", response.Data)
+	}
+
+	if rr := serveAdmin(router, adminID, http.MethodDelete, "/admin/lockouts/user/login@example.com", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an invalid scope, got %d", http.StatusBadRequest, rr.Code)
+	}
+	if rr := serveAdmin(router, adminID, http.MethodDelete, "/admin/lockouts/email/login%40example.com", ""); rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if rr := serveAdmin(router, adminID, http.MethodDelete, "/admin/lockouts/email/login@example.com", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected cleared lockout to be gone, got %d", rr.Code)
+	}
+
+	if rr := loginAs(handler, "login@example.com", "password123"); rr.Code != http.StatusOK {
+		t.Errorf("expected login after clearing the lockout, got %d", rr.Code)
+	}
+}
+
+func TestAuthHandler_Login_DeletesExpiredThrottles(t *testing.T) {
+	cleanupUsers(t)
+
+	_, err := testDB.Exec(`
+		INSERT INTO login_throttles (scope, subject, failures, last_failure_at, locked_until) VALUES
+			('ip', '203.0.113.1', 2, NOW() - INTERVAL '2 hours', NULL),
+			('ip', '203.0.113.2', 9, NOW() - INTERVAL '2 hours', NOW() - INTERVAL '1 hour'),
+			('email', 'locked@example.com', 9, NOW() - INTERVAL '2 hours', NOW() + INTERVAL '1 hour'),
+			('email', 'recent@example.com', 1, NOW(), NULL)
+	`)
+	if err != nil {
+		t.Fatalf("failed to insert throttles: %v", err)
+	}
+
+	handler := newThrottledAuthHandler()
+	loginAs(handler, "nobody@example.com", "password123")
+
+	rows, err := testDB.Query("SELECT subject FROM login_throttles ORDER BY subject")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var subjects []string
+	for rows.Next() {
+		var subject string
+		rows.Scan(&subject)
+		subjects = append(subjects, subject)
+	}
+
+	expected := []string{"192.0.2.1", "locked@example.com", "nobody@example.com", "recent@example.com"}
+	if len(subjects) != len(expected) {
+		t.Fatalf("expected throttles %v, got %v", expected, subjects)
+	}
+	for i := range expected {
+		if subjects[i] != expected[i] {
+			t.Errorf("expected throttles %v, got %v", expected, subjects)
+			break
+		}
+	}
+}
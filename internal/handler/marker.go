@@ -1,40 +1,108 @@
 package handler
 
 import (
+	"archive/zip"
 	"bytes"
+	"context"
 	"database/sql"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
-
+	"strings"
+	"time"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/auth"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/branding"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/imaging"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/labels"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/markerimage"
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/middleware"
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/qr"
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/storage"
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/util"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
-	"github.com/yeqown/go-qrcode/v2"
-	"github.com/yeqown/go-qrcode/writer/standard"
 )
 
 const maxUploadSize = 10 << 20 // 10 MB
 
+// photoLocationWarningDistance is how far (in meters) submitted coordinates may be
+// from the photo's EXIF GPS position before the client is warned
+const photoLocationWarningDistance = 200.0
+
+// Marker image processing states
+const (
+	imageStatusPending = "pending"
+	imageStatusReady   = "ready"
+	imageStatusFailed  = "failed"
+)
+
+// maxQRArchiveMarkers limits how many QR codes one archive may contain
+const maxQRArchiveMarkers = 2000
+
+// imageCacheMaxAge is how long clients may cache a served marker image
+const imageCacheMaxAge = time.Hour
+
+// MarkerHandlerConfig holds the optional dependencies of a MarkerHandler
+type MarkerHandlerConfig struct {
+	// Storage stores marker images, nil disables image uploads
+	Storage storage.Storage
+	// ImageQueue processes uploads in the background, nil uploads during the request
+	ImageQueue *markerimage.Queue
+	// URLSigner signs image links so they can be opened without a bearer token, nil leaves links unsigned
+	URLSigner *auth.URLSigner
+	// DeepLinkBaseURL is the base URL encoded into marker QR codes
+	DeepLinkBaseURL string
+	// APIBaseURL is prefixed to image links, empty produces relative links
+	APIBaseURL string
+	// Brandings style QR codes and labels, nil renders plain black on white codes
+	Brandings *branding.Set
+	// AppLinks identifies the mobile apps linked from marker pages
+	AppLinks AppLinksConfig
+	// ScanHashKey keys the hashes of client IPs stored with marker scans
+	ScanHashKey []byte
+	// ShortCodeLength is the length of newly generated short codes, zero uses util.DefaultShortCodeLength
+	ShortCodeLength int
+}
+
 // MarkerHandler handles marker-related requests
 type MarkerHandler struct {
 	queries         *repository.Queries
-	gdrive          *storage.GDriveService
+	storage         storage.Storage
+	imageQueue      *markerimage.Queue
+	urlSigner       *auth.URLSigner
 	deepLinkBaseURL string
+	apiBaseURL      string
+	brandings       *branding.Set
+	appLinks        AppLinksConfig
+	scanHashKey     []byte
+	shortCodeLength int
 }
 
 // NewMarkerHandler creates a new MarkerHandler
-func NewMarkerHandler(queries *repository.Queries, gdrive *storage.GDriveService, deepLinkBaseURL string) *MarkerHandler {
+func NewMarkerHandler(queries *repository.Queries, cfg MarkerHandlerConfig) *MarkerHandler {
+	if cfg.ShortCodeLength == 0 {
+		cfg.ShortCodeLength = util.DefaultShortCodeLength
+	}
 	return &MarkerHandler{
 		queries:         queries,
-		gdrive:          gdrive,
-		deepLinkBaseURL: deepLinkBaseURL,
+		storage:         cfg.Storage,
+		imageQueue:      cfg.ImageQueue,
+		urlSigner:       cfg.URLSigner,
+		deepLinkBaseURL: cfg.DeepLinkBaseURL,
+		apiBaseURL:      cfg.APIBaseURL,
+		brandings:       cfg.Brandings,
+		appLinks:        cfg.AppLinks,
+		scanHashKey:     cfg.ScanHashKey,
+		shortCodeLength: cfg.ShortCodeLength,
 	}
 }
 
@@ -81,7 +149,7 @@ func (h *MarkerHandler) ListPaginated(w http.ResponseWriter, r *http.Request) {
 	// Convert to response models
 	response := make([]model.MarkerResponse, len(result.Markers))
 	for i, m := range result.Markers {
-		response[i] = markerToResponse(m)
+		response[i] = h.markerToResponse(m)
 	}
 
 	// Calculate pagination metadata
@@ -95,17 +163,28 @@ func (h *MarkerHandler) ListPaginated(w http.ResponseWriter, r *http.Request) {
 	respondPaginated(w, http.StatusOK, "Markers retrieved successfully", response, pagination)
 }
 
-// markerToResponse converts a repository.Marker to model.MarkerResponse
-func markerToResponse(m repository.Marker) model.MarkerResponse {
+// canEditMarker reports whether the user may update, delete or rotate the short code of a marker:
+// any marker with auth.PermMarkersEditAll, markers they created with auth.PermMarkersEditOwn
+func canEditMarker(claims *auth.Claims, marker repository.Marker) bool {
+	if claims.HasPermission(auth.PermMarkersEditAll) {
+		return true
+	}
+	return claims.HasPermission(auth.PermMarkersEditOwn) && marker.CreatorID == claims.UserID
+}
+
+// markerToResponse converts a repository.Marker to model.MarkerResponse.
+// Image URLs point at the API image endpoint rather than the storage backend.
+func (h *MarkerHandler) markerToResponse(m repository.Marker) model.MarkerResponse {
 	response := model.MarkerResponse{
-		ID:        m.ID,
-		ShortCode: m.ShortCode,
-		CreatorID: m.CreatorID,
-		Name:      m.Name,
-		Latitude:  m.Latitude,
-		Longitude: m.Longitude,
-		CreatedAt: m.CreatedAt.Time,
-		UpdatedAt: m.UpdatedAt.Time,
+		ID:         m.ID,
+		ShortCode:  m.ShortCode,
+		CreatorID:  m.CreatorID,
+		Name:       m.Name,
+		Latitude:   m.Latitude,
+		Longitude:  m.Longitude,
+		Visibility: m.Visibility,
+		CreatedAt:  m.CreatedAt.Time,
+		UpdatedAt:  m.UpdatedAt.Time,
 	}
 
 	if m.Description.Valid {
@@ -118,7 +197,13 @@ func markerToResponse(m repository.Marker) model.MarkerResponse {
 		response.Quantity = &m.Quantity.Int32
 	}
 	if m.ImageUrl.Valid {
-		response.ImageURL = &m.ImageUrl.String
+		response.ImageURL = h.imageLink(m.ID, imaging.VariantOriginal)
+	}
+	if m.ImageThumbnailUrl.Valid {
+		response.ImageThumbnailURL = h.imageLink(m.ID, imaging.VariantThumbnail)
+	}
+	if m.ImageMediumUrl.Valid {
+		response.ImageMediumURL = h.imageLink(m.ID, imaging.VariantMedium)
 	}
 	if m.OwnerName.Valid {
 		response.OwnerName = &m.OwnerName.String
@@ -126,6 +211,12 @@ func markerToResponse(m repository.Marker) model.MarkerResponse {
 	if m.OwnerContact.Valid {
 		response.OwnerContact = &m.OwnerContact.String
 	}
+	if m.ImageCapturedAt.Valid {
+		response.ImageCapturedAt = &m.ImageCapturedAt.Time
+	}
+	if m.ImageStatus.Valid {
+		response.ImageStatus = &m.ImageStatus.String
+	}
 
 	return response
 }
@@ -149,50 +240,105 @@ func (h *MarkerHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert to response model with nullable fields
-	response := model.MarkerResponse{
-		ID:        marker.ID,
-		ShortCode: marker.ShortCode,
-		CreatorID: marker.CreatorID,
-		Name:      marker.Name,
-		Latitude:  marker.Latitude,
-		Longitude: marker.Longitude,
-		CreatedAt: marker.CreatedAt.Time,
-		UpdatedAt: marker.UpdatedAt.Time,
+	response := h.markerToResponse(marker)
+
+	respondSuccess(w, http.StatusOK, "Marker retrieved successfully", response)
+}
+
+// GetByShortCode returns marker details by short_code (for QR code scanning).
+// Authenticated callers get the full details, others the public view allowed by
+// the marker's visibility. Every lookup is recorded as a scan.
+func (h *MarkerHandler) GetByShortCode(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+	if shortCode == "" {
+		respondError(w, http.StatusBadRequest, "Short code is required", nil)
+		return
 	}
 
-	// Handle nullable fields
-	if marker.Description.Valid {
-		response.Description = &marker.Description.String
+	marker, err := h.findByShortCode(r.Context(), shortCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if alias, ok := h.retiredShortCode(r, shortCode); ok {
+				respondRetiredShortCode(w, r, alias)
+				return
+			}
+			respondShortCodeNotFound(w, shortCode)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch marker", nil)
+		return
 	}
-	if marker.Strain.Valid {
-		response.Strain = &marker.Strain.String
+
+	// Unauthenticated callers get the redacted public view, and nothing of private markers
+	_, authenticated := middleware.GetClaims(r.Context())
+	if !authenticated && marker.Visibility == model.VisibilityPrivate {
+		respondError(w, http.StatusNotFound, "Marker not found", nil)
+		return
 	}
-	if marker.Quantity.Valid {
-		response.Quantity = &marker.Quantity.Int32
+
+	h.recordScan(r, marker.ID, scanSourceApp)
+
+	response := h.markerToResponse(marker)
+	if !authenticated {
+		respondSuccess(w, http.StatusOK, "Marker retrieved successfully", response.PublicView())
+		return
 	}
-	if marker.ImageUrl.Valid {
-		response.ImageURL = &marker.ImageUrl.String
+
+	respondSuccess(w, http.StatusOK, "Marker retrieved successfully", response)
+}
+
+// findByShortCode looks a marker up by a typed short code, tolerating case,
+// hyphens and confusable characters. Codes are matched as given first so
+// codes issued before the Crockford alphabet keep working.
+func (h *MarkerHandler) findByShortCode(ctx context.Context, shortCode string) (repository.Marker, error) {
+	for _, candidate := range util.ShortCodeCandidates(shortCode) {
+		marker, err := h.queries.GetMarkerByShortCode(ctx, candidate)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return marker, err
+		}
 	}
-	if marker.OwnerName.Valid {
-		response.OwnerName = &marker.OwnerName.String
+	return repository.Marker{}, sql.ErrNoRows
+}
+
+// respondShortCodeNotFound reports an unknown short code, pointing out a likely typo
+// when the code has the shape of a current code but its check character doesn't match
+func respondShortCodeNotFound(w http.ResponseWriter, shortCode string) {
+	if util.MistypedShortCode(shortCode) {
+		respondError(w, http.StatusNotFound, "Marker not found", map[string]string{
+			"short_code": "Short code check character doesn't match, the code may be mistyped",
+		})
+		return
 	}
-	if marker.OwnerContact.Valid {
-		response.OwnerContact = &marker.OwnerContact.String
+	respondError(w, http.StatusNotFound, "Marker not found", nil)
+}
+
+// GetImage streams a marker image variant from storage.
+// Access requires a bearer token or a valid signature from an image link.
+func (h *MarkerHandler) GetImage(w http.ResponseWriter, r *http.Request) {
+	idParam := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid marker ID", nil)
+		return
 	}
 
-	respondSuccess(w, http.StatusOK, "Marker retrieved successfully", response)
-}
+	variant := imaging.Variant(r.URL.Query().Get("variant"))
+	if variant == "" {
+		variant = imaging.VariantOriginal
+	}
+	if variant != imaging.VariantOriginal && variant != imaging.VariantMedium && variant != imaging.VariantThumbnail {
+		respondError(w, http.StatusBadRequest, "Invalid image variant", map[string]string{
+			"variant": "Must be one of: original, medium, thumbnail",
+		})
+		return
+	}
 
-// GetByShortCode returns full marker details by short_code (for QR code scanning)
-func (h *MarkerHandler) GetByShortCode(w http.ResponseWriter, r *http.Request) {
-	shortCode := chi.URLParam(r, "shortCode")
-	if shortCode == "" {
-		respondError(w, http.StatusBadRequest, "Short code is required", nil)
+	if !h.canAccessImage(r, id, variant) {
+		respondError(w, http.StatusUnauthorized, "Unauthorized", nil)
 		return
 	}
 
-	marker, err := h.queries.GetMarkerByShortCode(r.Context(), shortCode)
+	marker, err := h.queries.GetMarkerByID(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			respondError(w, http.StatusNotFound, "Marker not found", nil)
@@ -202,39 +348,88 @@ func (h *MarkerHandler) GetByShortCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert to response model with nullable fields
-	response := model.MarkerResponse{
-		ID:        marker.ID,
-		ShortCode: marker.ShortCode,
-		CreatorID: marker.CreatorID,
-		Name:      marker.Name,
-		Latitude:  marker.Latitude,
-		Longitude: marker.Longitude,
-		CreatedAt: marker.CreatedAt.Time,
-		UpdatedAt: marker.UpdatedAt.Time,
+	var stored sql.NullString
+	switch variant {
+	case imaging.VariantOriginal:
+		stored = marker.ImageUrl
+	case imaging.VariantMedium:
+		stored = marker.ImageMediumUrl
+	case imaging.VariantThumbnail:
+		stored = marker.ImageThumbnailUrl
+	}
+	fileID := ""
+	if stored.Valid {
+		fileID = storage.ExtractFileID(stored.String)
+	}
+	if fileID == "" {
+		respondError(w, http.StatusNotFound, "Image not found", nil)
+		return
 	}
 
-	// Handle nullable fields
-	if marker.Description.Valid {
-		response.Description = &marker.Description.String
+	if h.storage == nil {
+		respondError(w, http.StatusServiceUnavailable, "Image storage is not configured", nil)
+		return
 	}
-	if marker.Strain.Valid {
-		response.Strain = &marker.Strain.String
+
+	// Stored files are immutable (a new upload gets a new file ID), so the ID is a strong ETag
+	etag := `"` + fileID + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(imageCacheMaxAge.Seconds())))
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
-	if marker.Quantity.Valid {
-		response.Quantity = &marker.Quantity.Int32
+
+	body, contentType, size, err := h.storage.OpenFile(fileID)
+	if err != nil {
+		log.Printf("Failed to fetch image from storage: %v", err)
+		respondError(w, http.StatusBadGateway, "Failed to fetch image", nil)
+		return
 	}
-	if marker.ImageUrl.Valid {
-		response.ImageURL = &marker.ImageUrl.String
+	defer body.Close()
+
+	if contentType == "" {
+		contentType = imaging.ContentType
 	}
-	if marker.OwnerName.Valid {
-		response.OwnerName = &marker.OwnerName.String
+	w.Header().Set("Content-Type", contentType)
+	if size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
 	}
-	if marker.OwnerContact.Valid {
-		response.OwnerContact = &marker.OwnerContact.String
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, body); err != nil {
+		log.Printf("Failed to stream image: %v", err)
 	}
+}
 
-	respondSuccess(w, http.StatusOK, "Marker retrieved successfully", response)
+// canAccessImage reports whether the request is authenticated or carries a valid image signature
+func (h *MarkerHandler) canAccessImage(r *http.Request, id uuid.UUID, variant imaging.Variant) bool {
+	if _, ok := middleware.GetClaims(r.Context()); ok {
+		return true
+	}
+	if h.urlSigner == nil {
+		return false
+	}
+	query := r.URL.Query()
+	return h.urlSigner.Verify(markerImageResource(id, variant), query.Get("expires"), query.Get("signature"))
+}
+
+// imageLink builds the API URL of a marker image variant, signed when a signer is configured
+func (h *MarkerHandler) imageLink(id uuid.UUID, variant imaging.Variant) *string {
+	query := url.Values{}
+	query.Set("variant", string(variant))
+	if h.urlSigner != nil {
+		expires, signature := h.urlSigner.Sign(markerImageResource(id, variant))
+		query.Set("expires", strconv.FormatInt(expires, 10))
+		query.Set("signature", signature)
+	}
+
+	link := fmt.Sprintf("%s/v1/markers/%s/image?%s", h.apiBaseURL, id, query.Encode())
+	return &link
+}
+
+// markerImageResource is the signed resource name of a marker image variant
+func markerImageResource(id uuid.UUID, variant imaging.Variant) string {
+	return fmt.Sprintf("marker-image:%s:%s", id, variant)
 }
 
 // Create handles creating a new marker with optional image upload
@@ -272,6 +467,9 @@ func (h *MarkerHandler) Create(w http.ResponseWriter, r *http.Request) {
 	if ownerContact := r.FormValue("owner_contact"); ownerContact != "" {
 		req.OwnerContact = &ownerContact
 	}
+	if visibility := r.FormValue("visibility"); visibility != "" {
+		req.Visibility = &visibility
+	}
 
 	// Handle optional quantity field
 	if qtyStr := r.FormValue("quantity"); qtyStr != "" {
@@ -286,6 +484,18 @@ func (h *MarkerHandler) Create(w http.ResponseWriter, r *http.Request) {
 		req.Quantity = &qty32
 	}
 
+	// Read image upload (optional) before validation so EXIF GPS can fill missing coordinates
+	imageData, ok := readImageUpload(w, r)
+	if !ok {
+		return
+	}
+	var photoMeta imaging.Metadata
+	var notices map[string]string
+	if imageData != nil {
+		photoMeta = imaging.ReadMetadata(imageData)
+		notices = applyPhotoLocation(&req, photoMeta)
+	}
+
 	// Validate request
 	if validationErrors := req.Validate(); len(validationErrors) > 0 {
 		respondError(w, http.StatusBadRequest, "Validation failed", validationErrors)
@@ -293,83 +503,124 @@ func (h *MarkerHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate short code first (needed for image filename)
-	shortCode := util.GenerateShortCode()
+	shortCode, err := h.newShortCode(r.Context())
+	if err != nil {
+		log.Printf("Failed to generate short code: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to create marker", nil)
+		return
+	}
 
 	// Handle image upload (optional)
-	var imageURL sql.NullString
-	file, header, err := r.FormFile("image")
-	if err == nil {
-		defer file.Close()
-
-		// Upload to Google Drive with short_code as filename
-		if h.gdrive != nil {
-			// Get file extension from original filename
-			ext := getFileExtension(header.Filename)
-			filename := shortCode + ext
-
-			url, uploadErr := h.gdrive.UploadFile(file, filename, header.Header.Get("Content-Type"))
+	var images markerimage.URLs
+	var imageStatus sql.NullString
+	var imageJobID uuid.NullUUID
+	if imageData != nil {
+		switch {
+		case h.storage != nil && h.imageQueue != nil:
+			// Spool the image and let the queue upload it after responding
+			jobID, ok := h.spoolImage(w, imageData)
+			if !ok {
+				return
+			}
+			imageJobID = uuid.NullUUID{UUID: jobID, Valid: true}
+			imageStatus = sql.NullString{String: imageStatusPending, Valid: true}
+		case h.storage != nil:
+			// Upload to storage with short_code as filename
+			var uploadErr error
+			images, uploadErr = markerimage.Upload(h.storage, imageData, shortCode)
 			if uploadErr != nil {
-				log.Printf("Failed to upload image to Google Drive: %v", uploadErr)
-				respondError(w, http.StatusInternalServerError, "Failed to upload image", nil)
+				respondUploadError(w, uploadErr)
 				return
 			}
-			imageURL = sql.NullString{String: url, Valid: true}
-		} else {
-			log.Println("Image provided but Google Drive service not configured")
+			imageStatus = sql.NullString{String: imageStatusReady, Valid: true}
+		default:
+			log.Println("Image provided but image storage not configured")
 		}
 	}
+	var capturedAt sql.NullTime
+	if imageStatus.Valid {
+		capturedAt = toNullTime(photoMeta.CapturedAt)
+	}
+
+	visibility := model.DefaultVisibility
+	if req.Visibility != nil {
+		visibility = *req.Visibility
+	}
 
 	// Create marker in database
-	marker, err := h.queries.CreateMarker(r.Context(), repository.CreateMarkerParams{
-		ShortCode:    shortCode,
-		CreatorID:    claims.UserID,
-		Name:         req.Name,
-		Description:  toNullString(req.Description),
-		Strain:       toNullString(req.Strain),
-		Quantity:     toNullInt32(req.Quantity),
-		Latitude:     req.Latitude,
-		Longitude:    req.Longitude,
-		ImageUrl:     imageURL,
-		OwnerName:    toNullString(req.OwnerName),
-		OwnerContact: toNullString(req.OwnerContact),
+	params := repository.CreateMarkerParams{
+		ShortCode:         shortCode,
+		CreatorID:         claims.UserID,
+		Name:              req.Name,
+		Description:       toNullString(req.Description),
+		Strain:            toNullString(req.Strain),
+		Quantity:          toNullInt32(req.Quantity),
+		Latitude:          req.Latitude,
+		Longitude:         req.Longitude,
+		ImageUrl:          images.Original,
+		OwnerName:         toNullString(req.OwnerName),
+		OwnerContact:      toNullString(req.OwnerContact),
+		ImageThumbnailUrl: images.Thumbnail,
+		ImageMediumUrl:    images.Medium,
+		ImageCapturedAt:   capturedAt,
+		ImageStatus:       imageStatus,
+		ImageJobID:        imageJobID,
+		Visibility:        visibility,
+	}
+	var marker repository.Marker
+	err = h.retryShortCode(r.Context(), shortCode, func(shortCode string) error {
+		params.ShortCode = shortCode
+		var err error
+		marker, err = h.queries.CreateMarker(r.Context(), params)
+		return err
 	})
 	if err != nil {
+		log.Printf("Failed to create marker: %v", err)
+		// The marker was never written, so the uploaded or spooled image is unreferenced
+		h.discardImages(images, imageJobID)
 		respondError(w, http.StatusInternalServerError, "Failed to create marker", nil)
 		return
 	}
 
-	// Build response
-	response := model.MarkerResponse{
-		ID:        marker.ID,
-		ShortCode: marker.ShortCode,
-		CreatorID: marker.CreatorID,
-		Name:      marker.Name,
-		Latitude:  marker.Latitude,
-		Longitude: marker.Longitude,
-		CreatedAt: marker.CreatedAt.Time,
-		UpdatedAt: marker.UpdatedAt.Time,
+	if imageJobID.Valid {
+		h.enqueueImage(r.Context(), imageJobID.UUID, &marker)
 	}
 
-	if marker.Description.Valid {
-		response.Description = &marker.Description.String
-	}
-	if marker.Strain.Valid {
-		response.Strain = &marker.Strain.String
-	}
-	if marker.Quantity.Valid {
-		response.Quantity = &marker.Quantity.Int32
+	response := h.markerToResponse(marker)
+
+	respondSuccessWithDetails(w, http.StatusCreated, "Marker created successfully", response, notices)
+}
+
+// applyPhotoLocation fills missing coordinates from the photo's EXIF GPS position,
+// or flags submitted coordinates that are far from it. Returns notices for the client.
+func applyPhotoLocation(req *model.CreateMarkerRequest, meta imaging.Metadata) map[string]string {
+	if meta.GPS == nil {
+		return nil
 	}
-	if marker.ImageUrl.Valid {
-		response.ImageURL = &marker.ImageUrl.String
+
+	if req.Latitude == "" && req.Longitude == "" {
+		req.Latitude = strconv.FormatFloat(meta.GPS.Latitude, 'f', 8, 64)
+		req.Longitude = strconv.FormatFloat(meta.GPS.Longitude, 'f', 8, 64)
+		return map[string]string{
+			"location": "Coordinates were filled from the photo's GPS data",
+		}
 	}
-	if marker.OwnerName.Valid {
-		response.OwnerName = &marker.OwnerName.String
+
+	lat, latErr := strconv.ParseFloat(req.Latitude, 64)
+	lng, lngErr := strconv.ParseFloat(req.Longitude, 64)
+	if latErr != nil || lngErr != nil {
+		return nil
 	}
-	if marker.OwnerContact.Valid {
-		response.OwnerContact = &marker.OwnerContact.String
+
+	distance := util.DistanceMeters(lat, lng, meta.GPS.Latitude, meta.GPS.Longitude)
+	if distance <= photoLocationWarningDistance {
+		return nil
 	}
 
-	respondSuccess(w, http.StatusCreated, "Marker created successfully", response)
+	log.Printf("Submitted coordinates %s,%s are %.0fm from photo GPS position", req.Latitude, req.Longitude, distance)
+	return map[string]string{
+		"location": fmt.Sprintf("Submitted coordinates are %.0f m from where the photo was taken", distance),
+	}
 }
 
 // Helper functions for nullable types
@@ -380,6 +631,13 @@ func toNullString(s *string) sql.NullString {
 	return sql.NullString{String: *s, Valid: true}
 }
 
+func toNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{Valid: false}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
 func toNullInt32(i *int32) sql.NullInt32 {
 	if i == nil {
 		return sql.NullInt32{Valid: false}
@@ -387,30 +645,124 @@ func toNullInt32(i *int32) sql.NullInt32 {
 	return sql.NullInt32{Int32: *i, Valid: true}
 }
 
-// getFileExtension extracts the file extension from a filename (e.g., ".jpg")
-func getFileExtension(filename string) string {
-	for i := len(filename) - 1; i >= 0; i-- {
-		if filename[i] == '.' {
-			return filename[i:]
-		}
+// readImageUpload reads the optional "image" form file and verifies it is an image.
+// Returns (nil, true) when no image was sent and (_, false) after writing an error response.
+func readImageUpload(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		return nil, true
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxUploadSize+1))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Failed to read image", nil)
+		return nil, false
+	}
+	if len(data) > maxUploadSize {
+		respondError(w, http.StatusBadRequest, "Validation failed", map[string]string{
+			"image": "Image must be 10 MB or smaller",
+		})
+		return nil, false
+	}
+
+	// Sniff the real content type instead of trusting the client-declared one
+	if _, err := imaging.DetectContentType(data); err != nil {
+		respondError(w, http.StatusBadRequest, "Validation failed", map[string]string{
+			"image": "Image must be a JPEG, PNG, GIF or WebP file",
+		})
+		return nil, false
+	}
+
+	return data, true
+}
+
+// deleteMarkerImages removes all stored image variants of a marker.
+// Failures are logged only - the file might already be deleted.
+func (h *MarkerHandler) deleteMarkerImages(m repository.Marker) {
+	markerimage.Delete(h.storage, markerimage.URLs{
+		Original:  m.ImageUrl,
+		Medium:    m.ImageMediumUrl,
+		Thumbnail: m.ImageThumbnailUrl,
+	})
+}
+
+// spoolImage validates an upload and writes it to the image queue's spool directory.
+// Writes an error response and returns false on failure.
+func (h *MarkerHandler) spoolImage(w http.ResponseWriter, data []byte) (uuid.UUID, bool) {
+	// Processing happens later, so catch undecodable files while the client is still waiting
+	if _, err := imaging.Validate(data); err != nil {
+		respondUploadError(w, err)
+		return uuid.Nil, false
+	}
+
+	jobID, err := h.imageQueue.Spool(data)
+	if err != nil {
+		log.Printf("Failed to spool image: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to upload image", nil)
+		return uuid.Nil, false
+	}
+	return jobID, true
+}
+
+// enqueueImage schedules a spooled image for processing.
+// If that fails the spooled image is dropped and the marker's image is marked failed.
+func (h *MarkerHandler) enqueueImage(ctx context.Context, jobID uuid.UUID, m *repository.Marker) {
+	err := h.imageQueue.Enqueue(markerimage.Job{
+		ID:        jobID,
+		MarkerID:  m.ID,
+		ShortCode: m.ShortCode,
+	})
+	if err == nil {
+		return
+	}
+
+	log.Printf("Failed to enqueue image for marker %s: %v", m.ID, err)
+	h.imageQueue.Discard(jobID)
+	err = h.queries.FailMarkerImage(ctx, repository.FailMarkerImageParams{
+		ID:         m.ID,
+		ImageJobID: uuid.NullUUID{UUID: jobID, Valid: true},
+	})
+	if err != nil {
+		log.Printf("Failed to mark image of marker %s as failed: %v", m.ID, err)
+		return
+	}
+	m.ImageStatus = sql.NullString{String: imageStatusFailed, Valid: true}
+	m.ImageJobID = uuid.NullUUID{}
+}
+
+// discardImages removes images uploaded or spooled for a write that did not commit
+func (h *MarkerHandler) discardImages(images markerimage.URLs, jobID uuid.NullUUID) {
+	if h.storage != nil {
+		markerimage.Delete(h.storage, images)
+	}
+	if jobID.Valid && h.imageQueue != nil {
+		h.imageQueue.Discard(jobID.UUID)
 	}
-	return ""
 }
 
-// extractGDriveFileID extracts the file ID from a Google Drive URL
-// Format: https://drive.google.com/uc?id=FILE_ID
-func extractGDriveFileID(url string) string {
-	const prefix = "https://drive.google.com/uc?id="
-	if len(url) > len(prefix) && url[:len(prefix)] == prefix {
-		return url[len(prefix):]
+// respondUploadError writes the response for a failed image upload
+func respondUploadError(w http.ResponseWriter, err error) {
+	if errors.Is(err, imaging.ErrUnsupportedFormat) {
+		respondError(w, http.StatusBadRequest, "Validation failed", map[string]string{
+			"image": "Image could not be decoded",
+		})
+		return
 	}
-	return ""
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		respondError(w, http.StatusBadRequest, "Validation failed", map[string]string{
+			"image": fmt.Sprintf("Image must be at most %d megapixels", imaging.MaxPixels/1_000_000),
+		})
+		return
+	}
+	log.Printf("Failed to upload image to storage: %v", err)
+	respondError(w, http.StatusInternalServerError, "Failed to upload image", nil)
 }
 
 // Update handles updating an existing marker
 func (h *MarkerHandler) Update(w http.ResponseWriter, r *http.Request) {
 	// Ensure user is authenticated
-	_, ok := middleware.GetClaims(r.Context())
+	claims, ok := middleware.GetClaims(r.Context())
 	if !ok {
 		respondError(w, http.StatusUnauthorized, "Unauthorized", nil)
 		return
@@ -434,6 +786,10 @@ func (h *MarkerHandler) Update(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "Failed to fetch marker", nil)
 		return
 	}
+	if !canEditMarker(claims, existingMarker) {
+		respondError(w, http.StatusForbidden, "You can only change markers you created", nil)
+		return
+	}
 
 	// Parse multipart form with size limit
 	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
@@ -465,6 +821,9 @@ func (h *MarkerHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if ownerContact := r.FormValue("owner_contact"); ownerContact != "" {
 		req.OwnerContact = &ownerContact
 	}
+	if visibility := r.FormValue("visibility"); visibility != "" {
+		req.Visibility = &visibility
+	}
 
 	// Handle optional quantity field
 	if qtyStr := r.FormValue("quantity"); qtyStr != "" {
@@ -487,16 +846,22 @@ func (h *MarkerHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 	// Prepare update params - use existing values for fields not provided
 	updateParams := repository.UpdateMarkerParams{
-		ID:           id,
-		Name:         existingMarker.Name,
-		Description:  existingMarker.Description,
-		Strain:       existingMarker.Strain,
-		Quantity:     existingMarker.Quantity,
-		Latitude:     existingMarker.Latitude,
-		Longitude:    existingMarker.Longitude,
-		ImageUrl:     existingMarker.ImageUrl,
-		OwnerName:    existingMarker.OwnerName,
-		OwnerContact: existingMarker.OwnerContact,
+		ID:                id,
+		Name:              existingMarker.Name,
+		Description:       existingMarker.Description,
+		Strain:            existingMarker.Strain,
+		Quantity:          existingMarker.Quantity,
+		Latitude:          existingMarker.Latitude,
+		Longitude:         existingMarker.Longitude,
+		ImageUrl:          existingMarker.ImageUrl,
+		OwnerName:         existingMarker.OwnerName,
+		OwnerContact:      existingMarker.OwnerContact,
+		ImageThumbnailUrl: existingMarker.ImageThumbnailUrl,
+		ImageMediumUrl:    existingMarker.ImageMediumUrl,
+		ImageCapturedAt:   existingMarker.ImageCapturedAt,
+		ImageStatus:       existingMarker.ImageStatus,
+		ImageJobID:        existingMarker.ImageJobID,
+		Visibility:        existingMarker.Visibility,
 	}
 
 	// Override with provided values
@@ -524,37 +889,47 @@ func (h *MarkerHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.OwnerContact != nil {
 		updateParams.OwnerContact = toNullString(req.OwnerContact)
 	}
+	if req.Visibility != nil {
+		updateParams.Visibility = *req.Visibility
+	}
 
 	// Handle image upload (optional)
-	file, header, err := r.FormFile("image")
-	if err == nil {
-		defer file.Close()
-
-		if h.gdrive != nil {
-			// Delete old image if exists
-			if existingMarker.ImageUrl.Valid {
-				oldFileID := extractGDriveFileID(existingMarker.ImageUrl.String)
-				if oldFileID != "" {
-					if deleteErr := h.gdrive.DeleteFile(oldFileID); deleteErr != nil {
-						log.Printf("Failed to delete old image from Google Drive: %v", deleteErr)
-						// Continue anyway - old file might already be deleted
-					}
-				}
+	imageData, ok := readImageUpload(w, r)
+	if !ok {
+		return
+	}
+	var newImages markerimage.URLs
+	var spooledJob uuid.NullUUID
+	if imageData != nil {
+		switch {
+		case h.storage != nil && h.imageQueue != nil:
+			// Spool the image; the old one stays visible until the queue replaces it
+			jobID, ok := h.spoolImage(w, imageData)
+			if !ok {
+				return
 			}
-
-			// Upload new image with short_code as filename
-			ext := getFileExtension(header.Filename)
-			filename := existingMarker.ShortCode + ext
-
-			url, uploadErr := h.gdrive.UploadFile(file, filename, header.Header.Get("Content-Type"))
+			spooledJob = uuid.NullUUID{UUID: jobID, Valid: true}
+			updateParams.ImageJobID = spooledJob
+			updateParams.ImageStatus = sql.NullString{String: imageStatusPending, Valid: true}
+			updateParams.ImageCapturedAt = toNullTime(imaging.ReadMetadata(imageData).CapturedAt)
+		case h.storage != nil:
+			// Upload new image with short_code as filename.
+			// Old images are kept until the update commits, so a failure never loses the photo.
+			images, uploadErr := markerimage.Upload(h.storage, imageData, existingMarker.ShortCode)
 			if uploadErr != nil {
-				log.Printf("Failed to upload image to Google Drive: %v", uploadErr)
-				respondError(w, http.StatusInternalServerError, "Failed to upload image", nil)
+				respondUploadError(w, uploadErr)
 				return
 			}
-			updateParams.ImageUrl = sql.NullString{String: url, Valid: true}
-		} else {
-			log.Println("Image provided but Google Drive service not configured")
+			newImages = images
+			updateParams.ImageUrl = images.Original
+			updateParams.ImageThumbnailUrl = images.Thumbnail
+			updateParams.ImageMediumUrl = images.Medium
+			updateParams.ImageCapturedAt = toNullTime(imaging.ReadMetadata(imageData).CapturedAt)
+			updateParams.ImageStatus = sql.NullString{String: imageStatusReady, Valid: true}
+			// Supersede any job still queued for the old image
+			updateParams.ImageJobID = uuid.NullUUID{}
+		default:
+			log.Println("Image provided but image storage not configured")
 		}
 	}
 
@@ -562,48 +937,29 @@ func (h *MarkerHandler) Update(w http.ResponseWriter, r *http.Request) {
 	marker, err := h.queries.UpdateMarker(r.Context(), updateParams)
 	if err != nil {
 		log.Printf("Failed to update marker: %v", err)
+		// The marker still points at the old images, so discard the new ones
+		h.discardImages(newImages, spooledJob)
 		respondError(w, http.StatusInternalServerError, "Failed to update marker", nil)
 		return
 	}
 
-	// Build response
-	response := model.MarkerResponse{
-		ID:        marker.ID,
-		ShortCode: marker.ShortCode,
-		CreatorID: marker.CreatorID,
-		Name:      marker.Name,
-		Latitude:  marker.Latitude,
-		Longitude: marker.Longitude,
-		CreatedAt: marker.CreatedAt.Time,
-		UpdatedAt: marker.UpdatedAt.Time,
-	}
-
-	if marker.Description.Valid {
-		response.Description = &marker.Description.String
-	}
-	if marker.Strain.Valid {
-		response.Strain = &marker.Strain.String
+	// The marker now points at the new images, so the replaced ones can go
+	if newImages.Original.Valid {
+		h.deleteMarkerImages(existingMarker)
 	}
-	if marker.Quantity.Valid {
-		response.Quantity = &marker.Quantity.Int32
-	}
-	if marker.ImageUrl.Valid {
-		response.ImageURL = &marker.ImageUrl.String
-	}
-	if marker.OwnerName.Valid {
-		response.OwnerName = &marker.OwnerName.String
-	}
-	if marker.OwnerContact.Valid {
-		response.OwnerContact = &marker.OwnerContact.String
+	if spooledJob.Valid {
+		h.enqueueImage(r.Context(), spooledJob.UUID, &marker)
 	}
 
+	response := h.markerToResponse(marker)
+
 	respondSuccess(w, http.StatusOK, "Marker updated successfully", response)
 }
 
 // Delete handles deleting an existing marker
 func (h *MarkerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	// Ensure user is authenticated
-	_, ok := middleware.GetClaims(r.Context())
+	claims, ok := middleware.GetClaims(r.Context())
 	if !ok {
 		respondError(w, http.StatusUnauthorized, "Unauthorized", nil)
 		return
@@ -627,16 +983,9 @@ func (h *MarkerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "Failed to fetch marker", nil)
 		return
 	}
-
-	// Delete image from Google Drive if exists
-	if existingMarker.ImageUrl.Valid && h.gdrive != nil {
-		fileID := extractGDriveFileID(existingMarker.ImageUrl.String)
-		if fileID != "" {
-			if deleteErr := h.gdrive.DeleteFile(fileID); deleteErr != nil {
-				log.Printf("Failed to delete image from Google Drive: %v", deleteErr)
-				// Continue anyway - don't fail the delete operation
-			}
-		}
+	if !canEditMarker(claims, existingMarker) {
+		respondError(w, http.StatusForbidden, "You can only change markers you created", nil)
+		return
 	}
 
 	// Delete marker from database
@@ -646,17 +995,16 @@ func (h *MarkerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondSuccess(w, http.StatusOK, "Marker deleted successfully", nil)
-}
+	// Delete images only once nothing refers to them
+	if h.storage != nil {
+		h.deleteMarkerImages(existingMarker)
+	}
 
-// nopCloser wraps bytes.Buffer to implement io.WriteCloser
-type nopCloser struct {
-	*bytes.Buffer
+	respondSuccess(w, http.StatusOK, "Marker deleted successfully", nil)
 }
 
-func (nopCloser) Close() error { return nil }
-
-// GenerateQR generates a QR code image for a marker
+// GenerateQR generates a QR code for a marker.
+// Query parameters: format (png, svg, pdf), size, ec (L, M, Q, H), margin and logo (a logo name or "none").
 func (h *MarkerHandler) GenerateQR(w http.ResponseWriter, r *http.Request) {
 	// Ensure user is authenticated
 	_, ok := middleware.GetClaims(r.Context())
@@ -673,6 +1021,12 @@ func (h *MarkerHandler) GenerateQR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	opts, details := h.parseQROptions(r.URL.Query())
+	if len(details) > 0 {
+		respondError(w, http.StatusBadRequest, "Invalid QR code options", details)
+		return
+	}
+
 	// Fetch marker to get short_code
 	marker, err := h.queries.GetMarkerByID(r.Context(), id)
 	if err != nil {
@@ -684,35 +1038,312 @@ func (h *MarkerHandler) GenerateQR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Build deep link URL
-	deepLink := fmt.Sprintf("%s/marker/%s", h.deepLinkBaseURL, marker.ShortCode)
-
-	// Generate QR code
-	qrc, err := qrcode.New(deepLink)
-	if err != nil {
-		log.Printf("Failed to create QR code: %v", err)
+	// Render into a buffer so a failure can still be reported as JSON
+	buf := &bytes.Buffer{}
+	if err := qr.Render(buf, h.deepLink(marker.ShortCode), opts); err != nil {
+		log.Printf("Failed to generate QR code: %v", err)
 		respondError(w, http.StatusInternalServerError, "Failed to generate QR code", nil)
 		return
 	}
 
-	// Write QR code to buffer (PNG format with logo)
+	w.Header().Set("Content-Type", qr.ContentType(opts.Format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s.%s\"", marker.ShortCode, opts.Format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// GenerateLabels renders a PDF sheet of QR code labels for a batch of markers.
+// Markers are selected by marker_ids in the body, or else by the marker list query parameters.
+func (h *MarkerHandler) GenerateLabels(w http.ResponseWriter, r *http.Request) {
+	// Ensure user is authenticated
+	_, ok := middleware.GetClaims(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	// An empty body selects markers by filters on the default template
+	var req model.MarkerLabelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	validationErrors := req.Validate()
+	if req.Template == "" {
+		req.Template = labels.DefaultTemplate
+	}
+	tmpl, ok := labels.Templates[req.Template]
+	if !ok {
+		validationErrors["template"] = "Template must be one of a4-3x8, letter-3x10"
+	}
+	b, ok := h.branding(req.Branding)
+	if !ok {
+		validationErrors["branding"] = "Unknown branding"
+	}
+	if len(validationErrors) > 0 {
+		respondError(w, http.StatusBadRequest, "Validation failed", validationErrors)
+		return
+	}
+
+	var markers []repository.Marker
+	if len(req.MarkerIDs) > 0 {
+		found, err := h.queries.GetMarkersByIDs(r.Context(), req.MarkerIDs)
+		if err != nil {
+			log.Printf("Failed to fetch markers for labels: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to fetch markers", nil)
+			return
+		}
+
+		// Print in the requested order
+		byID := make(map[uuid.UUID]repository.Marker, len(found))
+		for _, m := range found {
+			byID[m.ID] = m
+		}
+		var missing []string
+		for _, id := range req.MarkerIDs {
+			m, ok := byID[id]
+			if !ok {
+				missing = append(missing, id.String())
+				continue
+			}
+			markers = append(markers, m)
+		}
+		if len(missing) > 0 {
+			respondError(w, http.StatusNotFound, "Markers not found", map[string]string{
+				"marker_ids": strings.Join(missing, ", "),
+			})
+			return
+		}
+	} else {
+		params, err := ParseListMarkersParams(r)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid query parameters", nil)
+			return
+		}
+
+		// Fetch one more than allowed to detect an oversized selection
+		markers, err = h.queries.ListMarkersFiltered(r.Context(), params, model.MaxLabelsPerRequest+1)
+		if err != nil {
+			log.Printf("Failed to fetch markers for labels: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to fetch markers", nil)
+			return
+		}
+		if len(markers) == 0 {
+			respondError(w, http.StatusNotFound, "No markers match the filters", nil)
+			return
+		}
+		if len(markers) > model.MaxLabelsPerRequest {
+			respondError(w, http.StatusBadRequest, "Too many markers", map[string]string{
+				"filters": fmt.Sprintf("Filters match more than %d markers, narrow them down", model.MaxLabelsPerRequest),
+			})
+			return
+		}
+	}
+
+	sheet := make([]labels.Label, len(markers))
+	for i, m := range markers {
+		sheet[i] = labels.Label{
+			Link:      h.deepLink(m.ShortCode),
+			Name:      m.Name,
+			ShortCode: m.ShortCode,
+			Strain:    m.Strain.String,
+			OwnerName: m.OwnerName.String,
+		}
+	}
+
+	// Render into a buffer so a failure can still be reported as JSON
 	buf := &bytes.Buffer{}
-	writerOpts := []standard.ImageOption{
-		standard.WithQRWidth(10),
-		standard.WithBuiltinImageEncoder(standard.PNG_FORMAT),
-		standard.WithLogoImageFilePNG("assets/logo_sapuran.png"),
-		standard.WithLogoSizeMultiplier(3),
-	}
-	wr := standard.NewWithWriter(nopCloser{buf}, writerOpts...)
-	if err := qrc.Save(wr); err != nil {
-		log.Printf("Failed to save QR code: %v", err)
-		respondError(w, http.StatusInternalServerError, "Failed to generate QR code", nil)
+	if err := labels.Render(buf, tmpl, sheet, b); err != nil {
+		log.Printf("Failed to generate labels: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to generate labels", nil)
 		return
 	}
 
-	// Return PNG image
-	w.Header().Set("Content-Type", "image/png")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s.png\"", marker.ShortCode))
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"marker-labels-%s.pdf\"", tmpl.Name))
 	w.WriteHeader(http.StatusOK)
 	w.Write(buf.Bytes())
 }
+
+// GenerateQRArchive streams a ZIP of QR codes, one per marker matching the marker list
+// query parameters, with a manifest.csv mapping short codes to names and deep links.
+// Accepts the QR options of GenerateQR except the pdf format.
+func (h *MarkerHandler) GenerateQRArchive(w http.ResponseWriter, r *http.Request) {
+	// Ensure user is authenticated
+	_, ok := middleware.GetClaims(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	opts, details := h.parseQROptions(r.URL.Query())
+	if opts.Format == qr.FormatPDF {
+		details["format"] = "Format must be one of png, svg"
+	}
+	if len(details) > 0 {
+		respondError(w, http.StatusBadRequest, "Invalid QR code options", details)
+		return
+	}
+
+	params, err := ParseListMarkersParams(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid query parameters", nil)
+		return
+	}
+
+	// Fetch one more than allowed to detect an oversized selection
+	markers, err := h.queries.ListMarkersFiltered(r.Context(), params, maxQRArchiveMarkers+1)
+	if err != nil {
+		log.Printf("Failed to fetch markers for QR archive: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch markers", nil)
+		return
+	}
+	if len(markers) == 0 {
+		respondError(w, http.StatusNotFound, "No markers match the filters", nil)
+		return
+	}
+	if len(markers) > maxQRArchiveMarkers {
+		respondError(w, http.StatusBadRequest, "Too many markers", map[string]string{
+			"filters": fmt.Sprintf("Filters match more than %d markers, narrow them down", maxQRArchiveMarkers),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"marker-qr-codes.zip\"")
+	w.WriteHeader(http.StatusOK)
+
+	// Headers are sent, so failures from here on can only be logged and cut the archive short
+	if err := h.writeQRArchive(w, markers, opts); err != nil {
+		log.Printf("Failed to write QR archive: %v", err)
+	}
+}
+
+// writeQRArchive writes one QR code per marker and the manifest as a ZIP
+func (h *MarkerHandler) writeQRArchive(w io.Writer, markers []repository.Marker, opts qr.Options) error {
+	zw := zip.NewWriter(w)
+
+	manifest := &bytes.Buffer{}
+	cw := csv.NewWriter(manifest)
+	cw.Write([]string{"short_code", "name", "deep_link", "file"})
+
+	// PNG is already compressed
+	method := zip.Deflate
+	if opts.Format == qr.FormatPNG {
+		method = zip.Store
+	}
+
+	for _, m := range markers {
+		filename := fmt.Sprintf("%s.%s", m.ShortCode, opts.Format)
+		link := h.deepLink(m.ShortCode)
+
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     filename,
+			Method:   method,
+			Modified: m.UpdatedAt.Time,
+		})
+		if err != nil {
+			return err
+		}
+		if err := qr.Render(fw, link, opts); err != nil {
+			return fmt.Errorf("failed to render QR code for %s: %w", m.ShortCode, err)
+		}
+
+		cw.Write([]string{m.ShortCode, m.Name, link, filename})
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: "manifest.csv", Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(manifest.Bytes()); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// branding returns a configured branding by name, the default for an empty name.
+// It returns nil with ok true when no brandings are configured and no name was given.
+func (h *MarkerHandler) branding(name string) (*branding.Branding, bool) {
+	if h.brandings == nil {
+		return nil, name == ""
+	}
+	return h.brandings.Get(name)
+}
+
+// deepLink returns the URL encoded into a marker's QR code
+func (h *MarkerHandler) deepLink(shortCode string) string {
+	return fmt.Sprintf("%s/marker/%s", h.deepLinkBaseURL, shortCode)
+}
+
+// parseQROptions reads QR rendering options from query parameters.
+// Invalid values are reported per parameter in the returned details.
+func (h *MarkerHandler) parseQROptions(query url.Values) (qr.Options, map[string]string) {
+	opts := qr.Options{Margin: qr.DefaultMargin}
+	details := make(map[string]string)
+
+	format, err := qr.ParseFormat(query.Get("format"))
+	if err != nil {
+		details["format"] = "Format must be one of png, svg, pdf"
+	}
+	opts.Format = format
+
+	if v := query.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < qr.MinSize || size > qr.MaxSize {
+			details["size"] = fmt.Sprintf("Size must be between %d and %d", qr.MinSize, qr.MaxSize)
+		}
+		opts.Size = size
+	}
+
+	level, err := qr.ParseECLevel(query.Get("ec"))
+	if err != nil {
+		details["ec"] = "Error correction level must be one of L, M, Q, H"
+	}
+	opts.ECLevel = level
+
+	if v := query.Get("margin"); v != "" {
+		margin, err := strconv.Atoi(v)
+		if err != nil || margin < 0 || margin > qr.MaxMargin {
+			details["margin"] = fmt.Sprintf("Margin must be between 0 and %d modules", qr.MaxMargin)
+		}
+		opts.Margin = margin
+	}
+
+	b, ok := h.branding(query.Get("branding"))
+	if !ok {
+		details["branding"] = "Unknown branding"
+	}
+	if b != nil {
+		opts.Logo = b.Logo
+		opts.Foreground = b.Foreground
+		opts.Background = b.Background
+		opts.Caption = b.Caption
+	}
+
+	// logo overrides the branding's logo: none, or the name of another branding
+	switch logoName := query.Get("logo"); logoName {
+	case "":
+	case branding.NoLogo:
+		opts.Logo = nil
+	default:
+		other, ok := h.branding(logoName)
+		if !ok || other == nil {
+			details["logo"] = "Logo must be none or a branding name"
+			break
+		}
+		opts.Logo = other.Logo
+	}
+
+	if opts.Logo != nil && opts.ECLevel == qr.ECLevelLow {
+		details["ec"] = "Error correction level L is too low for a logo, use M or higher or logo=none"
+	}
+
+	return opts, details
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/util"
+	"github.com/go-chi/chi/v5"
+)
+
+//go:embed templates/marker_page.html
+var markerPageHTML string
+
+var markerPageTemplate = template.Must(template.New("marker_page").Parse(markerPageHTML))
+
+// markerPageData is the data rendered by the marker page template
+type markerPageData struct {
+	Marker           *model.PublicMarkerResponse
+	Summary          string
+	DeepLink         string
+	MapURL           string
+	AndroidIntentURL template.URL
+	AppleAppStoreID  string
+
+	// Set when the marker wasn't found or its code was retired
+	ShortCode string
+	Mistyped  bool
+	Retired   bool
+}
+
+// ShowPage serves the public web page of a marker, the fallback for QR scans on phones
+// without the app. When the app is installed the OS opens the link in the app instead,
+// using the association files served by AppLinksHandler.
+// Short links (/m/{shortCode}) and mistyped codes redirect to the canonical /marker/{shortCode};
+// only the page itself is recorded as a scan. Retired codes redirect to the current code or are gone.
+func (h *MarkerHandler) ShowPage(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+
+	marker, err := h.findByShortCode(r.Context(), shortCode)
+	if err == nil && marker.Visibility == model.VisibilityPrivate {
+		// The page is public, so private markers don't exist here
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if alias, ok := h.retiredShortCode(r, shortCode); ok {
+				if alias.Redirect {
+					// Temporary, as a later rotation moves the current code on
+					http.Redirect(w, r, "/marker/"+alias.CurrentShortCode, http.StatusFound)
+					return
+				}
+				renderMarkerPage(w, http.StatusGone, markerPageData{ShortCode: alias.ShortCode, Retired: true})
+				return
+			}
+			renderMarkerPage(w, http.StatusNotFound, markerPageData{
+				ShortCode: shortCode,
+				Mistyped:  util.MistypedShortCode(shortCode),
+			})
+			return
+		}
+		log.Printf("Failed to fetch marker for page: %v", err)
+		http.Error(w, "Failed to fetch marker", http.StatusInternalServerError)
+		return
+	}
+
+	canonical := "/marker/" + marker.ShortCode
+	if r.URL.Path != canonical {
+		http.Redirect(w, r, canonical, http.StatusFound)
+		return
+	}
+
+	h.recordScan(r, marker.ID, scanSourceWeb)
+
+	response := h.markerToResponse(marker).PublicView()
+	data := markerPageData{
+		Marker:          &response,
+		Summary:         markerSummary(response),
+		DeepLink:        h.deepLink(marker.ShortCode),
+		MapURL:          fmt.Sprintf("https://www.openstreetmap.org/?mlat=%s&mlon=%s#map=18/%s/%s", marker.Latitude, marker.Longitude, marker.Latitude, marker.Longitude),
+		AppleAppStoreID: h.appLinks.AppleAppStoreID,
+	}
+	if h.appLinks.AndroidPackage != "" {
+		data.AndroidIntentURL = h.androidIntentURL(marker.ShortCode)
+	}
+
+	// Every view is a scan, so caches must come back rather than serve a stored copy
+	w.Header().Set("Cache-Control", "private, no-cache")
+	renderMarkerPage(w, http.StatusOK, data)
+}
+
+// renderMarkerPage renders the marker page template
+func renderMarkerPage(w http.ResponseWriter, status int, data markerPageData) {
+	// Render into a buffer so a template failure doesn't leave a half-written page
+	buf := &bytes.Buffer{}
+	if err := markerPageTemplate.Execute(buf, data); err != nil {
+		log.Printf("Failed to render marker page: %v", err)
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// markerSummary describes a marker in one line for link previews
+func markerSummary(m model.PublicMarkerResponse) string {
+	parts := []string{"Bamboo marker " + m.ShortCode}
+	if m.Strain != nil {
+		parts = append(parts, *m.Strain)
+	}
+	if m.Quantity != nil {
+		parts = append(parts, fmt.Sprintf("%d bamboo", *m.Quantity))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// androidIntentURL returns a link that opens the marker in the Android app,
+// falling back to the Play Store when the app isn't installed.
+// It is marked safe because html/template rejects the intent: scheme.
+func (h *MarkerHandler) androidIntentURL(shortCode string) template.URL {
+	link, err := url.Parse(h.deepLink(shortCode))
+	if err != nil {
+		return ""
+	}
+	store := "https://play.google.com/store/apps/details?id=" + url.QueryEscape(h.appLinks.AndroidPackage)
+	return template.URL(fmt.Sprintf("intent://%s%s#Intent;scheme=%s;package=%s;S.browser_fallback_url=%s;end",
+		link.Host, link.Path, link.Scheme, url.QueryEscape(h.appLinks.AndroidPackage), url.QueryEscape(store)))
+}
@@ -0,0 +1,294 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/middleware"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/util"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// Scan sources, recorded with every scan
+const (
+	scanSourceApp = "app"
+	scanSourceWeb = "web"
+)
+
+const scanDateLayout = "2006-01-02"
+
+// recordScan stores a lookup of a marker by its short code. Crawlers and link
+// previews are ignored. Failures are logged and never fail the lookup itself.
+// The app may report where the code was scanned with the lat and lng query parameters.
+func (h *MarkerHandler) recordScan(r *http.Request, markerID uuid.UUID, source string) {
+	userAgent := r.UserAgent()
+	if util.IsBot(userAgent) {
+		return
+	}
+
+	params := repository.CreateMarkerScanParams{
+		MarkerID: markerID,
+		Source:   source,
+		Platform: util.ClientPlatform(userAgent),
+	}
+	if ip := util.ClientIP(r); ip != "" {
+		params.IpHash = sql.NullString{String: util.HashIP(h.scanHashKey, ip), Valid: true}
+	}
+	if lat, lng, ok := parseScanLocation(r.URL.Query()); ok {
+		params.Latitude = sql.NullString{String: lat, Valid: true}
+		params.Longitude = sql.NullString{String: lng, Valid: true}
+	}
+
+	if err := h.queries.CreateMarkerScan(r.Context(), params); err != nil {
+		log.Printf("Failed to record scan of marker %s: %v", markerID, err)
+	}
+}
+
+// parseScanLocation reads a client-reported location, ignoring missing or out of range values
+func parseScanLocation(query url.Values) (string, string, bool) {
+	lat, err := strconv.ParseFloat(query.Get("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return "", "", false
+	}
+	lng, err := strconv.ParseFloat(query.Get("lng"), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return "", "", false
+	}
+	return strconv.FormatFloat(lat, 'f', 8, 64), strconv.FormatFloat(lng, 'f', 8, 64), true
+}
+
+// MarkerScanStats returns scan statistics of a single marker with daily buckets
+func (h *MarkerHandler) MarkerScanStats(w http.ResponseWriter, r *http.Request) {
+	// Ensure user is authenticated
+	_, ok := middleware.GetClaims(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid marker ID", nil)
+		return
+	}
+
+	params, details := parseScanStatsParams(r.URL.Query(), time.Now())
+	if len(details) > 0 {
+		respondError(w, http.StatusBadRequest, "Invalid query parameters", details)
+		return
+	}
+
+	marker, err := h.queries.GetMarkerByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "Marker not found", nil)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch marker", nil)
+		return
+	}
+
+	markerID := uuid.NullUUID{UUID: marker.ID, Valid: true}
+	summary, daily, platforms, err := h.scanStats(r, markerID, params)
+	if err != nil {
+		log.Printf("Failed to fetch scan statistics of marker %s: %v", marker.ID, err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch scan statistics", nil)
+		return
+	}
+
+	response := model.MarkerScanStatsResponse{
+		MarkerID:       marker.ID,
+		ShortCode:      marker.ShortCode,
+		DateFrom:       params.DateFrom.Format(scanDateLayout),
+		DateTo:         params.DateTo.Format(scanDateLayout),
+		TimeZone:       params.Location.String(),
+		Scans:          summary.Scans,
+		UniqueVisitors: summary.UniqueVisitors,
+		Daily:          daily,
+		Platforms:      platforms,
+	}
+	if summary.FirstScannedAt.Valid {
+		response.FirstScannedAt = &summary.FirstScannedAt.Time
+	}
+	if summary.LastScannedAt.Valid {
+		response.LastScannedAt = &summary.LastScannedAt.Time
+	}
+
+	respondSuccess(w, http.StatusOK, "Scan statistics retrieved successfully", response)
+}
+
+// ScanStats returns scan statistics across all markers with daily buckets and the top markers
+func (h *MarkerHandler) ScanStats(w http.ResponseWriter, r *http.Request) {
+	// Ensure user is authenticated
+	_, ok := middleware.GetClaims(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	params, details := parseScanStatsParams(r.URL.Query(), time.Now())
+	if len(details) > 0 {
+		respondError(w, http.StatusBadRequest, "Invalid query parameters", details)
+		return
+	}
+
+	summary, daily, platforms, err := h.scanStats(r, uuid.NullUUID{}, params)
+	if err != nil {
+		log.Printf("Failed to fetch scan statistics: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch scan statistics", nil)
+		return
+	}
+
+	top, err := h.queries.ListTopScannedMarkers(r.Context(), repository.ListTopScannedMarkersParams{
+		ScannedFrom: params.DateFrom,
+		ScannedTo:   params.End(),
+		RowLimit:    int32(params.Limit),
+	})
+	if err != nil {
+		log.Printf("Failed to fetch top scanned markers: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch scan statistics", nil)
+		return
+	}
+
+	topMarkers := make([]model.TopScannedMarker, len(top))
+	for i, m := range top {
+		topMarkers[i] = model.TopScannedMarker{
+			MarkerID:       m.ID,
+			ShortCode:      m.ShortCode,
+			Name:           m.Name,
+			Scans:          m.Scans,
+			UniqueVisitors: m.UniqueVisitors,
+			LastScannedAt:  m.LastScannedAt,
+		}
+	}
+
+	respondSuccess(w, http.StatusOK, "Scan statistics retrieved successfully", model.ScanStatsResponse{
+		DateFrom:       params.DateFrom.Format(scanDateLayout),
+		DateTo:         params.DateTo.Format(scanDateLayout),
+		TimeZone:       params.Location.String(),
+		Scans:          summary.Scans,
+		UniqueVisitors: summary.UniqueVisitors,
+		MarkersScanned: summary.MarkersScanned,
+		Daily:          daily,
+		Platforms:      platforms,
+		TopMarkers:     topMarkers,
+	})
+}
+
+// scanStats fetches the totals, daily buckets and platform breakdown of scans,
+// for one marker or all markers when markerID is null
+func (h *MarkerHandler) scanStats(r *http.Request, markerID uuid.NullUUID, params model.ScanStatsParams) (repository.GetScanSummaryRow, []model.ScanDay, []model.ScanPlatformCount, error) {
+	summary, err := h.queries.GetScanSummary(r.Context(), repository.GetScanSummaryParams{
+		MarkerID:    markerID,
+		ScannedFrom: params.DateFrom,
+		ScannedTo:   params.End(),
+	})
+	if err != nil {
+		return summary, nil, nil, err
+	}
+
+	days, err := h.queries.ListScansByDay(r.Context(), repository.ListScansByDayParams{
+		TimeZone:    params.Location.String(),
+		MarkerID:    markerID,
+		ScannedFrom: params.DateFrom,
+		ScannedTo:   params.End(),
+	})
+	if err != nil {
+		return summary, nil, nil, err
+	}
+
+	rows, err := h.queries.ListScansByPlatform(r.Context(), repository.ListScansByPlatformParams{
+		MarkerID:    markerID,
+		ScannedFrom: params.DateFrom,
+		ScannedTo:   params.End(),
+	})
+	if err != nil {
+		return summary, nil, nil, err
+	}
+
+	platforms := make([]model.ScanPlatformCount, len(rows))
+	for i, row := range rows {
+		platforms[i] = model.ScanPlatformCount{Platform: row.Platform, Source: row.Source, Scans: row.Scans}
+	}
+
+	return summary, fillScanDays(params, days), platforms, nil
+}
+
+// fillScanDays returns one bucket per day in the range, with zero for days without scans
+func fillScanDays(params model.ScanStatsParams, rows []repository.ListScansByDayRow) []model.ScanDay {
+	byDate := make(map[string]repository.ListScansByDayRow, len(rows))
+	for _, row := range rows {
+		byDate[row.Day.Format(scanDateLayout)] = row
+	}
+
+	var days []model.ScanDay
+	for day := params.DateFrom; !day.After(params.DateTo); day = day.AddDate(0, 0, 1) {
+		date := day.Format(scanDateLayout)
+		row := byDate[date]
+		days = append(days, model.ScanDay{Date: date, Scans: row.Scans, UniqueVisitors: row.UniqueVisitors})
+	}
+	return days
+}
+
+// parseScanStatsParams reads the date range (date_from, date_to), time zone (tz)
+// and top marker limit (limit) of scan statistics. The range defaults to the last
+// 30 days including today. Invalid values are reported per parameter.
+func parseScanStatsParams(query url.Values, now time.Time) (model.ScanStatsParams, map[string]string) {
+	params := model.ScanStatsParams{Location: time.UTC, Limit: model.DefaultTopMarkers}
+	details := make(map[string]string)
+
+	if tz := query.Get("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			details["tz"] = "Time zone must be an IANA name such as Asia/Jakarta"
+		} else {
+			params.Location = loc
+		}
+	}
+
+	today := now.In(params.Location)
+	params.DateTo = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, params.Location)
+	if v := query.Get("date_to"); v != "" {
+		dateTo, err := time.ParseInLocation(scanDateLayout, v, params.Location)
+		if err != nil {
+			details["date_to"] = "Date must be formatted as YYYY-MM-DD"
+		}
+		params.DateTo = dateTo
+	}
+
+	params.DateFrom = params.DateTo.AddDate(0, 0, -(model.DefaultScanStatsDays - 1))
+	if v := query.Get("date_from"); v != "" {
+		dateFrom, err := time.ParseInLocation(scanDateLayout, v, params.Location)
+		if err != nil {
+			details["date_from"] = "Date must be formatted as YYYY-MM-DD"
+		}
+		params.DateFrom = dateFrom
+	}
+
+	if len(details) == 0 {
+		if params.DateFrom.After(params.DateTo) {
+			details["date_from"] = "Date from must not be after date to"
+		} else if params.DateFrom.AddDate(0, 0, model.MaxScanStatsDays).Before(params.End()) {
+			details["date_from"] = fmt.Sprintf("Date range must not exceed %d days", model.MaxScanStatsDays)
+		}
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > model.MaxTopMarkers {
+			details["limit"] = fmt.Sprintf("Limit must be between 1 and %d", model.MaxTopMarkers)
+		}
+		params.Limit = limit
+	}
+
+	return params, details
+}
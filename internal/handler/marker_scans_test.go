@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const androidUserAgent = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"
+
+func TestMarkerHandler_GetByShortCode_RecordsScan(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{
+		DeepLinkBaseURL: "https://test.bamboomapper.com",
+		ScanHashKey:     []byte("test-secret"),
+	})
+
+	r := chi.NewRouter()
+	r.Get("/markers/code/{shortCode}", handler.GetByShortCode)
+
+	req := httptest.NewRequest(http.MethodGet, "/markers/code/TEST001?lat=-7.5&lng=110.25", nil)
+	req.Header.Set("User-Agent", androidUserAgent)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	// Link previews are not scans
+	req = httptest.NewRequest(http.MethodGet, "/markers/code/TEST001", nil)
+	req.Header.Set("User-Agent", "WhatsApp/2.23.20.0")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var count int
+	var source, platform, ipHash, lat, lng string
+	err := testDB.QueryRow(`
+		SELECT COUNT(*) OVER (), source, platform, ip_hash, latitude::text, longitude::text
+		FROM marker_scans WHERE marker_id = $1
+	`, markerID).Scan(&count, &source, &platform, &ipHash, &lat, &lng)
+	if err != nil {
+		t.Fatalf("failed to fetch scan: %v", err)
+	}
+
+	if count != 1 {
+		t.Errorf("expected 1 scan, got %d", count)
+	}
+	if source != scanSourceApp || platform != "android" {
+		t.Errorf("expected an app scan from android, got %s from %s", source, platform)
+	}
+	if len(ipHash) != 64 || ipHash == "203.0.113.7" {
+		t.Errorf("expected a hashed IP, got %q", ipHash)
+	}
+	if lat != "-7.50000000" || lng != "110.25000000" {
+		t.Errorf("expected the reported location, got %s, %s", lat, lng)
+	}
+}
+
+func TestMarkerHandler_MarkerScanStats(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+
+	// 2024-03-01 23:30 UTC is 2024-03-02 in Jakarta (UTC+7)
+	insertScan(t, markerID, "2024-03-01T23:30:00Z", "app", "android", "a")
+	insertScan(t, markerID, "2024-03-02T02:00:00Z", "web", "ios", "b")
+	insertScan(t, markerID, "2024-03-02T03:00:00Z", "web", "ios", "b")
+	insertScan(t, markerID, "2024-03-04T05:00:00Z", "app", "android", "a")
+	// Outside the range
+	insertScan(t, markerID, "2024-03-05T18:00:00Z", "app", "android", "c")
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	r := chi.NewRouter()
+	r.Get("/markers/{id}/scans/stats", handler.MarkerScanStats)
+
+	query := url.Values{"date_from": {"2024-03-02"}, "date_to": {"2024-03-05"}, "tz": {"Asia/Jakarta"}}
+	req := httptest.NewRequest(http.MethodGet, "/markers/"+markerID.String()+"/scans/stats?"+query.Encode(), nil)
+	req = addClaimsToContext(req, userID)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Data model.MarkerScanStatsResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	stats := response.Data
+
+	if stats.Scans != 4 || stats.UniqueVisitors != 2 {
+		t.Errorf("expected 4 scans from 2 visitors, got %d from %d", stats.Scans, stats.UniqueVisitors)
+	}
+	if stats.TimeZone != "Asia/Jakarta" || stats.ShortCode != "TEST001" {
+		t.Errorf("unexpected time zone %s or short code %s", stats.TimeZone, stats.ShortCode)
+	}
+
+	expected := []model.ScanDay{
+		{Date: "2024-03-02", Scans: 3, UniqueVisitors: 2},
+		{Date: "2024-03-03", Scans: 0, UniqueVisitors: 0},
+		{Date: "2024-03-04", Scans: 1, UniqueVisitors: 1},
+		{Date: "2024-03-05", Scans: 0, UniqueVisitors: 0},
+	}
+	if len(stats.Daily) != len(expected) {
+		t.Fatalf("expected %d days, got %v", len(expected), stats.Daily)
+	}
+	for i, day := range expected {
+		if stats.Daily[i] != day {
+			t.Errorf("day %d: expected %+v, got %+v", i, day, stats.Daily[i])
+		}
+	}
+
+	if len(stats.Platforms) != 2 || stats.Platforms[0].Platform != "android" || stats.Platforms[0].Scans != 2 {
+		t.Errorf("unexpected platforms: %+v", stats.Platforms)
+	}
+}
+
+func TestMarkerHandler_ScanStats_TopMarkers(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	quiet := createTestMarker(t, userID)
+	var popular uuid.UUID
+	err := testDB.QueryRow(`
+		INSERT INTO markers (creator_id, short_code, name, latitude, longitude)
+		VALUES ($1, 'TEST002', 'Popular Bamboo', -7.2, 110.2)
+		RETURNING id
+	`, userID).Scan(&popular)
+	if err != nil {
+		t.Fatalf("failed to create marker: %v", err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	insertScan(t, quiet, now, "app", "android", "a")
+	insertScan(t, popular, now, "web", "ios", "b")
+	insertScan(t, popular, now, "web", "ios", "c")
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	req := httptest.NewRequest(http.MethodGet, "/markers/scans/stats?limit=1", nil)
+	req = addClaimsToContext(req, userID)
+	rr := httptest.NewRecorder()
+	handler.ScanStats(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Data model.ScanStatsResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	stats := response.Data
+
+	if stats.Scans != 3 || stats.MarkersScanned != 2 {
+		t.Errorf("expected 3 scans of 2 markers, got %d of %d", stats.Scans, stats.MarkersScanned)
+	}
+	if len(stats.Daily) != model.DefaultScanStatsDays {
+		t.Errorf("expected %d days by default, got %d", model.DefaultScanStatsDays, len(stats.Daily))
+	}
+	if len(stats.TopMarkers) != 1 || stats.TopMarkers[0].MarkerID != popular || stats.TopMarkers[0].Scans != 2 {
+		t.Errorf("expected the popular marker on top, got %+v", stats.TopMarkers)
+	}
+}
+
+func TestParseScanStatsParams(t *testing.T) {
+	now := time.Date(2024, 3, 10, 20, 0, 0, 0, time.UTC)
+
+	params, details := parseScanStatsParams(url.Values{}, now)
+	if len(details) > 0 {
+		t.Fatalf("unexpected errors: %v", details)
+	}
+	if params.DateTo.Format(scanDateLayout) != "2024-03-10" || params.DateFrom.Format(scanDateLayout) != "2024-02-10" {
+		t.Errorf("expected the last 30 days, got %s to %s", params.DateFrom, params.DateTo)
+	}
+
+	// 20:00 UTC is already the next day in Jakarta
+	params, _ = parseScanStatsParams(url.Values{"tz": {"Asia/Jakarta"}}, now)
+	if params.DateTo.Format(scanDateLayout) != "2024-03-11" {
+		t.Errorf("expected today in Jakarta to be 2024-03-11, got %s", params.DateTo)
+	}
+	if !params.End().Equal(time.Date(2024, 3, 11, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the range to end at Jakarta midnight, got %s", params.End().UTC())
+	}
+
+	tests := []struct {
+		query url.Values
+		field string
+	}{
+		{url.Values{"tz": {"Mars/Olympus"}}, "tz"},
+		{url.Values{"date_from": {"03/01/2024"}}, "date_from"},
+		{url.Values{"date_to": {"yesterday"}}, "date_to"},
+		{url.Values{"date_from": {"2024-03-05"}, "date_to": {"2024-03-01"}}, "date_from"},
+		{url.Values{"date_from": {"2022-01-01"}, "date_to": {"2024-01-01"}}, "date_from"},
+		{url.Values{"limit": {"0"}}, "limit"},
+	}
+	for _, tt := range tests {
+		if _, details := parseScanStatsParams(tt.query, now); details[tt.field] == "" {
+			t.Errorf("%v: expected an error for %s, got %v", tt.query, tt.field, details)
+		}
+	}
+}
+
+// insertScan records a scan at a given time
+func insertScan(t *testing.T, markerID uuid.UUID, scannedAt, source, platform, ipHash string) {
+	t.Helper()
+	_, err := testDB.Exec(`
+		INSERT INTO marker_scans (marker_id, scanned_at, source, platform, ip_hash)
+		VALUES ($1, $2, $3, $4, $5)
+	`, markerID, scannedAt, source, platform, ipHash)
+	if err != nil {
+		t.Fatalf("failed to insert scan: %v", err)
+	}
+}
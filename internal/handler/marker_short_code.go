@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/middleware"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/util"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// maxShortCodeAttempts bounds how many codes are generated before giving up on a collision
+const maxShortCodeAttempts = 5
+
+// errShortCodeExhausted is returned when every generated short code was already taken
+var errShortCodeExhausted = errors.New("no unused short code found")
+
+// shortCodeMetrics counts generated short codes, collisions with existing codes and
+// requests that gave up after maxShortCodeAttempts. Served with expvar at /debug/vars;
+// a growing collision rate means SHORT_CODE_LENGTH should be increased.
+var shortCodeMetrics = expvar.NewMap("short_codes")
+
+// RotateShortCode replaces a marker's short code with a newly generated one, for when a
+// sign was stolen or vandalized and a new QR code is printed. The old code is retired:
+// lookups of it redirect to the new code when the request sets redirect, and are gone otherwise.
+func (h *MarkerHandler) RotateShortCode(w http.ResponseWriter, r *http.Request) {
+	// Ensure user is authenticated
+	claims, ok := middleware.GetClaims(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid marker ID", nil)
+		return
+	}
+
+	existingMarker, err := h.queries.GetMarkerByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "Marker not found", nil)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch marker", nil)
+		return
+	}
+	if !canEditMarker(claims, existingMarker) {
+		respondError(w, http.StatusForbidden, "You can only change markers you created", nil)
+		return
+	}
+
+	// An empty body retires the old code without a redirect
+	var req model.RotateShortCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	var marker repository.Marker
+	shortCode, err := h.newShortCode(r.Context())
+	if err == nil {
+		err = h.retryShortCode(r.Context(), shortCode, func(shortCode string) error {
+			var err error
+			marker, err = h.queries.RotateMarkerShortCode(r.Context(), repository.RotateMarkerShortCodeParams{
+				Redirect:  req.Redirect,
+				ID:        id,
+				ShortCode: shortCode,
+			})
+			return err
+		})
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "Marker not found", nil)
+			return
+		}
+		log.Printf("Failed to rotate short code of marker %s: %v", id, err)
+		respondError(w, http.StatusInternalServerError, "Failed to rotate short code", nil)
+		return
+	}
+
+	respondSuccess(w, http.StatusOK, "Short code rotated successfully", h.markerToResponse(marker))
+}
+
+// newShortCode generates a short code that no marker uses and that was never retired
+func (h *MarkerHandler) newShortCode(ctx context.Context) (string, error) {
+	for i := 0; i < maxShortCodeAttempts; i++ {
+		shortCode := util.GenerateShortCode(h.shortCodeLength)
+		shortCodeMetrics.Add("generated", 1)
+		inUse, err := h.queries.ShortCodeInUse(ctx, shortCode)
+		if err != nil {
+			return "", err
+		}
+		if !inUse {
+			return shortCode, nil
+		}
+		shortCodeMetrics.Add("collisions", 1)
+	}
+	shortCodeMetrics.Add("exhausted", 1)
+	return "", errShortCodeExhausted
+}
+
+// retryShortCode stores a record under shortCode. When a concurrent request took the code
+// between generating and storing it, it retries with a new code a bounded number of times.
+func (h *MarkerHandler) retryShortCode(ctx context.Context, shortCode string, store func(shortCode string) error) error {
+	for attempt := 1; ; attempt++ {
+		err := store(shortCode)
+		if !isShortCodeViolation(err) {
+			return err
+		}
+
+		shortCodeMetrics.Add("collisions", 1)
+		if attempt == maxShortCodeAttempts {
+			shortCodeMetrics.Add("exhausted", 1)
+			return errShortCodeExhausted
+		}
+		if shortCode, err = h.newShortCode(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// findShortCodeAlias looks a retired short code up, tolerating typing mistakes like findByShortCode
+func (h *MarkerHandler) findShortCodeAlias(ctx context.Context, shortCode string) (repository.GetShortCodeAliasRow, error) {
+	for _, candidate := range util.ShortCodeCandidates(shortCode) {
+		alias, err := h.queries.GetShortCodeAlias(ctx, candidate)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return alias, err
+		}
+	}
+	return repository.GetShortCodeAliasRow{}, sql.ErrNoRows
+}
+
+// retiredShortCode returns the alias of a short code that no current marker has.
+// Aliases of private markers are only visible to authenticated callers.
+func (h *MarkerHandler) retiredShortCode(r *http.Request, shortCode string) (repository.GetShortCodeAliasRow, bool) {
+	alias, err := h.findShortCodeAlias(r.Context(), shortCode)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Failed to fetch short code alias: %v", err)
+		}
+		return alias, false
+	}
+
+	if _, authenticated := middleware.GetClaims(r.Context()); !authenticated && alias.Visibility == model.VisibilityPrivate {
+		return alias, false
+	}
+	return alias, true
+}
+
+// respondRetiredShortCode redirects a lookup of a retired short code to the current
+// code, keeping the query, or reports it as gone. The redirect is temporary, as a later
+// rotation moves the current code on.
+func respondRetiredShortCode(w http.ResponseWriter, r *http.Request, alias repository.GetShortCodeAliasRow) {
+	if alias.Redirect {
+		location := path.Join(path.Dir(r.URL.Path), alias.CurrentShortCode)
+		if r.URL.RawQuery != "" {
+			location += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, location, http.StatusFound)
+		return
+	}
+
+	respondError(w, http.StatusGone, "Short code has been retired", map[string]string{
+		"short_code": fmt.Sprintf("Short code %s was retired on %s", alias.ShortCode, alias.RetiredAt.Format("2006-01-02")),
+	})
+}
+
+// isShortCodeViolation reports whether err is a Postgres unique violation (23505) of a short code:
+// a marker's current code or, when two rotations of the same marker race, its retired code
+func isShortCodeViolation(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return false
+	}
+	return pqErr.Constraint == "markers_short_code_key" || pqErr.Constraint == "marker_short_code_aliases_pkey"
+}
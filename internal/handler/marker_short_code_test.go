@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/util"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// rotateTestMarker rotates the short code of a marker and returns the new code
+func rotateTestMarker(t *testing.T, handler *MarkerHandler, userID, markerID uuid.UUID, body string) string {
+	r := chi.NewRouter()
+	r.Post("/markers/{id}/short-code/rotate", handler.RotateShortCode)
+
+	req := httptest.NewRequest(http.MethodPost, "/markers/"+markerID.String()+"/short-code/rotate", bytes.NewBufferString(body))
+	req = addClaimsToContext(req, userID)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response Response
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	data := response.Data.(map[string]interface{})
+	shortCode, _ := data["short_code"].(string)
+	return shortCode
+}
+
+func TestMarkerHandler_RotateShortCode_Gone(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	shortCode := rotateTestMarker(t, handler, userID, markerID, "")
+	if shortCode == "TEST001" || !util.ValidShortCode(shortCode) {
+		t.Fatalf("expected a new valid short code, got %q", shortCode)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/markers/code/{shortCode}", handler.GetByShortCode)
+
+	// The retired code is gone
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/markers/code/TEST001", nil))
+	if rr.Code != http.StatusGone {
+		t.Errorf("expected status %d, got %d: %s", http.StatusGone, rr.Code, rr.Body.String())
+	}
+
+	// The new code resolves to the same marker
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/markers/code/"+shortCode, nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+}
+
+func TestMarkerHandler_RotateShortCode_Redirect(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	first := rotateTestMarker(t, handler, userID, markerID, `{"redirect": true}`)
+	second := rotateTestMarker(t, handler, userID, markerID, `{"redirect": true}`)
+
+	r := chi.NewRouter()
+	r.Get("/markers/code/{shortCode}", handler.GetByShortCode)
+	r.Get("/marker/{shortCode}", handler.ShowPage)
+
+	// Every retired code redirects to the current one, keeping the query
+	for _, code := range []string{"TEST001", first} {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/markers/code/"+code+"?lat=-7.5&lng=110.25", nil))
+		if rr.Code != http.StatusFound {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusFound, rr.Code, rr.Body.String())
+		}
+		if location := rr.Header().Get("Location"); location != "/markers/code/"+second+"?lat=-7.5&lng=110.25" {
+			t.Errorf("unexpected redirect location: %s", location)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/marker/TEST001", nil))
+	if rr.Code != http.StatusFound || rr.Header().Get("Location") != "/marker/"+second {
+		t.Errorf("expected page redirect to /marker/%s, got %d %s", second, rr.Code, rr.Header().Get("Location"))
+	}
+}
+
+func TestMarkerHandler_RotateShortCode_NotFound(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	r := chi.NewRouter()
+	r.Post("/markers/{id}/short-code/rotate", handler.RotateShortCode)
+
+	req := httptest.NewRequest(http.MethodPost, "/markers/"+uuid.New().String()+"/short-code/rotate", nil)
+	req = addClaimsToContext(req, userID)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d: %s", http.StatusNotFound, rr.Code, rr.Body.String())
+	}
+}
+
+func TestMarkerHandler_RotateShortCode_Unauthorized(t *testing.T) {
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	r := chi.NewRouter()
+	r.Post("/markers/{id}/short-code/rotate", handler.RotateShortCode)
+
+	req := httptest.NewRequest(http.MethodPost, "/markers/"+uuid.New().String()+"/short-code/rotate", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d: %s", http.StatusUnauthorized, rr.Code, rr.Body.String())
+	}
+}
+
+func TestIsShortCodeViolation(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "marker short code", err: &pq.Error{Code: "23505", Constraint: "markers_short_code_key"}, expected: true},
+		{name: "retired short code", err: &pq.Error{Code: "23505", Constraint: "marker_short_code_aliases_pkey"}, expected: true},
+		{name: "other unique constraint", err: &pq.Error{Code: "23505", Constraint: "users_email_key"}, expected: false},
+		{name: "other error", err: &pq.Error{Code: "23503", Constraint: "markers_creator_id_fkey"}, expected: false},
+		{name: "no error", err: nil, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isShortCodeViolation(tt.err); got != tt.expected {
+				t.Errorf("isShortCodeViolation() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
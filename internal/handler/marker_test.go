@@ -1,16 +1,34 @@
 package handler
 
 import (
+	"archive/zip"
 	"bytes"
+	"compress/zlib"
 	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/auth"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/branding"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/imaging"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/markerimage"
 	appMiddleware "github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/middleware"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/storage"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
@@ -47,7 +65,7 @@ func createTestUserForMarker(t *testing.T) uuid.UUID {
 		INSERT INTO users (email, password_hash, name, role)
 		VALUES ($1, $2, $3, $4)
 		RETURNING id
-	`, "markertest@example.com", "$2a$12$test", "Marker Test User", "user").Scan(&userID)
+	`, "markertest@example.com", "$2a$12$test", "Marker Test User", "surveyor").Scan(&userID)
 	if err != nil {
 		t.Fatalf("failed to create test user: %v", err)
 	}
@@ -62,7 +80,7 @@ func TestMarkerHandler_List_Success(t *testing.T) {
 	userID := createTestUserForMarker(t)
 	createTestMarker(t, userID)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/markers", nil)
 	rr := httptest.NewRecorder()
@@ -108,7 +126,7 @@ func TestMarkerHandler_List_Empty(t *testing.T) {
 	cleanupMarkers(t)
 	cleanupUsers(t)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/markers", nil)
 	rr := httptest.NewRecorder()
@@ -145,7 +163,7 @@ func TestMarkerHandler_GetByID_Success(t *testing.T) {
 	userID := createTestUserForMarker(t)
 	markerID := createTestMarker(t, userID)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	// Create chi context with URL param
 	r := chi.NewRouter()
@@ -193,7 +211,7 @@ func TestMarkerHandler_GetByID_NotFound(t *testing.T) {
 	cleanupMarkers(t)
 	cleanupUsers(t)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	randomID := uuid.New()
 
@@ -224,7 +242,7 @@ func TestMarkerHandler_GetByID_NotFound(t *testing.T) {
 }
 
 func TestMarkerHandler_GetByID_InvalidID(t *testing.T) {
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	r := chi.NewRouter()
 	r.Get("/markers/{id}", handler.GetByID)
@@ -259,7 +277,7 @@ func TestMarkerHandler_List_LightweightFields(t *testing.T) {
 	userID := createTestUserForMarker(t)
 	createTestMarker(t, userID)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/markers", nil)
 	rr := httptest.NewRecorder()
@@ -314,7 +332,18 @@ func addClaimsToContext(req *http.Request, userID uuid.UUID) *http.Request {
 	claims := &auth.Claims{
 		UserID: userID,
 		Email:  "test@example.com",
-		Role:   "user",
+		Role:   auth.RoleSurveyor,
+	}
+	ctx := context.WithValue(req.Context(), appMiddleware.ClaimsKey, claims)
+	return req.WithContext(ctx)
+}
+
+// addRoleClaimsToContext adds JWT claims with the given role to the request context
+func addRoleClaimsToContext(req *http.Request, userID uuid.UUID, role string) *http.Request {
+	claims := &auth.Claims{
+		UserID: userID,
+		Email:  "test@example.com",
+		Role:   role,
 	}
 	ctx := context.WithValue(req.Context(), appMiddleware.ClaimsKey, claims)
 	return req.WithContext(ctx)
@@ -327,7 +356,7 @@ func TestMarkerHandler_Create_Success(t *testing.T) {
 	// Create test user
 	userID := createTestUserForMarker(t)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	// Create form data with required fields
 	fields := map[string]string{
@@ -392,6 +421,9 @@ func TestMarkerHandler_Create_Success(t *testing.T) {
 	if data["strain"] != "Dendrocalamus asper" {
 		t.Errorf("expected strain 'Dendrocalamus asper', got %v", data["strain"])
 	}
+	if data["visibility"] != "contact_hidden" {
+		t.Errorf("expected default visibility 'contact_hidden', got %v", data["visibility"])
+	}
 }
 
 func TestMarkerHandler_Create_MinimalFields(t *testing.T) {
@@ -400,7 +432,7 @@ func TestMarkerHandler_Create_MinimalFields(t *testing.T) {
 
 	userID := createTestUserForMarker(t)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	// Create form data with only required fields
 	fields := map[string]string{
@@ -448,7 +480,7 @@ func TestMarkerHandler_Create_ValidationErrors(t *testing.T) {
 
 	userID := createTestUserForMarker(t)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	// Missing required fields
 	fields := map[string]string{
@@ -500,7 +532,7 @@ func TestMarkerHandler_Create_InvalidQuantity(t *testing.T) {
 
 	userID := createTestUserForMarker(t)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	fields := map[string]string{
 		"name":      "Test Bamboo",
@@ -527,8 +559,386 @@ func TestMarkerHandler_Create_InvalidQuantity(t *testing.T) {
 	}
 }
 
+// createMarkerFormRequestWithFile creates a multipart request with form fields and an "image" file
+func createMarkerFormRequestWithFile(t *testing.T, fields map[string]string, filename string, content []byte) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			t.Fatalf("failed to write field %s: %v", key, err)
+		}
+	}
+
+	part, err := writer.CreateFormFile("image", filename)
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	if _, err := part.Write(content); err != nil {
+		t.Fatalf("failed to write form file: %v", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close multipart writer: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/markers", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestMarkerHandler_Create_RejectsNonImage(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	fields := map[string]string{
+		"name":      "Test Bamboo",
+		"latitude":  "-7.30000000",
+		"longitude": "110.50000000",
+	}
+
+	// Declared as a JPEG by filename, but the content is plain text
+	req := createMarkerFormRequestWithFile(t, fields, "photo.jpg", []byte("this is not an image"))
+	req = addClaimsToContext(req, userID)
+	rr := httptest.NewRecorder()
+
+	handler.Create(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+
+	var response Response
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	if response.Meta.Details["image"] == "" {
+		t.Errorf("expected image validation error, got %v", response.Meta.Details)
+	}
+
+	// No marker should have been created
+	var count int
+	testDB.QueryRow("SELECT COUNT(*) FROM markers").Scan(&count)
+	if count != 0 {
+		t.Errorf("expected no markers to be created, got %d", count)
+	}
+}
+
+// fakeStorage is an in-memory storage.Storage that records uploads and deletions
+type fakeStorage struct {
+	files     map[string][]byte
+	nextID    int
+	uploadErr error
+	deleted   []string
+}
+
+func newFakeStorage(existingIDs ...string) *fakeStorage {
+	f := &fakeStorage{files: make(map[string][]byte)}
+	for _, id := range existingIDs {
+		f.files[id] = []byte("existing")
+	}
+	return f
+}
+
+func (f *fakeStorage) UploadFile(file io.Reader, filename, mimeType string) (string, error) {
+	if f.uploadErr != nil {
+		return "", f.uploadErr
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	f.nextID++
+	id := fmt.Sprintf("fake-%d", f.nextID)
+	f.files[id] = data
+	return storage.FileURL(id), nil
+}
+
+func (f *fakeStorage) OpenFile(fileID string) (io.ReadCloser, string, int64, error) {
+	data, ok := f.files[fileID]
+	if !ok {
+		return nil, "", 0, errors.New("file not found")
+	}
+	return io.NopCloser(bytes.NewReader(data)), "image/jpeg", int64(len(data)), nil
+}
+
+func (f *fakeStorage) DeleteFile(fileID string) error {
+	f.deleted = append(f.deleted, fileID)
+	delete(f.files, fileID)
+	return nil
+}
+
+// testJPEG returns a small encoded JPEG image
+func testJPEG(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, img, nil); err != nil {
+		t.Fatalf("failed to encode test jpeg: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// updateMarkerFormRequestWithFile builds a PUT /markers/{id} request with an image
+func updateMarkerFormRequestWithFile(t *testing.T, markerID, userID uuid.UUID, fields map[string]string) *http.Request {
+	form := createMarkerFormRequestWithFile(t, fields, "photo.jpg", testJPEG(t))
+	req := httptest.NewRequest(http.MethodPut, "/markers/"+markerID.String(), form.Body)
+	req.Header = form.Header
+	return addClaimsToContext(req, userID)
+}
+
+func TestMarkerHandler_Create_DeletesImagesWhenInsertFails(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	store := newFakeStorage()
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{Storage: store})
+
+	// Passes request validation but is rejected by the DECIMAL column
+	fields := map[string]string{
+		"name":      "Test Bamboo",
+		"latitude":  "not-a-number",
+		"longitude": "110.50000000",
+	}
+
+	req := createMarkerFormRequestWithFile(t, fields, "photo.jpg", testJPEG(t))
+	req = addClaimsToContext(req, userID)
+	rr := httptest.NewRecorder()
+
+	handler.Create(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusInternalServerError, rr.Code, rr.Body.String())
+	}
+	if store.nextID != 3 {
+		t.Errorf("expected 3 variants to be uploaded, got %d", store.nextID)
+	}
+	if len(store.files) != 0 {
+		t.Errorf("expected uploaded images to be deleted, %d remain", len(store.files))
+	}
+}
+
+func TestMarkerHandler_Create_WithImage(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	store := newFakeStorage()
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{Storage: store})
+
+	fields := map[string]string{
+		"name":      "Test Bamboo",
+		"latitude":  "-7.30000000",
+		"longitude": "110.50000000",
+	}
+
+	req := createMarkerFormRequestWithFile(t, fields, "photo.jpg", testJPEG(t))
+	req = addClaimsToContext(req, userID)
+	rr := httptest.NewRecorder()
+
+	handler.Create(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	if len(store.files) != 3 || len(store.deleted) != 0 {
+		t.Errorf("expected 3 stored images and no deletions, got %d stored and %v deleted", len(store.files), store.deleted)
+	}
+}
+
+func TestMarkerHandler_Create_QueuesImage(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	store := newFakeStorage()
+	spoolDir := t.TempDir()
+	queue, err := markerimage.NewQueue(testQueries, store, markerimage.Config{SpoolDir: spoolDir})
+	if err != nil {
+		t.Fatalf("failed to create image queue: %v", err)
+	}
+
+	// The queue is not started, so the job stays pending
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{Storage: store, ImageQueue: queue})
+
+	fields := map[string]string{
+		"name":      "Test Bamboo",
+		"latitude":  "-7.30000000",
+		"longitude": "110.50000000",
+	}
+
+	req := createMarkerFormRequestWithFile(t, fields, "photo.jpg", testJPEG(t))
+	req = addClaimsToContext(req, userID)
+	rr := httptest.NewRecorder()
+
+	handler.Create(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var response Response
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	data := response.Data.(map[string]interface{})
+	if data["image_status"] != "pending" {
+		t.Errorf("expected image_status 'pending', got %v", data["image_status"])
+	}
+	if data["image_url"] != nil {
+		t.Errorf("expected no image_url while pending, got %v", data["image_url"])
+	}
+
+	// Nothing is uploaded during the request; the image waits in the spool
+	if len(store.files) != 0 {
+		t.Errorf("expected no uploads during the request, got %d", len(store.files))
+	}
+	entries, _ := os.ReadDir(spoolDir)
+	if len(entries) != 2 {
+		t.Errorf("expected spooled image and job files, got %d entries", len(entries))
+	}
+}
+
+func TestMarkerHandler_Update_ReplacesImageAfterCommit(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+	setTestMarkerImage(t, markerID)
+	store := newFakeStorage("orig", "med", "thumb")
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{Storage: store})
+
+	r := chi.NewRouter()
+	r.Put("/markers/{id}", handler.Update)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, updateMarkerFormRequestWithFile(t, markerID, userID, map[string]string{"name": "Updated"}))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	for _, id := range []string{"orig", "med", "thumb"} {
+		if _, ok := store.files[id]; ok {
+			t.Errorf("expected old image %s to be deleted", id)
+		}
+	}
+	if len(store.files) != 3 {
+		t.Errorf("expected 3 new images to remain, got %d", len(store.files))
+	}
+
+	marker, err := testQueries.GetMarkerByID(context.Background(), markerID)
+	if err != nil {
+		t.Fatalf("failed to fetch marker: %v", err)
+	}
+	if _, ok := store.files[storage.ExtractFileID(marker.ImageUrl.String)]; !ok {
+		t.Errorf("expected marker to reference a stored image, got %s", marker.ImageUrl.String)
+	}
+}
+
+func TestMarkerHandler_Update_KeepsOldImageWhenUpdateFails(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+	setTestMarkerImage(t, markerID)
+	store := newFakeStorage("orig", "med", "thumb")
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{Storage: store})
+
+	r := chi.NewRouter()
+	r.Put("/markers/{id}", handler.Update)
+
+	// Passes request validation but is rejected by the DECIMAL column
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, updateMarkerFormRequestWithFile(t, markerID, userID, map[string]string{"latitude": "not-a-number"}))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusInternalServerError, rr.Code, rr.Body.String())
+	}
+
+	// Only the old images remain: the new uploads were compensated
+	if len(store.files) != 3 {
+		t.Errorf("expected 3 images to remain, got %d", len(store.files))
+	}
+	for _, id := range []string{"orig", "med", "thumb"} {
+		if _, ok := store.files[id]; !ok {
+			t.Errorf("expected old image %s to be kept", id)
+		}
+	}
+
+	marker, err := testQueries.GetMarkerByID(context.Background(), markerID)
+	if err != nil {
+		t.Fatalf("failed to fetch marker: %v", err)
+	}
+	if marker.ImageUrl.String != storage.FileURL("orig") {
+		t.Errorf("expected marker to keep its old image, got %s", marker.ImageUrl.String)
+	}
+}
+
+func TestMarkerHandler_Update_KeepsOldImageWhenUploadFails(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+	setTestMarkerImage(t, markerID)
+	store := newFakeStorage("orig", "med", "thumb")
+	store.uploadErr = errors.New("storage unavailable")
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{Storage: store})
+
+	r := chi.NewRouter()
+	r.Put("/markers/{id}", handler.Update)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, updateMarkerFormRequestWithFile(t, markerID, userID, map[string]string{"name": "Updated"}))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusInternalServerError, rr.Code, rr.Body.String())
+	}
+	if len(store.deleted) != 0 {
+		t.Errorf("expected no images to be deleted, got %v", store.deleted)
+	}
+}
+
+func TestMarkerHandler_Delete_RemovesImages(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+	setTestMarkerImage(t, markerID)
+	store := newFakeStorage("orig", "med", "thumb")
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{Storage: store})
+
+	r := chi.NewRouter()
+	r.Delete("/markers/{id}", handler.Delete)
+
+	req := httptest.NewRequest(http.MethodDelete, "/markers/"+markerID.String(), nil)
+	req = addClaimsToContext(req, userID)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if len(store.files) != 0 {
+		t.Errorf("expected all images to be deleted, %d remain", len(store.files))
+	}
+}
+
 func TestMarkerHandler_Create_Unauthorized(t *testing.T) {
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	fields := map[string]string{
 		"name":      "Test Bamboo",
@@ -555,7 +965,7 @@ func TestMarkerHandler_Update_Success(t *testing.T) {
 	userID := createTestUserForMarker(t)
 	markerID := createTestMarker(t, userID)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	// Update all fields
 	fields := map[string]string{
@@ -567,6 +977,7 @@ func TestMarkerHandler_Update_Success(t *testing.T) {
 		"quantity":      "200",
 		"owner_name":    "Updated Owner",
 		"owner_contact": "08123456789",
+		"visibility":    "public",
 	}
 
 	req := createMarkerFormRequest(t, fields)
@@ -623,6 +1034,9 @@ func TestMarkerHandler_Update_Success(t *testing.T) {
 	if data["description"] != "Updated description" {
 		t.Errorf("expected description 'Updated description', got %v", data["description"])
 	}
+	if data["visibility"] != "public" {
+		t.Errorf("expected visibility 'public', got %v", data["visibility"])
+	}
 }
 
 func TestMarkerHandler_Update_PartialFields(t *testing.T) {
@@ -632,7 +1046,7 @@ func TestMarkerHandler_Update_PartialFields(t *testing.T) {
 	userID := createTestUserForMarker(t)
 	markerID := createTestMarker(t, userID)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	// Only update name - other fields should remain unchanged
 	fields := map[string]string{
@@ -679,7 +1093,7 @@ func TestMarkerHandler_Update_NotFound(t *testing.T) {
 
 	userID := createTestUserForMarker(t)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	randomID := uuid.New()
 
@@ -721,7 +1135,7 @@ func TestMarkerHandler_Update_Unauthorized(t *testing.T) {
 	userID := createTestUserForMarker(t)
 	markerID := createTestMarker(t, userID)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	fields := map[string]string{
 		"name": "Unauthorized Update",
@@ -743,120 +1157,988 @@ func TestMarkerHandler_Update_Unauthorized(t *testing.T) {
 	}
 }
 
-func TestMarkerHandler_Delete_Success(t *testing.T) {
+func TestMarkerHandler_Delete_Success(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	r := chi.NewRouter()
+	r.Delete("/markers/{id}", handler.Delete)
+
+	req := httptest.NewRequest(http.MethodDelete, "/markers/"+markerID.String(), nil)
+	req = addClaimsToContext(req, userID)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response Response
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if !response.Meta.Success {
+		t.Error("expected success=true")
+	}
+
+	if response.Meta.Message != "Marker deleted successfully" {
+		t.Errorf("unexpected message: %s", response.Meta.Message)
+	}
+
+	// Verify marker is actually deleted
+	_, err := testQueries.GetMarkerByID(context.Background(), markerID)
+	if err == nil {
+		t.Error("expected marker to be deleted, but it still exists")
+	}
+}
+
+func TestMarkerHandler_Delete_NotFound(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	randomID := uuid.New()
+
+	r := chi.NewRouter()
+	r.Delete("/markers/{id}", handler.Delete)
+
+	req := httptest.NewRequest(http.MethodDelete, "/markers/"+randomID.String(), nil)
+	req = addClaimsToContext(req, userID)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d: %s", http.StatusNotFound, rr.Code, rr.Body.String())
+	}
+
+	var response Response
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	if response.Meta.Success {
+		t.Error("expected success=false")
+	}
+
+	if response.Meta.Message != "Marker not found" {
+		t.Errorf("unexpected message: %s", response.Meta.Message)
+	}
+}
+
+func TestMarkerHandler_Delete_Unauthorized(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	r := chi.NewRouter()
+	r.Delete("/markers/{id}", handler.Delete)
+
+	req := httptest.NewRequest(http.MethodDelete, "/markers/"+markerID.String(), nil)
+	// Note: NOT adding claims to context
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d: %s", http.StatusUnauthorized, rr.Code, rr.Body.String())
+	}
+}
+
+func TestMarkerHandler_Delete_Permissions(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           string
+		ownMarker      bool
+		expectedStatus int
+	}{
+		{name: "surveyor deletes own marker", role: auth.RoleSurveyor, ownMarker: true, expectedStatus: http.StatusOK},
+		{name: "surveyor deletes other marker", role: auth.RoleSurveyor, ownMarker: false, expectedStatus: http.StatusForbidden},
+		{name: "editor deletes other marker", role: auth.RoleEditor, ownMarker: false, expectedStatus: http.StatusOK},
+		{name: "admin deletes other marker", role: auth.RoleAdmin, ownMarker: false, expectedStatus: http.StatusOK},
+		{name: "viewer deletes own marker", role: auth.RoleViewer, ownMarker: true, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanupMarkers(t)
+			cleanupUsers(t)
+
+			userID := createTestUserForMarker(t)
+			markerID := createTestMarker(t, userID)
+			callerID := userID
+			if !tt.ownMarker {
+				callerID = uuid.New()
+			}
+
+			handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+			r := chi.NewRouter()
+			r.Delete("/markers/{id}", handler.Delete)
+
+			req := httptest.NewRequest(http.MethodDelete, "/markers/"+markerID.String(), nil)
+			req = addRoleClaimsToContext(req, callerID, tt.role)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestMarkerHandler_Update_OtherSurveyorForbidden(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	r := chi.NewRouter()
+	r.Put("/markers/{id}", handler.Update)
+
+	reqBody := createMarkerFormRequest(t, map[string]string{"name": "Hijacked"})
+	req := httptest.NewRequest(http.MethodPut, "/markers/"+markerID.String(), reqBody.Body)
+	req.Header = reqBody.Header
+	req = addRoleClaimsToContext(req, uuid.New(), auth.RoleSurveyor)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d: %s", http.StatusForbidden, rr.Code, rr.Body.String())
+	}
+
+	var response Response
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if response.Meta.Success {
+		t.Error("expected success=false")
+	}
+
+	marker, err := testQueries.GetMarkerByID(context.Background(), markerID)
+	if err != nil {
+		t.Fatalf("failed to fetch marker: %v", err)
+	}
+	if marker.Name != "Test Bamboo" {
+		t.Errorf("expected marker to be unchanged, got name %q", marker.Name)
+	}
+}
+
+func TestMarkerHandler_GetByShortCode_Success(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	createTestMarker(t, userID) // Creates marker with short_code "TEST001"
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	r := chi.NewRouter()
+	r.Get("/markers/code/{shortCode}", handler.GetByShortCode)
+
+	req := httptest.NewRequest(http.MethodGet, "/markers/code/TEST001", nil)
+	req = addClaimsToContext(req, userID)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response Response
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if !response.Meta.Success {
+		t.Error("expected success=true")
+	}
+
+	data, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected data to be a map, got %T", response.Data)
+	}
+
+	if data["short_code"] != "TEST001" {
+		t.Errorf("expected short_code 'TEST001', got %v", data["short_code"])
+	}
+	if data["name"] != "Test Bamboo" {
+		t.Errorf("expected name 'Test Bamboo', got %v", data["name"])
+	}
+}
+
+func TestMarkerHandler_GetByShortCode_NotFound(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	r := chi.NewRouter()
+	r.Get("/markers/code/{shortCode}", handler.GetByShortCode)
+
+	req := httptest.NewRequest(http.MethodGet, "/markers/code/NOTEXIST", nil)
+	req = addClaimsToContext(req, userID)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d: %s", http.StatusNotFound, rr.Code, rr.Body.String())
+	}
+
+	var response Response
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	if response.Meta.Success {
+		t.Error("expected success=false")
+	}
+
+	if response.Meta.Message != "Marker not found" {
+		t.Errorf("unexpected message: %s", response.Meta.Message)
+	}
+}
+
+func TestMarkerHandler_GetByShortCode_Normalized(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	createTestMarker(t, userID) // Legacy code "TEST001"
+
+	// A current code: 7 characters plus its check character
+	_, err := testDB.Exec(`
+		INSERT INTO markers (creator_id, short_code, name, latitude, longitude)
+		VALUES ($1, $2, $3, $4, $5)
+	`, userID, "10AB2CDN", "Checked Bamboo", "-7.1", "110.1")
+	if err != nil {
+		t.Fatalf("failed to create marker: %v", err)
+	}
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	r := chi.NewRouter()
+	r.Get("/markers/code/{shortCode}", handler.GetByShortCode)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"10AB2CDN", "10AB2CDN"},
+		{"10ab-2cdn", "10AB2CDN"},
+		{"IOAB2CDN", "10AB2CDN"},
+		{"lOab2cdn", "10AB2CDN"},
+		{"test001", "TEST001"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/markers/code/"+tt.input, nil)
+		req = addClaimsToContext(req, userID)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.input, http.StatusOK, rr.Code, rr.Body.String())
+			continue
+		}
+
+		var response Response
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		data := response.Data.(map[string]interface{})
+		if data["short_code"] != tt.expected {
+			t.Errorf("%s: expected short_code %s, got %v", tt.input, tt.expected, data["short_code"])
+		}
+	}
+}
+
+func TestMarkerHandler_GetByShortCode_Mistyped(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	r := chi.NewRouter()
+	r.Get("/markers/code/{shortCode}", handler.GetByShortCode)
+
+	// 10AB2CDN with its check character mistyped
+	req := httptest.NewRequest(http.MethodGet, "/markers/code/10AB2CDM", nil)
+	req = addClaimsToContext(req, userID)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNotFound, rr.Code, rr.Body.String())
+	}
+
+	var response Response
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if _, ok := response.Meta.Details["short_code"]; !ok {
+		t.Errorf("expected a short_code detail pointing out the typo, got %v", response.Meta.Details)
+	}
+}
+
+func TestMarkerHandler_ShowPage(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	createTestMarker(t, userID)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{
+		DeepLinkBaseURL: "https://test.bamboomapper.com",
+		AppLinks:        AppLinksConfig{AndroidPackage: "com.sapuran.bamboomapper", AppleAppStoreID: "123456789"},
+	})
+
+	r := chi.NewRouter()
+	r.Get("/marker/{shortCode}", handler.ShowPage)
+	r.Get("/m/{shortCode}", handler.ShowPage)
+
+	req := httptest.NewRequest(http.MethodGet, "/marker/TEST001", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("expected an HTML page, got %s", got)
+	}
+	// Shared caches must not answer scans without the server counting them
+	if got := rr.Header().Get("Cache-Control"); got != "private, no-cache" {
+		t.Errorf("expected private, no-cache, got %s", got)
+	}
+	for _, want := range []string{
+		"<h1>Test Bamboo</h1>",
+		"Bambusa vulgaris",
+		`<link rel="canonical" href="https://test.bamboomapper.com/marker/TEST001">`,
+		"app-id=123456789",
+		"intent://test.bamboomapper.com/marker/TEST001#Intent;scheme=https;package=com.sapuran.bamboomapper;",
+	} {
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("expected page to contain %q", want)
+		}
+	}
+	// Short links and loosely typed codes redirect to the canonical page
+	for _, path := range []string{"/m/TEST001", "/marker/test001"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusFound {
+			t.Errorf("%s: expected status %d, got %d", path, http.StatusFound, rr.Code)
+		}
+		if got := rr.Header().Get("Location"); got != "/marker/TEST001" {
+			t.Errorf("%s: expected redirect to /marker/TEST001, got %s", path, got)
+		}
+	}
+}
+
+func TestMarkerHandler_ShowPage_NotFound(t *testing.T) {
+	cleanupMarkers(t)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	r := chi.NewRouter()
+	r.Get("/m/{shortCode}", handler.ShowPage)
+
+	req := httptest.NewRequest(http.MethodGet, "/m/10AB2CDM", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "Marker not found") || !strings.Contains(rr.Body.String(), "mistyped") {
+		t.Errorf("expected a not found page pointing out the typo, got %s", rr.Body.String())
+	}
+}
+
+func TestMarkerHandler_GenerateQR_Success(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	r := chi.NewRouter()
+	r.Get("/markers/{id}/qr", handler.GenerateQR)
+
+	req := httptest.NewRequest(http.MethodGet, "/markers/"+markerID.String()+"/qr", nil)
+	req = addClaimsToContext(req, userID)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	// Verify Content-Type is image/png
+	contentType := rr.Header().Get("Content-Type")
+	if contentType != "image/png" {
+		t.Errorf("expected Content-Type 'image/png', got %s", contentType)
+	}
+
+	// Verify Content-Disposition header
+	contentDisposition := rr.Header().Get("Content-Disposition")
+	if contentDisposition == "" {
+		t.Error("expected Content-Disposition header to be set")
+	}
+
+	// Verify response body is not empty (contains PNG data)
+	if rr.Body.Len() == 0 {
+		t.Error("expected response body to contain PNG data")
+	}
+
+	// Verify PNG magic bytes (first 8 bytes of PNG file)
+	pngMagic := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
+	bodyBytes := rr.Body.Bytes()
+	if len(bodyBytes) < 8 {
+		t.Errorf("response body too short to be a valid PNG")
+	} else {
+		for i := 0; i < 8; i++ {
+			if bodyBytes[i] != pngMagic[i] {
+				t.Errorf("invalid PNG magic bytes at position %d: expected %x, got %x", i, pngMagic[i], bodyBytes[i])
+				break
+			}
+		}
+	}
+}
+
+func TestMarkerHandler_GenerateQR_NotFound(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	randomID := uuid.New()
+
+	r := chi.NewRouter()
+	r.Get("/markers/{id}/qr", handler.GenerateQR)
+
+	req := httptest.NewRequest(http.MethodGet, "/markers/"+randomID.String()+"/qr", nil)
+	req = addClaimsToContext(req, userID)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d: %s", http.StatusNotFound, rr.Code, rr.Body.String())
+	}
+
+	var response Response
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	if response.Meta.Success {
+		t.Error("expected success=false")
+	}
+
+	if response.Meta.Message != "Marker not found" {
+		t.Errorf("unexpected message: %s", response.Meta.Message)
+	}
+}
+
+func TestMarkerHandler_GenerateQR_Unauthorized(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	r := chi.NewRouter()
+	r.Get("/markers/{id}/qr", handler.GenerateQR)
+
+	req := httptest.NewRequest(http.MethodGet, "/markers/"+markerID.String()+"/qr", nil)
+	// Note: NOT adding claims to context
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d: %s", http.StatusUnauthorized, rr.Code, rr.Body.String())
+	}
+}
+
+func TestMarkerHandler_GenerateQR_InvalidID(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	r := chi.NewRouter()
+	r.Get("/markers/{id}/qr", handler.GenerateQR)
+
+	req := httptest.NewRequest(http.MethodGet, "/markers/invalid-uuid/qr", nil)
+	req = addClaimsToContext(req, userID)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+
+	var response Response
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	if response.Meta.Success {
+		t.Error("expected success=false")
+	}
+
+	if response.Meta.Message != "Invalid marker ID" {
+		t.Errorf("unexpected message: %s", response.Meta.Message)
+	}
+}
+
+func TestMarkerHandler_GenerateQR_Formats(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	r := chi.NewRouter()
+	r.Get("/markers/{id}/qr", handler.GenerateQR)
+
+	tests := []struct {
+		query       string
+		contentType string
+		prefix      string
+	}{
+		{"format=svg&size=512&ec=H&margin=2", "image/svg+xml", "<?xml"},
+		{"format=pdf&logo=none", "application/pdf", "%PDF-"},
+		{"format=png&ec=L&logo=none", "image/png", "\x89PNG"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/markers/"+markerID.String()+"/qr?"+tt.query, nil)
+		req = addClaimsToContext(req, userID)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.query, http.StatusOK, rr.Code, rr.Body.String())
+			continue
+		}
+		if got := rr.Header().Get("Content-Type"); got != tt.contentType {
+			t.Errorf("%s: expected Content-Type %s, got %s", tt.query, tt.contentType, got)
+		}
+		if !strings.HasPrefix(rr.Body.String(), tt.prefix) {
+			t.Errorf("%s: unexpected body prefix %q", tt.query, rr.Body.String()[:min(8, rr.Body.Len())])
+		}
+	}
+}
+
+func TestMarkerHandler_GenerateQR_InvalidOptions(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+
+	brandings, err := branding.Load("", branding.Settings{})
+	if err != nil {
+		t.Fatalf("Failed to load branding: %v", err)
+	}
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{
+		DeepLinkBaseURL: "https://test.bamboomapper.com",
+		Brandings:       brandings,
+	})
+
+	r := chi.NewRouter()
+	r.Get("/markers/{id}/qr", handler.GenerateQR)
+
+	tests := []struct {
+		query string
+		field string
+	}{
+		{"format=gif", "format"},
+		{"size=10", "size"},
+		{"size=abc", "size"},
+		{"ec=X", "ec"},
+		{"margin=-1", "margin"},
+		{"logo=unknown", "logo"},
+		{"branding=unknown", "branding"},
+		// The default logo needs more recovery than level L offers
+		{"ec=L", "ec"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/markers/"+markerID.String()+"/qr?"+tt.query, nil)
+		req = addClaimsToContext(req, userID)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", tt.query, http.StatusBadRequest, rr.Code)
+			continue
+		}
+
+		var response Response
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		if _, ok := response.Meta.Details[tt.field]; !ok {
+			t.Errorf("%s: expected details for %s, got %v", tt.query, tt.field, response.Meta.Details)
+		}
+	}
+}
+
+func TestMarkerHandler_GenerateQR_Branding(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "branding.json")
+	config := `{"default": "sapuran", "brandings": [
+		{"name": "sapuran", "foreground": "#1b5e20", "caption": "Sapuran Berperan"},
+		{"name": "plain", "logo": "none"}
+	]}`
+	if err := os.WriteFile(file, []byte(config), 0o644); err != nil {
+		t.Fatalf("Failed to write branding file: %v", err)
+	}
+	brandings, err := branding.Load(file, branding.Settings{})
+	if err != nil {
+		t.Fatalf("Failed to load branding: %v", err)
+	}
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{
+		DeepLinkBaseURL: "https://test.bamboomapper.com",
+		Brandings:       brandings,
+	})
+
+	r := chi.NewRouter()
+	r.Get("/markers/{id}/qr", handler.GenerateQR)
+
+	tests := []struct {
+		query   string
+		want    []string
+		notWant []string
+	}{
+		{"format=svg", []string{`fill="#1b5e20"`, "Sapuran Berperan</text>", "<image"}, nil},
+		{"format=svg&branding=plain", []string{`fill="#000000"`}, []string{"<text", "<image"}},
+		{"format=svg&logo=none", []string{"Sapuran Berperan</text>"}, []string{"<image"}},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/markers/"+markerID.String()+"/qr?"+tt.query, nil)
+		req = addClaimsToContext(req, userID)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.query, http.StatusOK, rr.Code, rr.Body.String())
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(rr.Body.String(), want) {
+				t.Errorf("%s: expected SVG to contain %q", tt.query, want)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(rr.Body.String(), notWant) {
+				t.Errorf("%s: expected SVG not to contain %q", tt.query, notWant)
+			}
+		}
+	}
+}
+
+func TestMarkerHandler_GenerateLabels_ByIDs(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	body := fmt.Sprintf(`{"marker_ids": [%q], "template": "letter-3x10"}`, markerID)
+	req := httptest.NewRequest(http.MethodPost, "/markers/labels", strings.NewReader(body))
+	req = addClaimsToContext(req, userID)
+
+	rr := httptest.NewRecorder()
+	handler.GenerateLabels(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Type"); got != "application/pdf" {
+		t.Errorf("expected Content-Type application/pdf, got %s", got)
+	}
+	if !strings.HasPrefix(rr.Body.String(), "%PDF-") {
+		t.Error("expected a PDF document")
+	}
+	for _, want := range []string{"(Test Bamboo)", "(TEST001)", "(Bambusa vulgaris)"} {
+		if !pdfContains(t, rr.Body.Bytes(), want) {
+			t.Errorf("expected label text %s", want)
+		}
+	}
+}
+
+// pdfContains reports whether any compressed stream of a PDF contains s
+func pdfContains(t *testing.T, doc []byte, s string) bool {
+	t.Helper()
+	for _, part := range bytes.Split(doc, []byte("stream\n"))[1:] {
+		zr, err := zlib.NewReader(bytes.NewReader(part))
+		if err != nil {
+			continue
+		}
+		data, _ := io.ReadAll(zr)
+		if bytes.Contains(data, []byte(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMarkerHandler_GenerateLabels_ByFilters(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	createTestMarker(t, userID)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	// Empty body: default template, markers selected by query parameters
+	req := httptest.NewRequest(http.MethodPost, "/markers/labels?search=Bambusa&creator_id="+userID.String(), nil)
+	req = addClaimsToContext(req, userID)
+
+	rr := httptest.NewRecorder()
+	handler.GenerateLabels(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Header().Get("Content-Disposition"), "a4-3x8") {
+		t.Errorf("expected the default template, got %s", rr.Header().Get("Content-Disposition"))
+	}
+
+	// Filters matching nothing
+	req = httptest.NewRequest(http.MethodPost, "/markers/labels?search=nothing-matches", nil)
+	req = addClaimsToContext(req, userID)
+
+	rr = httptest.NewRecorder()
+	handler.GenerateLabels(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestMarkerHandler_GenerateLabels_MissingMarker(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+	missingID := uuid.New()
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	body := fmt.Sprintf(`{"marker_ids": [%q, %q]}`, markerID, missingID)
+	req := httptest.NewRequest(http.MethodPost, "/markers/labels", strings.NewReader(body))
+	req = addClaimsToContext(req, userID)
+
+	rr := httptest.NewRecorder()
+	handler.GenerateLabels(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	var response Response
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if response.Meta.Details["marker_ids"] != missingID.String() {
+		t.Errorf("expected the missing ID in details, got %v", response.Meta.Details)
+	}
+}
+
+func TestMarkerHandler_GenerateLabels_InvalidRequest(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	tests := []struct {
+		body  string
+		field string
+	}{
+		{`{"template": "a5-2x4"}`, "template"},
+		{fmt.Sprintf(`{"marker_ids": [%s]}`, strings.TrimSuffix(strings.Repeat(`"`+uuid.NewString()+`",`, model.MaxLabelsPerRequest+1), ",")), "marker_ids"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/markers/labels", strings.NewReader(tt.body))
+		req = addClaimsToContext(req, userID)
+
+		rr := httptest.NewRecorder()
+		handler.GenerateLabels(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", tt.field, http.StatusBadRequest, rr.Code)
+			continue
+		}
+
+		var response Response
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		if _, ok := response.Meta.Details[tt.field]; !ok {
+			t.Errorf("expected details for %s, got %v", tt.field, response.Meta.Details)
+		}
+	}
+}
+
+func TestMarkerHandler_GenerateQRArchive_Success(t *testing.T) {
 	cleanupMarkers(t)
 	cleanupUsers(t)
 
 	userID := createTestUserForMarker(t)
-	markerID := createTestMarker(t, userID)
-
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	createTestMarker(t, userID)
+	if _, err := testDB.Exec(`
+		INSERT INTO markers (creator_id, short_code, name, latitude, longitude)
+		VALUES ($1, 'TEST002', 'Second Bamboo', '-7.1', '110.1')
+	`, userID); err != nil {
+		t.Fatalf("failed to create second marker: %v", err)
+	}
 
-	r := chi.NewRouter()
-	r.Delete("/markers/{id}", handler.Delete)
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
-	req := httptest.NewRequest(http.MethodDelete, "/markers/"+markerID.String(), nil)
+	req := httptest.NewRequest(http.MethodGet, "/markers/qr-archive?format=svg&sort_by=name&sort_dir=asc", nil)
 	req = addClaimsToContext(req, userID)
 
 	rr := httptest.NewRecorder()
-	r.ServeHTTP(rr, req)
+	handler.GenerateQRArchive(rr, req)
 
 	if rr.Code != http.StatusOK {
-		t.Errorf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
 	}
-
-	var response Response
-	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
-		t.Fatalf("failed to parse response: %v", err)
+	if got := rr.Header().Get("Content-Type"); got != "application/zip" {
+		t.Errorf("expected Content-Type application/zip, got %s", got)
 	}
 
-	if !response.Meta.Success {
-		t.Error("expected success=true")
+	zr, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
 	}
 
-	if response.Meta.Message != "Marker deleted successfully" {
-		t.Errorf("unexpected message: %s", response.Meta.Message)
+	var names []string
+	var manifest []byte
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Name == "manifest.csv" {
+			rc, _ := f.Open()
+			manifest, _ = io.ReadAll(rc)
+			rc.Close()
+		}
+	}
+	if strings.Join(names, ",") != "TEST002.svg,TEST001.svg,manifest.csv" {
+		t.Errorf("unexpected archive entries: %v", names)
 	}
 
-	// Verify marker is actually deleted
-	_, err := testQueries.GetMarkerByID(context.Background(), markerID)
-	if err == nil {
-		t.Error("expected marker to be deleted, but it still exists")
+	records, err := csv.NewReader(bytes.NewReader(manifest)).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse manifest: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected header and 2 rows, got %v", records)
+	}
+	want := []string{"TEST002", "Second Bamboo", "https://test.bamboomapper.com/marker/TEST002", "TEST002.svg"}
+	if strings.Join(records[1], ",") != strings.Join(want, ",") {
+		t.Errorf("unexpected manifest row: %v", records[1])
 	}
 }
 
-func TestMarkerHandler_Delete_NotFound(t *testing.T) {
+func TestMarkerHandler_GenerateQRArchive_InvalidFormat(t *testing.T) {
 	cleanupMarkers(t)
 	cleanupUsers(t)
 
 	userID := createTestUserForMarker(t)
+	createTestMarker(t, userID)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
-
-	randomID := uuid.New()
-
-	r := chi.NewRouter()
-	r.Delete("/markers/{id}", handler.Delete)
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
-	req := httptest.NewRequest(http.MethodDelete, "/markers/"+randomID.String(), nil)
+	req := httptest.NewRequest(http.MethodGet, "/markers/qr-archive?format=pdf", nil)
 	req = addClaimsToContext(req, userID)
 
 	rr := httptest.NewRecorder()
-	r.ServeHTTP(rr, req)
-
-	if rr.Code != http.StatusNotFound {
-		t.Errorf("expected status %d, got %d: %s", http.StatusNotFound, rr.Code, rr.Body.String())
-	}
-
-	var response Response
-	json.Unmarshal(rr.Body.Bytes(), &response)
-
-	if response.Meta.Success {
-		t.Error("expected success=false")
-	}
+	handler.GenerateQRArchive(rr, req)
 
-	if response.Meta.Message != "Marker not found" {
-		t.Errorf("unexpected message: %s", response.Meta.Message)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
 	}
 }
 
-func TestMarkerHandler_Delete_Unauthorized(t *testing.T) {
+func TestMarkerHandler_GenerateQRArchive_NoMatches(t *testing.T) {
 	cleanupMarkers(t)
 	cleanupUsers(t)
 
 	userID := createTestUserForMarker(t)
-	markerID := createTestMarker(t, userID)
-
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
 
-	r := chi.NewRouter()
-	r.Delete("/markers/{id}", handler.Delete)
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
-	req := httptest.NewRequest(http.MethodDelete, "/markers/"+markerID.String(), nil)
-	// Note: NOT adding claims to context
+	req := httptest.NewRequest(http.MethodGet, "/markers/qr-archive", nil)
+	req = addClaimsToContext(req, userID)
 
 	rr := httptest.NewRecorder()
-	r.ServeHTTP(rr, req)
+	handler.GenerateQRArchive(rr, req)
 
-	if rr.Code != http.StatusUnauthorized {
-		t.Errorf("expected status %d, got %d: %s", http.StatusUnauthorized, rr.Code, rr.Body.String())
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
 	}
 }
 
-func TestMarkerHandler_GetByShortCode_Success(t *testing.T) {
+func TestMarkerHandler_GetByShortCode_Public(t *testing.T) {
 	cleanupMarkers(t)
 	cleanupUsers(t)
 
 	userID := createTestUserForMarker(t)
-	createTestMarker(t, userID) // Creates marker with short_code "TEST001"
+	createTestMarker(t, userID)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	r := chi.NewRouter()
 	r.Get("/markers/code/{shortCode}", handler.GetByShortCode)
 
+	// Request WITHOUT auth context - should still work since it's public
 	req := httptest.NewRequest(http.MethodGet, "/markers/code/TEST001", nil)
-	req = addClaimsToContext(req, userID)
 
 	rr := httptest.NewRecorder()
 	r.ServeHTTP(rr, req)
@@ -871,36 +2153,106 @@ func TestMarkerHandler_GetByShortCode_Success(t *testing.T) {
 	}
 
 	if !response.Meta.Success {
-		t.Error("expected success=true")
+		t.Error("expected success=true for public endpoint")
 	}
+}
 
-	data, ok := response.Data.(map[string]interface{})
-	if !ok {
-		t.Fatalf("expected data to be a map, got %T", response.Data)
+// setTestMarkerVisibility sets the visibility and owner details of a test marker
+func setTestMarkerVisibility(t *testing.T, markerID uuid.UUID, visibility string) {
+	_, err := testDB.Exec(`
+		UPDATE markers
+		SET visibility = $2, owner_name = $3, owner_contact = $4
+		WHERE id = $1
+	`, markerID, visibility, "Pak Budi", "08123456789")
+	if err != nil {
+		t.Fatalf("failed to set test marker visibility: %v", err)
 	}
+}
 
-	if data["short_code"] != "TEST001" {
-		t.Errorf("expected short_code 'TEST001', got %v", data["short_code"])
+// getByShortCodeData looks up TEST001 and returns the response data
+func getByShortCodeData(t *testing.T, handler *MarkerHandler, userID *uuid.UUID) map[string]interface{} {
+	r := chi.NewRouter()
+	r.Get("/markers/code/{shortCode}", handler.GetByShortCode)
+
+	req := httptest.NewRequest(http.MethodGet, "/markers/code/TEST001", nil)
+	if userID != nil {
+		req = addClaimsToContext(req, *userID)
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var response Response
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	return response.Data.(map[string]interface{})
+}
+
+func TestMarkerHandler_GetByShortCode_PublicVisibility(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+	setTestMarkerVisibility(t, markerID, "public")
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+	data := getByShortCodeData(t, handler, nil)
+
+	if data["owner_name"] != "Pak Budi" || data["owner_contact"] != "08123456789" {
+		t.Errorf("expected owner details of a public marker, got %v and %v", data["owner_name"], data["owner_contact"])
+	}
+	if _, ok := data["creator_id"]; ok {
+		t.Error("expected creator_id to be hidden from unauthenticated callers")
+	}
+}
+
+func TestMarkerHandler_GetByShortCode_ContactHidden(t *testing.T) {
+	cleanupMarkers(t)
+	cleanupUsers(t)
+
+	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+	setTestMarkerVisibility(t, markerID, "contact_hidden")
+
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
+
+	data := getByShortCodeData(t, handler, nil)
+	if data["owner_name"] != nil || data["owner_contact"] != nil {
+		t.Errorf("expected owner details to be hidden, got %v and %v", data["owner_name"], data["owner_contact"])
 	}
 	if data["name"] != "Test Bamboo" {
 		t.Errorf("expected name 'Test Bamboo', got %v", data["name"])
 	}
+
+	// Authenticated callers see everything
+	data = getByShortCodeData(t, handler, &userID)
+	if data["owner_contact"] != "08123456789" {
+		t.Errorf("expected owner contact for authenticated caller, got %v", data["owner_contact"])
+	}
+	if data["visibility"] != "contact_hidden" {
+		t.Errorf("expected visibility 'contact_hidden', got %v", data["visibility"])
+	}
 }
 
-func TestMarkerHandler_GetByShortCode_NotFound(t *testing.T) {
+func TestMarkerHandler_GetByShortCode_Private(t *testing.T) {
 	cleanupMarkers(t)
 	cleanupUsers(t)
 
 	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+	setTestMarkerVisibility(t, markerID, "private")
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	r := chi.NewRouter()
 	r.Get("/markers/code/{shortCode}", handler.GetByShortCode)
 
-	req := httptest.NewRequest(http.MethodGet, "/markers/code/NOTEXIST", nil)
-	req = addClaimsToContext(req, userID)
-
+	req := httptest.NewRequest(http.MethodGet, "/markers/code/TEST001", nil)
 	rr := httptest.NewRecorder()
 	r.ServeHTTP(rr, req)
 
@@ -908,192 +2260,224 @@ func TestMarkerHandler_GetByShortCode_NotFound(t *testing.T) {
 		t.Errorf("expected status %d, got %d: %s", http.StatusNotFound, rr.Code, rr.Body.String())
 	}
 
-	var response Response
-	json.Unmarshal(rr.Body.Bytes(), &response)
+	var scans int
+	if err := testDB.QueryRow("SELECT COUNT(*) FROM marker_scans WHERE marker_id = $1", markerID).Scan(&scans); err != nil {
+		t.Fatalf("failed to count scans: %v", err)
+	}
+	if scans != 0 {
+		t.Errorf("expected hidden lookup not to be recorded, got %d scans", scans)
+	}
 
-	if response.Meta.Success {
-		t.Error("expected success=false")
+	data := getByShortCodeData(t, handler, &userID)
+	if data["visibility"] != "private" {
+		t.Errorf("expected visibility 'private', got %v", data["visibility"])
 	}
+}
 
-	if response.Meta.Message != "Marker not found" {
-		t.Errorf("unexpected message: %s", response.Meta.Message)
+// setTestMarkerImage stores an image reference on a test marker
+func setTestMarkerImage(t *testing.T, markerID uuid.UUID) {
+	_, err := testDB.Exec(`
+		UPDATE markers
+		SET image_url = $2, image_medium_url = $3, image_thumbnail_url = $4
+		WHERE id = $1
+	`, markerID, "https://drive.google.com/uc?id=orig", "https://drive.google.com/uc?id=med", "https://drive.google.com/uc?id=thumb")
+	if err != nil {
+		t.Fatalf("failed to set test marker image: %v", err)
 	}
 }
 
-func TestMarkerHandler_GenerateQR_Success(t *testing.T) {
+func TestMarkerHandler_GetByShortCode_SignedImageLinks(t *testing.T) {
 	cleanupMarkers(t)
 	cleanupUsers(t)
 
 	userID := createTestUserForMarker(t)
 	markerID := createTestMarker(t, userID)
+	setTestMarkerImage(t, markerID)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{
+		URLSigner:       auth.NewURLSigner("test-secret", time.Hour),
+		DeepLinkBaseURL: "https://test.bamboomapper.com",
+		APIBaseURL:      "https://api.test.bamboomapper.com",
+	})
 
 	r := chi.NewRouter()
-	r.Get("/markers/{id}/qr", handler.GenerateQR)
-
-	req := httptest.NewRequest(http.MethodGet, "/markers/"+markerID.String()+"/qr", nil)
-	req = addClaimsToContext(req, userID)
+	r.Get("/markers/code/{shortCode}", handler.GetByShortCode)
 
+	req := httptest.NewRequest(http.MethodGet, "/markers/code/TEST001", nil)
 	rr := httptest.NewRecorder()
 	r.ServeHTTP(rr, req)
 
 	if rr.Code != http.StatusOK {
-		t.Errorf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
 	}
 
-	// Verify Content-Type is image/png
-	contentType := rr.Header().Get("Content-Type")
-	if contentType != "image/png" {
-		t.Errorf("expected Content-Type 'image/png', got %s", contentType)
+	var response Response
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
 	}
 
-	// Verify Content-Disposition header
-	contentDisposition := rr.Header().Get("Content-Disposition")
-	if contentDisposition == "" {
-		t.Error("expected Content-Disposition header to be set")
+	data := response.Data.(map[string]interface{})
+	thumbnail, _ := data["image_thumbnail_url"].(string)
+	if strings.Contains(thumbnail, "drive.google.com") {
+		t.Errorf("expected storage URL to be hidden, got %s", thumbnail)
 	}
 
-	// Verify response body is not empty (contains PNG data)
-	if rr.Body.Len() == 0 {
-		t.Error("expected response body to contain PNG data")
+	link, err := url.Parse(thumbnail)
+	if err != nil {
+		t.Fatalf("failed to parse image link: %v", err)
 	}
-
-	// Verify PNG magic bytes (first 8 bytes of PNG file)
-	pngMagic := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
-	bodyBytes := rr.Body.Bytes()
-	if len(bodyBytes) < 8 {
-		t.Errorf("response body too short to be a valid PNG")
-	} else {
-		for i := 0; i < 8; i++ {
-			if bodyBytes[i] != pngMagic[i] {
-				t.Errorf("invalid PNG magic bytes at position %d: expected %x, got %x", i, pngMagic[i], bodyBytes[i])
-				break
-			}
-		}
+	expectedPath := "/v1/markers/" + markerID.String() + "/image"
+	if link.Host != "api.test.bamboomapper.com" || link.Path != expectedPath {
+		t.Errorf("unexpected image link: %s", thumbnail)
+	}
+	if link.Query().Get("variant") != "thumbnail" {
+		t.Errorf("expected variant thumbnail, got %s", link.Query().Get("variant"))
+	}
+	if link.Query().Get("signature") == "" || link.Query().Get("expires") == "" {
+		t.Errorf("expected signed image link, got %s", thumbnail)
 	}
 }
 
-func TestMarkerHandler_GenerateQR_NotFound(t *testing.T) {
+func TestMarkerHandler_GetImage_Unauthorized(t *testing.T) {
 	cleanupMarkers(t)
 	cleanupUsers(t)
 
 	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+	setTestMarkerImage(t, markerID)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
-
-	randomID := uuid.New()
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{
+		URLSigner: auth.NewURLSigner("test-secret", time.Hour),
+	})
 
 	r := chi.NewRouter()
-	r.Get("/markers/{id}/qr", handler.GenerateQR)
-
-	req := httptest.NewRequest(http.MethodGet, "/markers/"+randomID.String()+"/qr", nil)
-	req = addClaimsToContext(req, userID)
-
-	rr := httptest.NewRecorder()
-	r.ServeHTTP(rr, req)
+	r.Get("/markers/{id}/image", handler.GetImage)
 
-	if rr.Code != http.StatusNotFound {
-		t.Errorf("expected status %d, got %d: %s", http.StatusNotFound, rr.Code, rr.Body.String())
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"no signature", ""},
+		{"bad signature", "?expires=9999999999&signature=deadbeef"},
 	}
 
-	var response Response
-	json.Unmarshal(rr.Body.Bytes(), &response)
-
-	if response.Meta.Success {
-		t.Error("expected success=false")
-	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/markers/"+markerID.String()+"/image"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
 
-	if response.Meta.Message != "Marker not found" {
-		t.Errorf("unexpected message: %s", response.Meta.Message)
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("expected status %d, got %d: %s", http.StatusUnauthorized, rr.Code, rr.Body.String())
+			}
+		})
 	}
 }
 
-func TestMarkerHandler_GenerateQR_Unauthorized(t *testing.T) {
+func TestMarkerHandler_GetImage_SignedLinkWithoutStorage(t *testing.T) {
 	cleanupMarkers(t)
 	cleanupUsers(t)
 
 	userID := createTestUserForMarker(t)
 	markerID := createTestMarker(t, userID)
+	setTestMarkerImage(t, markerID)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	signer := auth.NewURLSigner("test-secret", time.Hour)
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{URLSigner: signer})
 
 	r := chi.NewRouter()
-	r.Get("/markers/{id}/qr", handler.GenerateQR)
-
-	req := httptest.NewRequest(http.MethodGet, "/markers/"+markerID.String()+"/qr", nil)
-	// Note: NOT adding claims to context
+	r.Get("/markers/{id}/image", handler.GetImage)
 
+	// A valid signature passes the auth check and reaches the storage backend
+	link := handler.imageLink(markerID, imaging.VariantMedium)
+	req := httptest.NewRequest(http.MethodGet, strings.TrimPrefix(*link, "/v1"), nil)
 	rr := httptest.NewRecorder()
 	r.ServeHTTP(rr, req)
 
-	if rr.Code != http.StatusUnauthorized {
-		t.Errorf("expected status %d, got %d: %s", http.StatusUnauthorized, rr.Code, rr.Body.String())
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d: %s", http.StatusServiceUnavailable, rr.Code, rr.Body.String())
 	}
 }
 
-func TestMarkerHandler_GenerateQR_InvalidID(t *testing.T) {
+func TestMarkerHandler_GetImage_StreamsFromStorage(t *testing.T) {
 	cleanupMarkers(t)
 	cleanupUsers(t)
 
 	userID := createTestUserForMarker(t)
+	markerID := createTestMarker(t, userID)
+	setTestMarkerImage(t, markerID)
+	store := newFakeStorage("orig", "med", "thumb")
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{Storage: store})
 
 	r := chi.NewRouter()
-	r.Get("/markers/{id}/qr", handler.GenerateQR)
+	r.Get("/markers/{id}/image", handler.GetImage)
 
-	req := httptest.NewRequest(http.MethodGet, "/markers/invalid-uuid/qr", nil)
+	req := httptest.NewRequest(http.MethodGet, "/markers/"+markerID.String()+"/image?variant=thumbnail", nil)
 	req = addClaimsToContext(req, userID)
-
 	rr := httptest.NewRecorder()
 	r.ServeHTTP(rr, req)
 
-	if rr.Code != http.StatusBadRequest {
-		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
 	}
-
-	var response Response
-	json.Unmarshal(rr.Body.Bytes(), &response)
-
-	if response.Meta.Success {
-		t.Error("expected success=false")
+	if rr.Body.String() != "existing" {
+		t.Errorf("expected thumbnail content, got %q", rr.Body.String())
+	}
+	if rr.Header().Get("ETag") != `"thumb"` {
+		t.Errorf("expected ETag \"thumb\", got %s", rr.Header().Get("ETag"))
+	}
+	if !strings.HasPrefix(rr.Header().Get("Cache-Control"), "private") {
+		t.Errorf("expected private Cache-Control, got %s", rr.Header().Get("Cache-Control"))
 	}
 
-	if response.Meta.Message != "Invalid marker ID" {
-		t.Errorf("unexpected message: %s", response.Meta.Message)
+	// A matching ETag is answered without a body
+	req = httptest.NewRequest(http.MethodGet, "/markers/"+markerID.String()+"/image?variant=thumbnail", nil)
+	req.Header.Set("If-None-Match", `"thumb"`)
+	req = addClaimsToContext(req, userID)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("expected status %d, got %d", http.StatusNotModified, rr.Code)
 	}
 }
 
-func TestMarkerHandler_GetByShortCode_Public(t *testing.T) {
+func TestMarkerHandler_GetImage_NoImage(t *testing.T) {
 	cleanupMarkers(t)
 	cleanupUsers(t)
 
 	userID := createTestUserForMarker(t)
-	createTestMarker(t, userID)
+	markerID := createTestMarker(t, userID)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{})
 
 	r := chi.NewRouter()
-	r.Get("/markers/code/{shortCode}", handler.GetByShortCode)
-
-	// Request WITHOUT auth context - should still work since it's public
-	req := httptest.NewRequest(http.MethodGet, "/markers/code/TEST001", nil)
+	r.Get("/markers/{id}/image", handler.GetImage)
 
+	req := httptest.NewRequest(http.MethodGet, "/markers/"+markerID.String()+"/image", nil)
+	req = addClaimsToContext(req, userID)
 	rr := httptest.NewRecorder()
 	r.ServeHTTP(rr, req)
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d: %s", http.StatusNotFound, rr.Code, rr.Body.String())
 	}
+}
 
-	var response Response
-	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
-		t.Fatalf("failed to parse response: %v", err)
-	}
+func TestMarkerHandler_GetImage_InvalidVariant(t *testing.T) {
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{})
 
-	if !response.Meta.Success {
-		t.Error("expected success=true for public endpoint")
+	r := chi.NewRouter()
+	r.Get("/markers/{id}/image", handler.GetImage)
+
+	req := httptest.NewRequest(http.MethodGet, "/markers/"+uuid.New().String()+"/image?variant=huge", nil)
+	req = addClaimsToContext(req, uuid.New())
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
 	}
 }
 
@@ -1125,7 +2509,7 @@ func TestMarkerHandler_ListPaginated_DefaultParams(t *testing.T) {
 	userID := createTestUserForMarker(t)
 	createMultipleTestMarkers(t, userID, 15)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/markers/paginated", nil)
 	rr := httptest.NewRecorder()
@@ -1184,7 +2568,7 @@ func TestMarkerHandler_ListPaginated_CustomPagination(t *testing.T) {
 	userID := createTestUserForMarker(t)
 	createMultipleTestMarkers(t, userID, 25)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/markers/paginated?page=2&per_page=5", nil)
 	rr := httptest.NewRecorder()
@@ -1223,7 +2607,7 @@ func TestMarkerHandler_ListPaginated_Search(t *testing.T) {
 	userID := createTestUserForMarker(t)
 	createMultipleTestMarkers(t, userID, 10)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	// Search for "Bamboo A" - should match "Test Bamboo A"
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/markers/paginated?search=Bamboo%20A", nil)
@@ -1260,7 +2644,7 @@ func TestMarkerHandler_ListPaginated_SearchNoResults(t *testing.T) {
 	userID := createTestUserForMarker(t)
 	createMultipleTestMarkers(t, userID, 5)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/markers/paginated?search=nonexistent", nil)
 	rr := httptest.NewRecorder()
@@ -1295,7 +2679,7 @@ func TestMarkerHandler_ListPaginated_SortByName(t *testing.T) {
 	userID := createTestUserForMarker(t)
 	createMultipleTestMarkers(t, userID, 5)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	// Sort by name ascending
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/markers/paginated?sort_by=name&sort_dir=asc", nil)
@@ -1334,7 +2718,7 @@ func TestMarkerHandler_ListPaginated_FilterByCreatorID(t *testing.T) {
 		INSERT INTO users (email, password_hash, name, role)
 		VALUES ($1, $2, $3, $4)
 		RETURNING id
-	`, "markertest2@example.com", "$2a$12$test", "Marker Test User 2", "user").Scan(&userID2)
+	`, "markertest2@example.com", "$2a$12$test", "Marker Test User 2", "surveyor").Scan(&userID2)
 	if err != nil {
 		t.Fatalf("failed to create second test user: %v", err)
 	}
@@ -1354,7 +2738,7 @@ func TestMarkerHandler_ListPaginated_FilterByCreatorID(t *testing.T) {
 		}
 	}
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	// Filter by user 1's creator_id
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/markers/paginated?creator_id="+userID1.String(), nil)
@@ -1381,7 +2765,7 @@ func TestMarkerHandler_ListPaginated_InvalidSortField(t *testing.T) {
 	userID := createTestUserForMarker(t)
 	createTestMarker(t, userID)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	// Invalid sort field should fall back to created_at
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/markers/paginated?sort_by=invalid_field", nil)
@@ -1402,7 +2786,7 @@ func TestMarkerHandler_ListPaginated_PerPageMaxLimit(t *testing.T) {
 	userID := createTestUserForMarker(t)
 	createTestMarker(t, userID)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	// Request more than max (100) - should be capped
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/markers/paginated?per_page=500", nil)
@@ -1430,7 +2814,7 @@ func TestMarkerHandler_ListPaginated_FullMarkerData(t *testing.T) {
 	userID := createTestUserForMarker(t)
 	createTestMarker(t, userID)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/markers/paginated", nil)
 	rr := httptest.NewRecorder()
@@ -1472,7 +2856,7 @@ func TestMarkerHandler_ListPaginated_Empty(t *testing.T) {
 	cleanupMarkers(t)
 	cleanupUsers(t)
 
-	handler := NewMarkerHandler(testQueries, nil, "https://test.bamboomapper.com")
+	handler := NewMarkerHandler(testQueries, MarkerHandlerConfig{DeepLinkBaseURL: "https://test.bamboomapper.com"})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/markers/paginated", nil)
 	rr := httptest.NewRecorder()
@@ -1503,3 +2887,40 @@ func TestMarkerHandler_ListPaginated_Empty(t *testing.T) {
 		t.Errorf("expected 0 markers, got %d", len(data))
 	}
 }
+
+func TestApplyPhotoLocation(t *testing.T) {
+	photo := imaging.Metadata{
+		Orientation: 1,
+		GPS:         &imaging.Coordinates{Latitude: -7.42450896, Longitude: 110.01069970},
+	}
+
+	tests := []struct {
+		name        string
+		meta        imaging.Metadata
+		latitude    string
+		longitude   string
+		expectLat   string
+		expectLng   string
+		expectNotes bool
+	}{
+		{"fills missing coordinates", photo, "", "", "-7.42450896", "110.01069970", true},
+		{"keeps nearby coordinates", photo, "-7.42460000", "110.01070000", "-7.42460000", "110.01070000", false},
+		{"warns on distant coordinates", photo, "-7.50000000", "110.01070000", "-7.50000000", "110.01070000", true},
+		{"ignores photo without GPS", imaging.Metadata{Orientation: 1}, "", "", "", "", false},
+		{"does not fill partial coordinates", photo, "-7.42460000", "", "-7.42460000", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := model.CreateMarkerRequest{Name: "Test", Latitude: tt.latitude, Longitude: tt.longitude}
+			notes := applyPhotoLocation(&req, tt.meta)
+
+			if req.Latitude != tt.expectLat || req.Longitude != tt.expectLng {
+				t.Errorf("expected %s,%s, got %s,%s", tt.expectLat, tt.expectLng, req.Latitude, req.Longitude)
+			}
+			if (len(notes) > 0) != tt.expectNotes {
+				t.Errorf("expected notes=%v, got %v", tt.expectNotes, notes)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/auth"
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
 	"github.com/google/uuid"
 )
@@ -19,6 +20,14 @@ var allowedSortFields = map[string]bool{
 	"quantity":   true,
 }
 
+// Allowed sort fields for user listing
+var allowedUserSortFields = map[string]bool{
+	"name":       true,
+	"email":      true,
+	"role":       true,
+	"created_at": true,
+}
+
 // Allowed sort directions
 var allowedSortDirs = map[string]bool{
 	"asc":  true,
@@ -107,6 +116,74 @@ func ParseListMarkersParams(r *http.Request) (model.ListMarkersParams, error) {
 	return params, nil
 }
 
+// ParseListUsersParams parses query parameters for paginated user listing.
+// Pagination and sorting fall back to defaults like markers; unknown role and
+// status filters are reported, as they would otherwise silently match nothing.
+func ParseListUsersParams(r *http.Request) (model.ListUsersParams, map[string]string) {
+	params := model.DefaultListUsersParams()
+	details := make(map[string]string)
+
+	// Parse page
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		page, err := strconv.Atoi(pageStr)
+		if err != nil || page < 1 {
+			page = defaultPage
+		}
+		params.Page = page
+	}
+
+	// Parse per_page
+	if perPageStr := r.URL.Query().Get("per_page"); perPageStr != "" {
+		perPage, err := strconv.Atoi(perPageStr)
+		if err != nil || perPage < minPerPage {
+			perPage = defaultPerPage
+		}
+		if perPage > maxPerPage {
+			perPage = maxPerPage
+		}
+		params.PerPage = perPage
+	}
+
+	// Parse sort_by
+	if sortBy := r.URL.Query().Get("sort_by"); sortBy != "" {
+		sortBy = strings.ToLower(strings.TrimSpace(sortBy))
+		if allowedUserSortFields[sortBy] {
+			params.SortBy = sortBy
+		}
+	}
+
+	// Parse sort_dir
+	if sortDir := r.URL.Query().Get("sort_dir"); sortDir != "" {
+		sortDir = strings.ToLower(strings.TrimSpace(sortDir))
+		if allowedSortDirs[sortDir] {
+			params.SortDir = sortDir
+		}
+	}
+
+	// Parse search
+	if search := r.URL.Query().Get("search"); search != "" {
+		params.Search = strings.TrimSpace(search)
+	}
+
+	// Parse role
+	if role := r.URL.Query().Get("role"); role != "" {
+		if !auth.ValidRole(role) {
+			details["role"] = "role must be one of " + strings.Join(auth.Roles, ", ")
+		}
+		params.Role = role
+	}
+
+	// Parse status
+	if status := r.URL.Query().Get("status"); status != "" {
+		if status != model.UserStatusActive && status != model.UserStatusDisabled {
+			details["status"] = "status must be active or disabled"
+		}
+		params.Status = status
+	}
+
+	return params, details
+}
+
 // CalculateTotalPages calculates total pages from total items and per page
 func CalculateTotalPages(totalItems int64, perPage int) int {
 	if totalItems == 0 {
@@ -38,6 +38,19 @@ func respondSuccess(w http.ResponseWriter, status int, message string, data inte
 	})
 }
 
+// respondSuccessWithDetails sends a success response with informational details
+// (e.g. warnings about the submitted data). Nil details are omitted.
+func respondSuccessWithDetails(w http.ResponseWriter, status int, message string, data interface{}, details map[string]string) {
+	respondJSON(w, status, Response{
+		Meta: Meta{
+			Success: true,
+			Message: message,
+			Details: details,
+		},
+		Data: data,
+	})
+}
+
 // respondError sends an error response with data: null
 func respondError(w http.ResponseWriter, status int, message string, details map[string]string) {
 	respondJSON(w, status, Response{
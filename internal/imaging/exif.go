@@ -0,0 +1,326 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+// EXIF tag IDs used by this package
+const (
+	tagOrientation        = 0x0112
+	tagExifIFDPointer     = 0x8769
+	tagGPSIFDPointer      = 0x8825
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+	tagGPSTimeStamp    = 0x0007
+	tagGPSDateStamp    = 0x001D
+)
+
+// TIFF field types used by this package
+const (
+	typeASCII    = 2
+	typeShort    = 3
+	typeRational = 5
+)
+
+// exifTimeLayout is the timestamp format used by EXIF date tags
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+// errNoExif is returned when an image carries no readable EXIF block
+var errNoExif = errors.New("no exif data")
+
+// Metadata contains the EXIF fields we care about
+type Metadata struct {
+	// Orientation is the EXIF orientation (1-8), 1 when absent
+	Orientation int
+	// GPS is the position the photo was taken at, nil when absent
+	GPS *Coordinates
+	// CapturedAt is when the photo was taken, nil when absent
+	CapturedAt *time.Time
+}
+
+// Coordinates is a WGS84 position in decimal degrees
+type Coordinates struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// ReadMetadata extracts EXIF metadata from a JPEG image.
+// Images without EXIF (or non-JPEG images) return default metadata.
+func ReadMetadata(data []byte) Metadata {
+	meta := Metadata{Orientation: 1}
+
+	tiff, err := findExifBlock(data)
+	if err != nil {
+		return meta
+	}
+
+	r, err := newIFDReader(tiff)
+	if err != nil {
+		return meta
+	}
+
+	ifd0, err := r.readIFD(r.firstIFDOffset())
+	if err != nil {
+		return meta
+	}
+
+	if e, ok := ifd0[tagOrientation]; ok {
+		if o := int(r.uint16Value(e)); o >= 1 && o <= 8 {
+			meta.Orientation = o
+		}
+	}
+
+	var gpsIFD map[uint16]ifdEntry
+	if e, ok := ifd0[tagGPSIFDPointer]; ok {
+		if gpsIFD, err = r.readIFD(r.uint32Value(e)); err == nil {
+			meta.GPS = r.gpsCoordinates(gpsIFD)
+		}
+	}
+
+	if e, ok := ifd0[tagExifIFDPointer]; ok {
+		if exifIFD, err := r.readIFD(r.uint32Value(e)); err == nil {
+			meta.CapturedAt = r.captureTime(exifIFD, gpsIFD)
+		}
+	}
+
+	return meta
+}
+
+// gpsCoordinates decodes latitude/longitude from the GPS IFD
+func (r *ifdReader) gpsCoordinates(gps map[uint16]ifdEntry) *Coordinates {
+	lat, ok := r.degrees(gps[tagGPSLatitude])
+	if !ok {
+		return nil
+	}
+	lng, ok := r.degrees(gps[tagGPSLongitude])
+	if !ok {
+		return nil
+	}
+
+	if strings.HasPrefix(r.asciiValue(gps[tagGPSLatitudeRef]), "S") {
+		lat = -lat
+	}
+	if strings.HasPrefix(r.asciiValue(gps[tagGPSLongitudeRef]), "W") {
+		lng = -lng
+	}
+
+	// Reject out-of-range values and the 0,0 placeholder some cameras write without a fix
+	if math.Abs(lat) > 90 || math.Abs(lng) > 180 || (lat == 0 && lng == 0) {
+		return nil
+	}
+
+	return &Coordinates{Latitude: lat, Longitude: lng}
+}
+
+// captureTime resolves when the photo was taken. DateTimeOriginal is local camera time,
+// so its offset tag is used when present, then the GPS UTC timestamp, then UTC as a fallback.
+func (r *ifdReader) captureTime(exif, gps map[uint16]ifdEntry) *time.Time {
+	original := r.asciiValue(exif[tagDateTimeOriginal])
+
+	if original != "" {
+		if offset := r.asciiValue(exif[tagOffsetTimeOriginal]); offset != "" {
+			if t, err := time.Parse(exifTimeLayout+"-07:00", original+offset); err == nil {
+				return &t
+			}
+		}
+	}
+
+	if gps != nil {
+		date := r.asciiValue(gps[tagGPSDateStamp])
+		hms, ok := r.rationals(gps[tagGPSTimeStamp], 3)
+		if date != "" && ok {
+			if d, err := time.Parse("2006:01:02", date); err == nil {
+				t := d.Add(time.Duration(hms[0]*float64(time.Hour) + hms[1]*float64(time.Minute) + hms[2]*float64(time.Second)))
+				return &t
+			}
+		}
+	}
+
+	if original != "" {
+		if t, err := time.Parse(exifTimeLayout, original); err == nil {
+			return &t
+		}
+	}
+
+	return nil
+}
+
+// degrees converts a degrees/minutes/seconds rational triple to decimal degrees
+func (r *ifdReader) degrees(e ifdEntry) (float64, bool) {
+	dms, ok := r.rationals(e, 3)
+	if !ok {
+		return 0, false
+	}
+	return dms[0] + dms[1]/60 + dms[2]/3600, true
+}
+
+// findExifBlock walks the JPEG segments and returns the TIFF payload of the APP1 Exif segment
+func findExifBlock(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errNoExif
+	}
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil, errNoExif
+		}
+		marker := data[pos+1]
+
+		// Start of scan or end of image: no more metadata segments
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if length < 2 || pos+2+length > len(data) {
+			return nil, errNoExif
+		}
+		segment := data[pos+4 : pos+2+length]
+
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:], nil
+		}
+
+		pos += 2 + length
+	}
+
+	return nil, errNoExif
+}
+
+// ifdEntry is a raw TIFF directory entry
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte // the 4-byte value/offset field
+}
+
+// ifdReader decodes TIFF image file directories
+type ifdReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func newIFDReader(tiff []byte) (*ifdReader, error) {
+	if len(tiff) < 8 {
+		return nil, errNoExif
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errNoExif
+	}
+
+	if order.Uint16(tiff[2:4]) != 42 {
+		return nil, errNoExif
+	}
+
+	return &ifdReader{data: tiff, order: order}, nil
+}
+
+func (r *ifdReader) firstIFDOffset() uint32 {
+	return r.order.Uint32(r.data[4:8])
+}
+
+// readIFD reads all entries of the directory at the given offset
+func (r *ifdReader) readIFD(offset uint32) (map[uint16]ifdEntry, error) {
+	start := int(offset)
+	if start < 8 || start+2 > len(r.data) {
+		return nil, errNoExif
+	}
+
+	count := int(r.order.Uint16(r.data[start : start+2]))
+	entries := make(map[uint16]ifdEntry, count)
+
+	for i := 0; i < count; i++ {
+		pos := start + 2 + i*12
+		if pos+12 > len(r.data) {
+			return nil, errNoExif
+		}
+		tag := r.order.Uint16(r.data[pos : pos+2])
+		entries[tag] = ifdEntry{
+			typ:   r.order.Uint16(r.data[pos+2 : pos+4]),
+			count: r.order.Uint32(r.data[pos+4 : pos+8]),
+			value: r.data[pos+8 : pos+12],
+		}
+	}
+
+	return entries, nil
+}
+
+// uint16Value returns the first SHORT value of an entry
+func (r *ifdReader) uint16Value(e ifdEntry) uint16 {
+	return r.order.Uint16(e.value[:2])
+}
+
+// uint32Value returns the first LONG value of an entry (or a SHORT widened to 32 bits)
+func (r *ifdReader) uint32Value(e ifdEntry) uint32 {
+	if e.typ == typeShort {
+		return uint32(r.uint16Value(e))
+	}
+	return r.order.Uint32(e.value)
+}
+
+// payload returns the raw bytes of an entry, following the offset when they don't fit inline
+func (r *ifdReader) payload(e ifdEntry, size int) ([]byte, bool) {
+	if e.value == nil || size < 0 {
+		return nil, false
+	}
+	if size <= 4 {
+		return e.value[:size], true
+	}
+	offset := int(r.order.Uint32(e.value))
+	if offset < 0 || offset+size > len(r.data) {
+		return nil, false
+	}
+	return r.data[offset : offset+size], true
+}
+
+// asciiValue returns an ASCII entry without its NUL terminator, "" when absent
+func (r *ifdReader) asciiValue(e ifdEntry) string {
+	if e.typ != typeASCII {
+		return ""
+	}
+	raw, ok := r.payload(e, int(e.count))
+	if !ok {
+		return ""
+	}
+	return strings.TrimRight(string(raw), "\x00 ")
+}
+
+// rationals returns the first n RATIONAL values of an entry as floats
+func (r *ifdReader) rationals(e ifdEntry, n int) ([]float64, bool) {
+	if e.typ != typeRational || int(e.count) < n {
+		return nil, false
+	}
+	raw, ok := r.payload(e, n*8)
+	if !ok {
+		return nil, false
+	}
+
+	values := make([]float64, n)
+	for i := range values {
+		num := r.order.Uint32(raw[i*8 : i*8+4])
+		den := r.order.Uint32(raw[i*8+4 : i*8+8])
+		if den == 0 {
+			return nil, false
+		}
+		values[i] = float64(num) / float64(den)
+	}
+	return values, true
+}
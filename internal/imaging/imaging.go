@@ -0,0 +1,204 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+
+	// Register decoders for the accepted upload formats
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ErrUnsupportedFormat is returned when the uploaded file is not an accepted image type
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+// ErrTooManyPixels is returned when the image header declares more than MaxPixels
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+// MaxPixels caps the width times height of an upload. Decoding allocates memory for
+// every pixel, so a small file with a crafted header could otherwise exhaust it.
+const MaxPixels = 50_000_000
+
+// Variant identifies a resized version of an uploaded image
+type Variant string
+
+const (
+	VariantThumbnail Variant = "thumbnail"
+	VariantMedium    Variant = "medium"
+	VariantOriginal  Variant = "original"
+)
+
+// ContentType is the MIME type of every processed variant
+const ContentType = "image/jpeg"
+
+// variantSpec describes how a variant is produced
+type variantSpec struct {
+	variant Variant
+	maxSide int // 0 keeps the original dimensions
+	quality int
+}
+
+// variantSpecs lists the variants generated for each upload, smallest first
+var variantSpecs = []variantSpec{
+	{variant: VariantThumbnail, maxSide: 320, quality: 80},
+	{variant: VariantMedium, maxSide: 1280, quality: 85},
+	{variant: VariantOriginal, maxSide: 0, quality: 90},
+}
+
+// allowedContentTypes are the sniffed MIME types accepted for upload
+var allowedContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// ProcessedImage is a single encoded variant of an uploaded image
+type ProcessedImage struct {
+	Variant Variant
+	Data    []byte
+	Width   int
+	Height  int
+}
+
+// DetectContentType sniffs the real MIME type of the data and rejects non-images
+func DetectContentType(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if !allowedContentTypes[contentType] {
+		return contentType, ErrUnsupportedFormat
+	}
+	return contentType, nil
+}
+
+// Validate checks that the data is an accepted image type with a readable header
+// within MaxPixels, without decoding the pixels
+func Validate(data []byte) (image.Config, error) {
+	if _, err := DetectContentType(data); err != nil {
+		return image.Config{}, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return image.Config{}, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	if int64(config.Width)*int64(config.Height) > MaxPixels {
+		return config, fmt.Errorf("%w: %dx%d", ErrTooManyPixels, config.Width, config.Height)
+	}
+	return config, nil
+}
+
+// Process decodes an uploaded image, normalizes its EXIF orientation and
+// re-encodes it as JPEG in every variant size. Re-encoding strips all metadata.
+func Process(data []byte) ([]ProcessedImage, error) {
+	// Check the declared size before Decode allocates the pixels
+	if _, err := Validate(data); err != nil {
+		return nil, err
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+
+	meta := ReadMetadata(data)
+	oriented := applyOrientation(flatten(src), meta.Orientation)
+
+	results := make([]ProcessedImage, 0, len(variantSpecs))
+	for _, spec := range variantSpecs {
+		img := resizeToFit(oriented, spec.maxSide)
+
+		buf := &bytes.Buffer{}
+		if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: spec.quality}); err != nil {
+			return nil, fmt.Errorf("failed to encode %s variant: %w", spec.variant, err)
+		}
+
+		bounds := img.Bounds()
+		results = append(results, ProcessedImage{
+			Variant: spec.variant,
+			Data:    buf.Bytes(),
+			Width:   bounds.Dx(),
+			Height:  bounds.Dy(),
+		})
+	}
+
+	return results, nil
+}
+
+// flatten converts any image to NRGBA over a white background (JPEG has no alpha)
+func flatten(src image.Image) *image.NRGBA {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Over)
+	return dst
+}
+
+// applyOrientation rotates/flips the image so it displays upright with orientation 1
+func applyOrientation(src *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for dy := 0; dy < dh; dy++ {
+		for dx := 0; dx < dw; dx++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirror horizontal
+				sx, sy = w-1-dx, dy
+			case 3: // rotate 180
+				sx, sy = w-1-dx, h-1-dy
+			case 4: // mirror vertical
+				sx, sy = dx, h-1-dy
+			case 5: // transpose
+				sx, sy = dy, dx
+			case 6: // rotate 90 clockwise
+				sx, sy = dy, h-1-dx
+			case 7: // transverse
+				sx, sy = w-1-dy, h-1-dx
+			case 8: // rotate 90 counter-clockwise
+				sx, sy = w-1-dy, dx
+			}
+			si := src.PixOffset(sx, sy)
+			di := dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+
+	return dst
+}
+
+// resizeToFit scales the image down so its longest side is at most maxSide.
+// Images that already fit (or maxSide 0) are returned unchanged.
+func resizeToFit(src *image.NRGBA, maxSide int) image.Image {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if maxSide <= 0 || (w <= maxSide && h <= maxSide) {
+		return src
+	}
+
+	var dw, dh int
+	if w >= h {
+		dw = maxSide
+		dh = max(1, h*maxSide/w)
+	} else {
+		dh = maxSide
+		dw = max(1, w*maxSide/h)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+	return dst
+}
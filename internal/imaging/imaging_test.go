@@ -0,0 +1,423 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+	"time"
+)
+
+// newTestImage creates a solid image of the given size
+func newTestImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: 120, G: 160, B: 60, A: 255})
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, img, nil); err != nil {
+		t.Fatalf("failed to encode jpeg: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// exifEntry is a TIFF directory entry used to build test EXIF blocks
+type exifEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func shortEntry(tag, value uint16) exifEntry {
+	data := make([]byte, 2)
+	binary.LittleEndian.PutUint16(data, value)
+	return exifEntry{tag: tag, typ: typeShort, count: 1, data: data}
+}
+
+func asciiEntry(tag uint16, value string) exifEntry {
+	return exifEntry{tag: tag, typ: typeASCII, count: uint32(len(value) + 1), data: append([]byte(value), 0)}
+}
+
+func rationalEntry(tag uint16, values ...[2]uint32) exifEntry {
+	data := make([]byte, 0, len(values)*8)
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint32(data, v[0])
+		data = binary.LittleEndian.AppendUint32(data, v[1])
+	}
+	return exifEntry{tag: tag, typ: typeRational, count: uint32(len(values)), data: data}
+}
+
+// buildTIFF lays out IFD0 followed by the optional Exif and GPS sub-directories
+func buildTIFF(ifd0, exifIFD, gpsIFD []exifEntry) []byte {
+	ifdSize := func(entries []exifEntry) int { return 2 + 12*len(entries) + 4 }
+
+	// Reserve pointer entries in IFD0 for the sub-directories
+	if len(exifIFD) > 0 {
+		ifd0 = append(ifd0, exifEntry{tag: tagExifIFDPointer, typ: 4, count: 1})
+	}
+	if len(gpsIFD) > 0 {
+		ifd0 = append(ifd0, exifEntry{tag: tagGPSIFDPointer, typ: 4, count: 1})
+	}
+
+	ifd0Offset := 8
+	exifOffset := ifd0Offset + ifdSize(ifd0)
+	gpsOffset := exifOffset
+	if len(exifIFD) > 0 {
+		gpsOffset += ifdSize(exifIFD)
+	}
+	dataOffset := gpsOffset
+	if len(gpsIFD) > 0 {
+		dataOffset += ifdSize(gpsIFD)
+	}
+
+	for i := range ifd0 {
+		switch ifd0[i].tag {
+		case tagExifIFDPointer:
+			ifd0[i].data = binary.LittleEndian.AppendUint32(nil, uint32(exifOffset))
+		case tagGPSIFDPointer:
+			ifd0[i].data = binary.LittleEndian.AppendUint32(nil, uint32(gpsOffset))
+		}
+	}
+
+	out := []byte("II")
+	out = binary.LittleEndian.AppendUint16(out, 42)
+	out = binary.LittleEndian.AppendUint32(out, uint32(ifd0Offset))
+
+	var extra []byte
+	writeIFD := func(entries []exifEntry) {
+		out = binary.LittleEndian.AppendUint16(out, uint16(len(entries)))
+		for _, e := range entries {
+			out = binary.LittleEndian.AppendUint16(out, e.tag)
+			out = binary.LittleEndian.AppendUint16(out, e.typ)
+			out = binary.LittleEndian.AppendUint32(out, e.count)
+			if len(e.data) <= 4 {
+				value := make([]byte, 4)
+				copy(value, e.data)
+				out = append(out, value...)
+			} else {
+				out = binary.LittleEndian.AppendUint32(out, uint32(dataOffset+len(extra)))
+				extra = append(extra, e.data...)
+			}
+		}
+		out = binary.LittleEndian.AppendUint32(out, 0) // next IFD
+	}
+
+	writeIFD(ifd0)
+	if len(exifIFD) > 0 {
+		writeIFD(exifIFD)
+	}
+	if len(gpsIFD) > 0 {
+		writeIFD(gpsIFD)
+	}
+
+	return append(out, extra...)
+}
+
+// withExif inserts an APP1 Exif segment carrying the TIFF block right after SOI
+func withExif(jpg, tiff []byte) []byte {
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, segment...)
+	return append(out, jpg[2:]...)
+}
+
+// withOrientation inserts an EXIF block carrying only the orientation tag
+func withOrientation(jpg []byte, orientation uint16) []byte {
+	return withExif(jpg, buildTIFF([]exifEntry{shortEntry(tagOrientation, orientation)}, nil, nil))
+}
+
+func TestDetectContentType(t *testing.T) {
+	img := newTestImage(4, 4)
+
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		wantErr     bool
+	}{
+		{"jpeg", encodeJPEG(t, img), "image/jpeg", false},
+		{"png", encodePNG(t, img), "image/png", false},
+		{"plain text", []byte("definitely not an image"), "text/plain; charset=utf-8", true},
+		{"pdf", []byte("%PDF-1.4\n"), "application/pdf", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, err := DetectContentType(tt.data)
+			if contentType != tt.contentType {
+				t.Errorf("expected content type %q, got %q", tt.contentType, contentType)
+			}
+			if tt.wantErr && !errors.Is(err, ErrUnsupportedFormat) {
+				t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestProcess_Variants(t *testing.T) {
+	data := encodePNG(t, newTestImage(2000, 1000))
+
+	variants, err := Process(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[Variant][2]int{
+		VariantThumbnail: {320, 160},
+		VariantMedium:    {1280, 640},
+		VariantOriginal:  {2000, 1000},
+	}
+
+	if len(variants) != len(expected) {
+		t.Fatalf("expected %d variants, got %d", len(expected), len(variants))
+	}
+
+	for _, v := range variants {
+		size, ok := expected[v.Variant]
+		if !ok {
+			t.Errorf("unexpected variant %q", v.Variant)
+			continue
+		}
+		if v.Width != size[0] || v.Height != size[1] {
+			t.Errorf("variant %s: expected %dx%d, got %dx%d", v.Variant, size[0], size[1], v.Width, v.Height)
+		}
+		if contentType, _ := DetectContentType(v.Data); contentType != ContentType {
+			t.Errorf("variant %s: expected %s output, got %s", v.Variant, ContentType, contentType)
+		}
+	}
+}
+
+func TestProcess_SmallImageNotUpscaled(t *testing.T) {
+	variants, err := Process(encodeJPEG(t, newTestImage(100, 50)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, v := range variants {
+		if v.Width != 100 || v.Height != 50 {
+			t.Errorf("variant %s: expected 100x50, got %dx%d", v.Variant, v.Width, v.Height)
+		}
+	}
+}
+
+func TestProcess_RejectsNonImage(t *testing.T) {
+	_, err := Process([]byte("<html><body>hello</body></html>"))
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	data := encodeJPEG(t, newTestImage(40, 30))
+	config, err := Validate(data)
+	if err != nil {
+		t.Errorf("expected valid jpeg, got %v", err)
+	}
+	if config.Width != 40 || config.Height != 30 {
+		t.Errorf("expected 40x30 config, got %dx%d", config.Width, config.Height)
+	}
+
+	// Correct magic bytes but a truncated header
+	if _, err := Validate(data[:4]); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat for truncated jpeg, got %v", err)
+	}
+	if _, err := Validate([]byte("plain text")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat for text, got %v", err)
+	}
+}
+
+// withDimensions rewrites the frame header of a baseline JPEG to declare another size
+func withDimensions(t *testing.T, jpg []byte, w, h uint16) []byte {
+	t.Helper()
+	sof := bytes.Index(jpg, []byte{0xFF, 0xC0})
+	if sof < 0 {
+		t.Fatal("no SOF0 marker in jpeg")
+	}
+	out := bytes.Clone(jpg)
+	// Marker, segment length and sample precision come before the dimensions
+	binary.BigEndian.PutUint16(out[sof+5:], h)
+	binary.BigEndian.PutUint16(out[sof+7:], w)
+	return out
+}
+
+func TestValidate_RejectsTooManyPixels(t *testing.T) {
+	// A few hundred bytes that claim to be 65535x65535, about 4.3 gigapixels
+	data := withDimensions(t, encodeJPEG(t, newTestImage(8, 8)), 65535, 65535)
+
+	if _, err := Validate(data); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("expected ErrTooManyPixels from Validate, got %v", err)
+	}
+	if _, err := Process(data); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("expected ErrTooManyPixels from Process, got %v", err)
+	}
+}
+
+func TestProcess_NormalizesOrientation(t *testing.T) {
+	data := withOrientation(encodeJPEG(t, newTestImage(400, 200)), 6)
+
+	if meta := ReadMetadata(data); meta.Orientation != 6 {
+		t.Fatalf("expected orientation 6, got %d", meta.Orientation)
+	}
+
+	variants, err := Process(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, v := range variants {
+		if v.Variant == VariantOriginal && (v.Width != 200 || v.Height != 400) {
+			t.Errorf("expected rotated original to be 200x400, got %dx%d", v.Width, v.Height)
+		}
+		// Output must not carry the orientation tag anymore
+		if meta := ReadMetadata(v.Data); meta.Orientation != 1 {
+			t.Errorf("variant %s: expected orientation to be stripped, got %d", v.Variant, meta.Orientation)
+		}
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// 2x1 image: left pixel red, right pixel blue
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	src.Set(0, 0, red)
+	src.Set(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		width       int
+		height      int
+		first       color.NRGBA // pixel at (0,0)
+	}{
+		{1, 2, 1, red},
+		{2, 2, 1, blue},
+		{3, 2, 1, blue},
+		{6, 1, 2, red},
+		{8, 1, 2, blue},
+	}
+
+	for _, tt := range tests {
+		dst := applyOrientation(src, tt.orientation)
+		if dst.Bounds().Dx() != tt.width || dst.Bounds().Dy() != tt.height {
+			t.Errorf("orientation %d: expected %dx%d, got %dx%d", tt.orientation, tt.width, tt.height, dst.Bounds().Dx(), dst.Bounds().Dy())
+		}
+		if got := dst.NRGBAAt(0, 0); got != tt.first {
+			t.Errorf("orientation %d: expected first pixel %v, got %v", tt.orientation, tt.first, got)
+		}
+	}
+}
+
+func TestReadMetadata_GPSAndCaptureTime(t *testing.T) {
+	jpg := encodeJPEG(t, newTestImage(8, 8))
+
+	gps := []exifEntry{
+		asciiEntry(tagGPSLatitudeRef, "S"),
+		rationalEntry(tagGPSLatitude, [2]uint32{7, 1}, [2]uint32{30, 1}, [2]uint32{3600, 100}),
+		asciiEntry(tagGPSLongitudeRef, "E"),
+		rationalEntry(tagGPSLongitude, [2]uint32{110, 1}, [2]uint32{15, 1}, [2]uint32{0, 1}),
+		rationalEntry(tagGPSTimeStamp, [2]uint32{3, 1}, [2]uint32{4, 1}, [2]uint32{5, 1}),
+		asciiEntry(tagGPSDateStamp, "2025:07:01"),
+	}
+
+	tests := []struct {
+		name     string
+		exif     []exifEntry
+		gps      []exifEntry
+		captured time.Time
+	}{
+		{
+			name: "offset time preferred",
+			exif: []exifEntry{
+				asciiEntry(tagDateTimeOriginal, "2025:07:01 10:04:05"),
+				asciiEntry(tagOffsetTimeOriginal, "+07:00"),
+			},
+			gps:      gps,
+			captured: time.Date(2025, 7, 1, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			name:     "gps timestamp without offset",
+			exif:     []exifEntry{asciiEntry(tagDateTimeOriginal, "2025:07:01 10:04:05")},
+			gps:      gps,
+			captured: time.Date(2025, 7, 1, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			name:     "local time as utc fallback",
+			exif:     []exifEntry{asciiEntry(tagDateTimeOriginal, "2025:07:01 10:04:05")},
+			captured: time.Date(2025, 7, 1, 10, 4, 5, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := ReadMetadata(withExif(jpg, buildTIFF(nil, tt.exif, tt.gps)))
+
+			if meta.CapturedAt == nil || !meta.CapturedAt.Equal(tt.captured) {
+				t.Errorf("expected captured at %v, got %v", tt.captured, meta.CapturedAt)
+			}
+
+			if tt.gps == nil {
+				if meta.GPS != nil {
+					t.Errorf("expected no GPS, got %+v", meta.GPS)
+				}
+				return
+			}
+			if meta.GPS == nil {
+				t.Fatal("expected GPS coordinates")
+			}
+			if math.Abs(meta.GPS.Latitude-(-7.51)) > 1e-9 {
+				t.Errorf("expected latitude -7.51, got %v", meta.GPS.Latitude)
+			}
+			if math.Abs(meta.GPS.Longitude-110.25) > 1e-9 {
+				t.Errorf("expected longitude 110.25, got %v", meta.GPS.Longitude)
+			}
+		})
+	}
+}
+
+func TestReadMetadata_IgnoresNullIsland(t *testing.T) {
+	gps := []exifEntry{
+		asciiEntry(tagGPSLatitudeRef, "N"),
+		rationalEntry(tagGPSLatitude, [2]uint32{0, 1}, [2]uint32{0, 1}, [2]uint32{0, 1}),
+		asciiEntry(tagGPSLongitudeRef, "E"),
+		rationalEntry(tagGPSLongitude, [2]uint32{0, 1}, [2]uint32{0, 1}, [2]uint32{0, 1}),
+	}
+
+	meta := ReadMetadata(withExif(encodeJPEG(t, newTestImage(8, 8)), buildTIFF(nil, nil, gps)))
+	if meta.GPS != nil {
+		t.Errorf("expected 0,0 to be ignored, got %+v", meta.GPS)
+	}
+}
+
+func TestReadMetadata_NoExif(t *testing.T) {
+	meta := ReadMetadata(encodePNG(t, newTestImage(8, 8)))
+	if meta.Orientation != 1 || meta.GPS != nil || meta.CapturedAt != nil {
+		t.Errorf("expected default metadata, got %+v", meta)
+	}
+}
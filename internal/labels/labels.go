@@ -0,0 +1,161 @@
+// Package labels lays out marker QR codes on printable sticker sheets
+package labels
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/branding"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/pdf"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/qr"
+)
+
+const mmToPt = 72 / 25.4
+
+// Template describes a sticker sheet; all sizes are in points
+type Template struct {
+	Name        string
+	PageWidth   float64
+	PageHeight  float64
+	Columns     int
+	Rows        int
+	LabelWidth  float64
+	LabelHeight float64
+	MarginLeft  float64
+	MarginTop   float64
+	GapX        float64
+	GapY        float64
+}
+
+// PerPage returns the number of labels on one sheet
+func (t Template) PerPage() int {
+	return t.Columns * t.Rows
+}
+
+// DefaultTemplate is used when a request doesn't name a template
+const DefaultTemplate = "a4-3x8"
+
+// Templates are the supported sticker sheets
+var Templates = map[string]Template{
+	// 24 labels of 70 x 37 mm (Avery 3474 and compatibles)
+	"a4-3x8": {
+		Name:        "a4-3x8",
+		PageWidth:   pdf.A4Width,
+		PageHeight:  pdf.A4Height,
+		Columns:     3,
+		Rows:        8,
+		LabelWidth:  70 * mmToPt,
+		LabelHeight: 37 * mmToPt,
+		MarginLeft:  0,
+		MarginTop:   0.5 * mmToPt,
+	},
+	// 30 labels of 2.625 x 1 in (Avery 5160 and compatibles)
+	"letter-3x10": {
+		Name:        "letter-3x10",
+		PageWidth:   pdf.LetterWidth,
+		PageHeight:  pdf.LetterHeight,
+		Columns:     3,
+		Rows:        10,
+		LabelWidth:  2.625 * 72,
+		LabelHeight: 72,
+		MarginLeft:  0.1875 * 72,
+		MarginTop:   0.5 * 72,
+		GapX:        0.125 * 72,
+	},
+}
+
+// Label is the content of a single sticker
+type Label struct {
+	// Link is encoded in the QR code
+	Link      string
+	Name      string
+	ShortCode string
+	Strain    string
+	OwnerName string
+}
+
+// Label layout in points
+const (
+	padding    = 6.0
+	qrMargin   = 2 // quiet zone in modules; the label padding adds to it
+	nameSize   = 9.0
+	codeSize   = 8.0
+	detailSize = 7.0
+	lineHeight = 1.3
+)
+
+// Render writes a PDF with one label per entry, filling sheets left to right, top to bottom.
+// The branding's logo and foreground colour are applied to every QR code; nil draws plain codes.
+func Render(w io.Writer, tmpl Template, labels []Label, b *branding.Branding) error {
+	doc := pdf.New()
+
+	var embedded *pdf.Image
+	var fg color.Color = color.Black
+	level := qr.ECLevelMedium
+	if b != nil {
+		fg = b.Foreground
+		if b.Logo != nil {
+			// Embedded once, drawn on every label
+			embedded = doc.AddImage(b.Logo)
+			level = qr.ECLevelQuartile
+		}
+	}
+
+	var page *pdf.Page
+	for i, label := range labels {
+		slot := i % tmpl.PerPage()
+		if slot == 0 {
+			page = doc.AddPage(tmpl.PageWidth, tmpl.PageHeight)
+		}
+
+		col, row := slot%tmpl.Columns, slot/tmpl.Columns
+		x := tmpl.MarginLeft + float64(col)*(tmpl.LabelWidth+tmpl.GapX)
+		y := tmpl.MarginTop + float64(row)*(tmpl.LabelHeight+tmpl.GapY)
+
+		if err := drawLabel(page, x, y, tmpl, label, level, embedded, fg); err != nil {
+			return fmt.Errorf("failed to draw label for %s: %w", label.ShortCode, err)
+		}
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// drawLabel draws the QR code on the left of a label and the text beside it
+func drawLabel(page *pdf.Page, x, y float64, tmpl Template, label Label, level qr.ECLevel, logo *pdf.Image, fg color.Color) error {
+	code, err := qr.Encode(label.Link, level)
+	if err != nil {
+		return err
+	}
+
+	side := tmpl.LabelHeight - 2*padding
+	code.DrawPDF(page, x+padding, y+padding, side, qrMargin, logo, fg)
+
+	textX := x + 2*padding + side
+	maxWidth := x + tmpl.LabelWidth - padding - textX
+
+	lines := []struct {
+		font pdf.Font
+		size float64
+		text string
+	}{
+		{pdf.HelveticaBold, nameSize, label.Name},
+		{pdf.HelveticaBold, codeSize, label.ShortCode},
+		{pdf.Helvetica, detailSize, label.Strain},
+		{pdf.Helvetica, detailSize, label.OwnerName},
+	}
+
+	baseline := y + padding
+	for _, line := range lines {
+		if line.text == "" {
+			continue
+		}
+		baseline += line.size * lineHeight
+		if baseline > y+tmpl.LabelHeight-padding {
+			break
+		}
+		page.Text(textX, baseline, line.font, line.size, color.Black, pdf.Truncate(line.font, line.size, line.text, maxWidth))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package labels
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/branding"
+)
+
+func TestTemplates_FitPage(t *testing.T) {
+	for name, tmpl := range Templates {
+		width := tmpl.MarginLeft + float64(tmpl.Columns)*tmpl.LabelWidth + float64(tmpl.Columns-1)*tmpl.GapX
+		height := tmpl.MarginTop + float64(tmpl.Rows)*tmpl.LabelHeight + float64(tmpl.Rows-1)*tmpl.GapY
+		if width > tmpl.PageWidth || height > tmpl.PageHeight {
+			t.Errorf("%s: labels (%.1f x %.1f) exceed the page (%.1f x %.1f)", name, width, height, tmpl.PageWidth, tmpl.PageHeight)
+		}
+		if tmpl.Name != name {
+			t.Errorf("%s: template named %q", name, tmpl.Name)
+		}
+	}
+	if _, ok := Templates[DefaultTemplate]; !ok {
+		t.Errorf("default template %q is not defined", DefaultTemplate)
+	}
+}
+
+func TestRender_PagesAndText(t *testing.T) {
+	tmpl := Templates["a4-3x8"]
+	labels := make([]Label, tmpl.PerPage()+1)
+	for i := range labels {
+		labels[i] = Label{
+			Link:      fmt.Sprintf("https://bamboomapper.com/marker/CODE%02d", i),
+			Name:      "Bambu Petung",
+			ShortCode: fmt.Sprintf("CODE%02d", i),
+			Strain:    "Dendrocalamus asper",
+			OwnerName: "Pak Slamet",
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	b := &branding.Branding{Logo: image.NewRGBA(image.Rect(0, 0, 10, 10)), Foreground: color.Black}
+	if err := Render(buf, tmpl, labels, b); err != nil {
+		t.Fatalf("failed to render labels: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "/Count 2") {
+		t.Error("expected a second page for the 25th label")
+	}
+	// The logo is embedded once for the whole document
+	if n := strings.Count(out, "/ColorSpace /DeviceRGB"); n != 1 {
+		t.Errorf("expected the logo to be embedded once, got %d images", n)
+	}
+}
+
+func TestRender_Empty(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := Render(buf, Templates["letter-3x10"], nil, nil); err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	if !strings.Contains(buf.String(), "/Count 0") {
+		t.Error("expected an empty document")
+	}
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"log"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is a backend that delivers emails
+type Mailer interface {
+	// Send delivers a message, returning once the backend has accepted it
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes messages to the log instead of delivering them,
+// for development without an SMTP server
+type LogMailer struct{}
+
+// Send logs the message
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	log.Printf("Email to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+var (
+	_ Mailer = LogMailer{}
+	_ Mailer = (*SMTPMailer)(nil)
+)
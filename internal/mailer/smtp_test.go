@@ -0,0 +1,157 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSink is a minimal SMTP server that records the envelope and data of one message
+type smtpSink struct {
+	listener net.Listener
+	from     string
+	to       []string
+	data     string
+	done     chan struct{}
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &smtpSink{listener: l, done: make(chan struct{})}
+	t.Cleanup(func() { l.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *smtpSink) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpSink) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 sink ready")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			tp.PrintfLine("250 sink")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			tp.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.to = append(s.to, strings.Trim(line[len("RCPT TO:"):], "<> "))
+			tp.PrintfLine("250 OK")
+		case cmd == "DATA":
+			tp.PrintfLine("354 Go ahead")
+			data, err := tp.ReadDotLines()
+			if err != nil {
+				return
+			}
+			s.data = strings.Join(data, "\n")
+			tp.PrintfLine("250 Queued")
+		case cmd == "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	sink := newSMTPSink(t)
+	m := NewSMTPMailer(SMTPConfig{
+		Host: "127.0.0.1",
+		Port: sink.port(),
+		From: "Bamboo Mapper <noreply@example.com>",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := m.Send(ctx, Message{
+		To:      "user@example.com",
+		Subject: "Reset your password",
+		Body:    "Hello,\nopen this link.",
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	<-sink.done
+
+	if sink.from != "noreply@example.com" {
+		t.Errorf("expected envelope sender noreply@example.com, got %q", sink.from)
+	}
+	if len(sink.to) != 1 || sink.to[0] != "user@example.com" {
+		t.Errorf("expected recipient user@example.com, got %v", sink.to)
+	}
+
+	msg, err := textproto.NewReader(bufio.NewReader(strings.NewReader(sink.data + "\n"))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("failed to parse headers: %v", err)
+	}
+	if msg.Get("Subject") != "Reset your password" {
+		t.Errorf("unexpected subject: %q", msg.Get("Subject"))
+	}
+	if msg.Get("From") != "Bamboo Mapper <noreply@example.com>" {
+		t.Errorf("unexpected From header: %q", msg.Get("From"))
+	}
+	if !strings.Contains(sink.data, "Hello,\nopen this link.") {
+		t.Errorf("body missing from message: %q", sink.data)
+	}
+}
+
+func TestSMTPMailer_Send_RejectsHeaderInjection(t *testing.T) {
+	m := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: 1, From: "noreply@example.com"})
+
+	err := m.Send(context.Background(), Message{To: "user@example.com\r\nBcc: other@example.com", Subject: "Hi"})
+	if err == nil {
+		t.Fatal("expected an error for a recipient with a line break")
+	}
+}
+
+func TestMailAddress(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		valid    bool
+	}{
+		{"user@example.com", "user@example.com", true},
+		{"Bamboo Mapper <noreply@example.com>", "noreply@example.com", true},
+		{" user@example.com ", "user@example.com", true},
+		{"not-an-address", "", false},
+		{"user@example.com\nRCPT TO:<x@example.com>", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Quote(tt.input), func(t *testing.T) {
+			got, err := mailAddress(tt.input)
+			if (err == nil) != tt.valid {
+				t.Fatalf("mailAddress(%q) error = %v, expected valid=%v", tt.input, err, tt.valid)
+			}
+			if got != tt.expected {
+				t.Errorf("mailAddress(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package markerimage
+
+import (
+	"bytes"
+	"database/sql"
+	"log"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/imaging"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/storage"
+)
+
+// URLs holds the storage URLs of every variant of a marker image
+type URLs struct {
+	Original  sql.NullString
+	Medium    sql.NullString
+	Thumbnail sql.NullString
+}
+
+// variantSuffixes maps each variant to the filename suffix used in storage
+var variantSuffixes = map[imaging.Variant]string{
+	imaging.VariantOriginal:  "",
+	imaging.VariantMedium:    "_medium",
+	imaging.VariantThumbnail: "_thumb",
+}
+
+// Upload resizes an image and uploads every variant, named after the marker short code.
+// A failed upload removes the variants already stored.
+func Upload(store storage.Storage, data []byte, shortCode string) (URLs, error) {
+	var urls URLs
+
+	variants, err := imaging.Process(data)
+	if err != nil {
+		return urls, err
+	}
+
+	for _, v := range variants {
+		filename := shortCode + variantSuffixes[v.Variant] + ".jpg"
+		url, err := store.UploadFile(bytes.NewReader(v.Data), filename, imaging.ContentType)
+		if err != nil {
+			// Don't leave a partial set behind
+			Delete(store, urls)
+			return URLs{}, err
+		}
+
+		value := sql.NullString{String: url, Valid: true}
+		switch v.Variant {
+		case imaging.VariantOriginal:
+			urls.Original = value
+		case imaging.VariantMedium:
+			urls.Medium = value
+		case imaging.VariantThumbnail:
+			urls.Thumbnail = value
+		}
+	}
+
+	return urls, nil
+}
+
+// Delete removes every valid URL in the set from storage.
+// Failures are logged only - the file might already be deleted.
+func Delete(store storage.Storage, urls URLs) {
+	for _, url := range []sql.NullString{urls.Original, urls.Medium, urls.Thumbnail} {
+		if !url.Valid {
+			continue
+		}
+		fileID := storage.ExtractFileID(url.String)
+		if fileID == "" {
+			continue
+		}
+		if err := store.DeleteFile(fileID); err != nil {
+			log.Printf("Failed to delete image from storage: %v", err)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package markerimage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/imaging"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/storage"
+	"github.com/google/uuid"
+)
+
+// Spool file extensions: the image is written first, the job file commits it
+const (
+	imageExt = ".img"
+	jobExt   = ".json"
+)
+
+// maxRetryDelay caps the exponential backoff between attempts
+const maxRetryDelay = 5 * time.Minute
+
+// Repository is the subset of the queries used by the queue
+type Repository interface {
+	CompleteMarkerImage(ctx context.Context, arg repository.CompleteMarkerImageParams) (repository.CompleteMarkerImageRow, error)
+	FailMarkerImage(ctx context.Context, arg repository.FailMarkerImageParams) error
+	ListPendingMarkerImages(ctx context.Context) ([]repository.ListPendingMarkerImagesRow, error)
+}
+
+// Config controls the queue
+type Config struct {
+	// SpoolDir holds uploaded images until they are processed
+	SpoolDir string
+	// Workers is the number of images processed concurrently
+	Workers int
+	// MaxAttempts is how often a job is tried before the image is marked failed
+	MaxAttempts int
+	// RetryDelay is the delay before the first retry, doubled on every further attempt
+	RetryDelay time.Duration
+}
+
+// Job is a spooled image waiting to be processed and uploaded for a marker
+type Job struct {
+	ID        uuid.UUID `json:"id"`
+	MarkerID  uuid.UUID `json:"marker_id"`
+	ShortCode string    `json:"short_code"`
+	Attempts  int       `json:"attempts"`
+}
+
+// Queue processes spooled marker images in the background with a worker pool.
+// Jobs live on disk until they finish, so they survive restarts.
+type Queue struct {
+	repo  Repository
+	store storage.Storage
+	cfg   Config
+
+	mu      sync.Mutex
+	pending []Job
+	wake    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewQueue creates a Queue and its spool directory
+func NewQueue(repo Repository, store storage.Storage, cfg Config) (*Queue, error) {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if err := os.MkdirAll(cfg.SpoolDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	return &Queue{
+		repo:  repo,
+		store: store,
+		cfg:   cfg,
+		wake:  make(chan struct{}, 1),
+	}, nil
+}
+
+// Spool writes an uploaded image to the spool directory and returns its job ID.
+// The image is not processed until Enqueue is called with that ID.
+func (q *Queue) Spool(data []byte) (uuid.UUID, error) {
+	id := uuid.New()
+	if err := writeFileAtomic(q.path(id, imageExt), data); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to spool image: %w", err)
+	}
+	return id, nil
+}
+
+// Discard removes a spooled image that will not be enqueued
+func (q *Queue) Discard(id uuid.UUID) {
+	q.remove(id)
+}
+
+// Enqueue commits a spooled image as a job and schedules it
+func (q *Queue) Enqueue(job Job) error {
+	if err := q.saveJob(job); err != nil {
+		return err
+	}
+	q.push(job)
+	return nil
+}
+
+// Start recovers jobs left in the spool directory and starts the workers.
+// Markers still pending on a job that isn't spooled are marked failed. Workers
+// finish their current job and stop when ctx is cancelled; waiting jobs stay spooled.
+func (q *Queue) Start(ctx context.Context) error {
+	jobs, err := q.recover()
+	if err != nil {
+		return err
+	}
+	q.failLostJobs(ctx, jobs)
+
+	q.mu.Lock()
+	queued := make(map[uuid.UUID]bool, len(q.pending))
+	for _, job := range q.pending {
+		queued[job.ID] = true
+	}
+	q.mu.Unlock()
+	for _, job := range jobs {
+		if !queued[job.ID] {
+			q.push(job)
+		}
+	}
+	if len(jobs) > 0 {
+		log.Printf("Recovered %d spooled image jobs", len(jobs))
+	}
+
+	for i := 0; i < q.cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.worker(ctx)
+	}
+	return nil
+}
+
+// Wait blocks until all workers have stopped
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
+
+// push adds a job to the pending list and wakes a worker
+func (q *Queue) push(job Job) {
+	q.mu.Lock()
+	q.pending = append(q.pending, job)
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next blocks until a job is pending or ctx is cancelled
+func (q *Queue) next(ctx context.Context) (Job, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.pending) > 0 {
+			job := q.pending[0]
+			q.pending = q.pending[1:]
+			more := len(q.pending) > 0
+			q.mu.Unlock()
+
+			// Pass the wake-up on so idle workers pick up the rest
+			if more {
+				select {
+				case q.wake <- struct{}{}:
+				default:
+				}
+			}
+			return job, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Job{}, false
+		case <-q.wake:
+		}
+	}
+}
+
+func (q *Queue) worker(ctx context.Context) {
+	defer q.wg.Done()
+	for {
+		job, ok := q.next(ctx)
+		if !ok {
+			return
+		}
+		q.run(ctx, job)
+	}
+}
+
+// run makes one attempt at a job and schedules a retry or gives up on failure
+func (q *Queue) run(ctx context.Context, job Job) {
+	job.Attempts++
+	// A started job runs to the end, so a shutdown doesn't waste its upload
+	err := q.process(context.WithoutCancel(ctx), job)
+	if err == nil {
+		q.remove(job.ID)
+		return
+	}
+
+	permanent := errors.Is(err, imaging.ErrUnsupportedFormat) || errors.Is(err, imaging.ErrTooManyPixels) ||
+		errors.Is(err, os.ErrNotExist)
+	if permanent || job.Attempts >= q.cfg.MaxAttempts {
+		log.Printf("Image job %s for marker %s failed after %d attempts: %v", job.ID, job.MarkerID, job.Attempts, err)
+		if err := q.repo.FailMarkerImage(ctx, repository.FailMarkerImageParams{
+			ID:         job.MarkerID,
+			ImageJobID: uuid.NullUUID{UUID: job.ID, Valid: true},
+		}); err != nil {
+			// Keep the spool files so the job is retried after a restart
+			log.Printf("Failed to mark image job %s as failed: %v", job.ID, err)
+			return
+		}
+		q.remove(job.ID)
+		return
+	}
+
+	delay := q.retryDelay(job.Attempts)
+	log.Printf("Image job %s for marker %s failed (attempt %d), retrying in %s: %v", job.ID, job.MarkerID, job.Attempts, delay, err)
+	if err := q.saveJob(job); err != nil {
+		log.Printf("Failed to save image job %s: %v", job.ID, err)
+	}
+
+	time.AfterFunc(delay, func() {
+		if ctx.Err() == nil {
+			q.push(job)
+		}
+	})
+}
+
+// process resizes and uploads a spooled image, then points the marker at it
+func (q *Queue) process(ctx context.Context, job Job) error {
+	data, err := os.ReadFile(q.path(job.ID, imageExt))
+	if err != nil {
+		return fmt.Errorf("failed to read spooled image: %w", err)
+	}
+
+	urls, err := Upload(q.store, data, job.ShortCode)
+	if err != nil {
+		return err
+	}
+
+	old, err := q.repo.CompleteMarkerImage(ctx, repository.CompleteMarkerImageParams{
+		ID:                job.MarkerID,
+		ImageJobID:        uuid.NullUUID{UUID: job.ID, Valid: true},
+		ImageUrl:          urls.Original,
+		ImageMediumUrl:    urls.Medium,
+		ImageThumbnailUrl: urls.Thumbnail,
+	})
+	if err != nil {
+		// Either way the marker does not point at the new images
+		Delete(q.store, urls)
+		if errors.Is(err, sql.ErrNoRows) {
+			// The marker was deleted or a newer upload replaced this one
+			return nil
+		}
+		return fmt.Errorf("failed to update marker image: %w", err)
+	}
+
+	// The marker now points at the new images, so the replaced ones can go
+	Delete(q.store, URLs{
+		Original:  old.ImageUrl,
+		Medium:    old.ImageMediumUrl,
+		Thumbnail: old.ImageThumbnailUrl,
+	})
+	return nil
+}
+
+// retryDelay returns the backoff before the next attempt
+func (q *Queue) retryDelay(attempts int) time.Duration {
+	delay := q.cfg.RetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// recover loads committed jobs from the spool directory and removes
+// images that were never enqueued (the request failed before committing them)
+func (q *Queue) recover() ([]Job, error) {
+	entries, err := os.ReadDir(q.cfg.SpoolDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	committed := make(map[string]bool)
+	var jobs []Job
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), jobExt) {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(q.cfg.SpoolDir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spooled job: %w", err)
+		}
+		var job Job
+		if err := json.Unmarshal(raw, &job); err != nil {
+			log.Printf("Skipping unreadable spooled job %s: %v", e.Name(), err)
+			continue
+		}
+		committed[job.ID.String()] = true
+		jobs = append(jobs, job)
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		orphaned := strings.HasSuffix(name, imageExt) && !committed[strings.TrimSuffix(name, imageExt)]
+		if orphaned || strings.HasSuffix(name, ".tmp") {
+			os.Remove(filepath.Join(q.cfg.SpoolDir, name))
+		}
+	}
+
+	return jobs, nil
+}
+
+// failLostJobs marks the images of pending markers failed when their job is not
+// among the recovered ones, as its spool files were lost (for example with the disk
+// of a replaced container). Their image could never finish otherwise.
+func (q *Queue) failLostJobs(ctx context.Context, jobs []Job) {
+	pending, err := q.repo.ListPendingMarkerImages(ctx)
+	if err != nil {
+		log.Printf("Failed to list pending marker images: %v", err)
+		return
+	}
+
+	spooled := make(map[uuid.UUID]bool, len(jobs))
+	for _, job := range jobs {
+		spooled[job.ID] = true
+	}
+	q.mu.Lock()
+	for _, job := range q.pending {
+		spooled[job.ID] = true
+	}
+	q.mu.Unlock()
+
+	failed := 0
+	for _, m := range pending {
+		if !m.ImageJobID.Valid || spooled[m.ImageJobID.UUID] {
+			continue
+		}
+		if err := q.repo.FailMarkerImage(ctx, repository.FailMarkerImageParams{
+			ID:         m.ID,
+			ImageJobID: m.ImageJobID,
+		}); err != nil {
+			log.Printf("Failed to mark lost image job %s as failed: %v", m.ImageJobID.UUID, err)
+			continue
+		}
+		failed++
+	}
+	if failed > 0 {
+		log.Printf("Marked %d image jobs without spool files as failed", failed)
+	}
+}
+
+// saveJob writes the job file that commits a spooled image
+func (q *Queue) saveJob(job Job) error {
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(q.path(job.ID, jobExt), raw); err != nil {
+		return fmt.Errorf("failed to save image job: %w", err)
+	}
+	return nil
+}
+
+// remove deletes every spool file of a job
+func (q *Queue) remove(id uuid.UUID) {
+	os.Remove(q.path(id, jobExt))
+	os.Remove(q.path(id, imageExt))
+}
+
+func (q *Queue) path(id uuid.UUID, ext string) string {
+	return filepath.Join(q.cfg.SpoolDir, id.String()+ext)
+}
+
+// writeFileAtomic writes a file via a temporary file so readers never see partial data
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,424 @@
+package markerimage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/storage"
+	"github.com/google/uuid"
+)
+
+// fakeStorage is an in-memory storage.Storage that can fail the first uploads
+type fakeStorage struct {
+	mu       sync.Mutex
+	files    map[string][]byte
+	nextID   int
+	failures int // number of uploads to fail before succeeding
+}
+
+func newFakeStorage(existingIDs ...string) *fakeStorage {
+	f := &fakeStorage{files: make(map[string][]byte)}
+	for _, id := range existingIDs {
+		f.files[id] = []byte("existing")
+	}
+	return f
+}
+
+func (f *fakeStorage) UploadFile(file io.Reader, filename, mimeType string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		return "", errors.New("storage unavailable")
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	f.nextID++
+	id := fmt.Sprintf("fake-%d", f.nextID)
+	f.files[id] = data
+	return storage.FileURL(id), nil
+}
+
+func (f *fakeStorage) OpenFile(fileID string) (io.ReadCloser, string, int64, error) {
+	return nil, "", 0, errors.New("not implemented")
+}
+
+func (f *fakeStorage) DeleteFile(fileID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.files, fileID)
+	return nil
+}
+
+func (f *fakeStorage) has(fileID string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.files[fileID]
+	return ok
+}
+
+func (f *fakeStorage) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.files)
+}
+
+// fakeRepository records job outcomes and reports them on done
+type fakeRepository struct {
+	mu         sync.Mutex
+	current    map[uuid.UUID]uuid.UUID // marker ID -> current image job ID
+	old        repository.CompleteMarkerImageRow
+	completed  []repository.CompleteMarkerImageParams
+	failed     []repository.FailMarkerImageParams
+	pending    []repository.ListPendingMarkerImagesRow
+	completeFn func() error
+	done       chan struct{}
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		current: make(map[uuid.UUID]uuid.UUID),
+		done:    make(chan struct{}, 10),
+	}
+}
+
+func (f *fakeRepository) CompleteMarkerImage(ctx context.Context, arg repository.CompleteMarkerImageParams) (repository.CompleteMarkerImageRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.completeFn != nil {
+		if err := f.completeFn(); err != nil {
+			return repository.CompleteMarkerImageRow{}, err
+		}
+	}
+	defer func() { f.done <- struct{}{} }()
+	if f.current[arg.ID] != arg.ImageJobID.UUID {
+		return repository.CompleteMarkerImageRow{}, sql.ErrNoRows
+	}
+	f.completed = append(f.completed, arg)
+	return f.old, nil
+}
+
+func (f *fakeRepository) FailMarkerImage(ctx context.Context, arg repository.FailMarkerImageParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failed = append(f.failed, arg)
+	f.done <- struct{}{}
+	return nil
+}
+
+func (f *fakeRepository) ListPendingMarkerImages(ctx context.Context) ([]repository.ListPendingMarkerImagesRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pending, nil
+}
+
+func testJPEG(t *testing.T) []byte {
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, image.NewRGBA(image.Rect(0, 0, 40, 30)), nil); err != nil {
+		t.Fatalf("failed to encode test jpeg: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func newTestQueue(t *testing.T, repo *fakeRepository, store *fakeStorage) *Queue {
+	q, err := NewQueue(repo, store, Config{
+		SpoolDir:    t.TempDir(),
+		Workers:     2,
+		MaxAttempts: 3,
+		RetryDelay:  time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	return q
+}
+
+// startQueue runs the queue until the test ends
+func startQueue(t *testing.T, q *Queue) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := q.Start(ctx); err != nil {
+		t.Fatalf("failed to start queue: %v", err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		q.Wait()
+	})
+}
+
+// spoolJob spools data and enqueues it as the marker's current image job
+func spoolJob(t *testing.T, q *Queue, repo *fakeRepository, data []byte) Job {
+	id, err := q.Spool(data)
+	if err != nil {
+		t.Fatalf("failed to spool: %v", err)
+	}
+	job := Job{ID: id, MarkerID: uuid.New(), ShortCode: "ABC123"}
+	repo.mu.Lock()
+	repo.current[job.MarkerID] = id
+	repo.mu.Unlock()
+	if err := q.Enqueue(job); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+	return job
+}
+
+func waitDone(t *testing.T, repo *fakeRepository) {
+	select {
+	case <-repo.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the job")
+	}
+}
+
+// waitSpoolEmpty waits for the queue to clean up the job's spool files
+func waitSpoolEmpty(t *testing.T, q *Queue) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		entries, _ := os.ReadDir(q.cfg.SpoolDir)
+		if len(entries) == 0 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("expected spool directory to be empty")
+}
+
+func TestQueue_ProcessesAndReplacesOldImage(t *testing.T) {
+	repo := newFakeRepository()
+	repo.old = repository.CompleteMarkerImageRow{
+		ImageUrl: sql.NullString{String: storage.FileURL("old"), Valid: true},
+	}
+	store := newFakeStorage("old")
+	q := newTestQueue(t, repo, store)
+	startQueue(t, q)
+
+	job := spoolJob(t, q, repo, testJPEG(t))
+	waitDone(t, repo)
+	waitSpoolEmpty(t, q)
+
+	if len(repo.completed) != 1 {
+		t.Fatalf("expected job to complete, got %d completions", len(repo.completed))
+	}
+	completed := repo.completed[0]
+	if completed.ID != job.MarkerID || !completed.ImageUrl.Valid || !completed.ImageMediumUrl.Valid || !completed.ImageThumbnailUrl.Valid {
+		t.Errorf("unexpected completion: %+v", completed)
+	}
+	if store.has("old") {
+		t.Error("expected replaced image to be deleted")
+	}
+	if store.count() != 3 {
+		t.Errorf("expected 3 stored variants, got %d", store.count())
+	}
+}
+
+func TestQueue_RetriesFailedUploads(t *testing.T) {
+	repo := newFakeRepository()
+	store := newFakeStorage()
+	store.failures = 2
+	q := newTestQueue(t, repo, store)
+	startQueue(t, q)
+
+	spoolJob(t, q, repo, testJPEG(t))
+	waitDone(t, repo)
+
+	if len(repo.completed) != 1 || len(repo.failed) != 0 {
+		t.Errorf("expected job to succeed on the third attempt, got %d completed and %d failed", len(repo.completed), len(repo.failed))
+	}
+}
+
+func TestQueue_MarksFailedAfterMaxAttempts(t *testing.T) {
+	repo := newFakeRepository()
+	store := newFakeStorage()
+	store.failures = 100
+	q := newTestQueue(t, repo, store)
+	startQueue(t, q)
+
+	job := spoolJob(t, q, repo, testJPEG(t))
+	waitDone(t, repo)
+	waitSpoolEmpty(t, q)
+
+	if len(repo.failed) != 1 || repo.failed[0].ID != job.MarkerID {
+		t.Errorf("expected marker image to be marked failed, got %+v", repo.failed)
+	}
+}
+
+func TestQueue_UndecodableImageFailsImmediately(t *testing.T) {
+	repo := newFakeRepository()
+	store := newFakeStorage()
+	q := newTestQueue(t, repo, store)
+	startQueue(t, q)
+
+	// Valid JPEG magic bytes, but no decodable image
+	spoolJob(t, q, repo, []byte("\xff\xd8\xff\xe0 broken"))
+	waitDone(t, repo)
+
+	if len(repo.failed) != 1 {
+		t.Errorf("expected job to fail without retries, got %d failures", len(repo.failed))
+	}
+	if store.count() != 0 {
+		t.Errorf("expected nothing to be stored, got %d files", store.count())
+	}
+}
+
+func TestQueue_SupersededJobDeletesUploads(t *testing.T) {
+	repo := newFakeRepository()
+	store := newFakeStorage()
+	q := newTestQueue(t, repo, store)
+
+	startQueue(t, q)
+
+	id, err := q.Spool(testJPEG(t))
+	if err != nil {
+		t.Fatalf("failed to spool: %v", err)
+	}
+	job := Job{ID: id, MarkerID: uuid.New(), ShortCode: "ABC123"}
+	// A newer upload replaced this job before it ran
+	repo.mu.Lock()
+	repo.current[job.MarkerID] = uuid.New()
+	repo.mu.Unlock()
+	if err := q.Enqueue(job); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+
+	waitDone(t, repo)
+	waitSpoolEmpty(t, q)
+
+	if len(repo.completed) != 0 {
+		t.Errorf("expected superseded job not to complete, got %+v", repo.completed)
+	}
+	if store.count() != 0 {
+		t.Errorf("expected superseded uploads to be deleted, got %d files", store.count())
+	}
+}
+
+func TestQueue_DatabaseErrorDeletesUploadsAndRetries(t *testing.T) {
+	repo := newFakeRepository()
+	calls := 0
+	repo.completeFn = func() error {
+		calls++
+		if calls == 1 {
+			return errors.New("connection reset")
+		}
+		return nil
+	}
+	store := newFakeStorage()
+	q := newTestQueue(t, repo, store)
+	startQueue(t, q)
+
+	spoolJob(t, q, repo, testJPEG(t))
+	waitDone(t, repo)
+
+	if len(repo.completed) != 1 {
+		t.Fatalf("expected job to complete on retry, got %d completions", len(repo.completed))
+	}
+	// Only the variants of the successful attempt remain
+	if store.count() != 3 {
+		t.Errorf("expected 3 stored variants, got %d", store.count())
+	}
+}
+
+func TestQueue_RecoversSpooledJobs(t *testing.T) {
+	repo := newFakeRepository()
+	store := newFakeStorage()
+	q := newTestQueue(t, repo, store)
+
+	// Committed before a restart
+	job := spoolJob(t, q, repo, testJPEG(t))
+	// Spooled but never committed: the request failed
+	if _, err := q.Spool(testJPEG(t)); err != nil {
+		t.Fatalf("failed to spool: %v", err)
+	}
+
+	restarted, err := NewQueue(repo, store, q.cfg)
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	startQueue(t, restarted)
+	waitDone(t, repo)
+	waitSpoolEmpty(t, restarted)
+
+	if len(repo.completed) != 1 || repo.completed[0].ID != job.MarkerID {
+		t.Errorf("expected recovered job to complete, got %+v", repo.completed)
+	}
+}
+
+func TestQueue_FailsPendingMarkersWithoutSpooledJob(t *testing.T) {
+	repo := newFakeRepository()
+	store := newFakeStorage()
+	q := newTestQueue(t, repo, store)
+
+	job := spoolJob(t, q, repo, testJPEG(t))
+	lost := repository.ListPendingMarkerImagesRow{
+		ID:         uuid.New(),
+		ImageJobID: uuid.NullUUID{UUID: uuid.New(), Valid: true},
+	}
+	repo.pending = []repository.ListPendingMarkerImagesRow{
+		{ID: job.MarkerID, ImageJobID: uuid.NullUUID{UUID: job.ID, Valid: true}},
+		lost,
+	}
+
+	restarted, err := NewQueue(repo, store, q.cfg)
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	startQueue(t, restarted)
+	waitDone(t, repo)
+	waitDone(t, repo)
+	waitSpoolEmpty(t, restarted)
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if len(repo.failed) != 1 || repo.failed[0].ID != lost.ID || repo.failed[0].ImageJobID != lost.ImageJobID {
+		t.Errorf("expected only the marker without a spooled job to fail, got %+v", repo.failed)
+	}
+	if len(repo.completed) != 1 || repo.completed[0].ID != job.MarkerID {
+		t.Errorf("expected the spooled job to complete, got %+v", repo.completed)
+	}
+}
+
+func TestQueue_RetryDelay(t *testing.T) {
+	q := &Queue{cfg: Config{RetryDelay: time.Second}}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{20, maxRetryDelay},
+	}
+
+	for _, tt := range tests {
+		if got := q.retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	q := newTestQueue(t, newFakeRepository(), newFakeStorage())
+
+	id, err := q.Spool([]byte("data"))
+	if err != nil {
+		t.Fatalf("failed to spool: %v", err)
+	}
+	q.Discard(id)
+
+	if _, err := os.Stat(filepath.Join(q.cfg.SpoolDir, id.String()+imageExt)); !os.IsNotExist(err) {
+		t.Error("expected spooled image to be removed")
+	}
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/auth"
@@ -46,15 +47,100 @@ func JWTAuth(jwtManager *auth.JWTManager) func(http.Handler) http.Handler {
 	}
 }
 
+// OptionalJWTAuth creates a middleware that stores JWT claims in the context when a
+// valid access token is presented, and otherwise lets the request through anonymously
+func OptionalJWTAuth(jwtManager *auth.JWTManager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+			if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
+				if claims, err := jwtManager.ValidateAccessToken(parts[1]); err == nil {
+					ctx := context.WithValue(r.Context(), ClaimsKey, claims)
+					r = r.WithContext(ctx)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // GetClaims retrieves the JWT claims from the request context
 func GetClaims(ctx context.Context) (*auth.Claims, bool) {
 	claims, ok := ctx.Value(ClaimsKey).(*auth.Claims)
 	return claims, ok
 }
 
+// RequireRole creates a middleware that only lets users with one of the roles through.
+// It must run after JWTAuth.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := GetClaims(r.Context())
+			if !ok {
+				respondUnauthorized(w, "Authorization header required")
+				return
+			}
+			if !slices.Contains(roles, claims.Role) {
+				respondForbidden(w, "Insufficient role")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePermission creates a middleware that only lets users whose role grants the
+// permission through. It must run after JWTAuth.
+func RequirePermission(permission auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := GetClaims(r.Context())
+			if !ok {
+				respondUnauthorized(w, "Authorization header required")
+				return
+			}
+			if !claims.HasPermission(permission) {
+				respondForbidden(w, "Insufficient permissions")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireVerifiedEmail creates a middleware that rejects users who haven't verified their
+// email, keeping them read-only. It lets everyone through when enabled is false.
+// It must run after JWTAuth.
+func RequireVerifiedEmail(enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := GetClaims(r.Context())
+			if !ok {
+				respondUnauthorized(w, "Authorization header required")
+				return
+			}
+			if claims.EmailUnverified {
+				respondForbidden(w, "Email address is not verified")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // respondUnauthorized sends a 401 response with the standard format
 func respondUnauthorized(w http.ResponseWriter, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusUnauthorized)
 	w.Write([]byte(`{"meta":{"success":false,"message":"` + message + `"},"data":null}`))
 }
+
+// respondForbidden sends a 403 response with the standard format
+func respondForbidden(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte(`{"meta":{"success":false,"message":"` + message + `"},"data":null}`))
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/util"
+)
+
+// RateLimit configures a token bucket per caller
+type RateLimit struct {
+	// Requests is how many requests a caller may make at once, zero disables the limit
+	Requests int
+	// Window is how long an empty bucket takes to refill completely
+	Window time.Duration
+}
+
+// RateLimiter creates a middleware that limits requests with a token bucket per caller,
+// keyed by the user ID of the claims or by the client IP for anonymous callers. To key
+// by user it must run after JWTAuth or OptionalJWTAuth. Buckets are kept in memory, so
+// every replica enforces the limit separately.
+//
+// Responses carry RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers; refused requests get 429 with Retry-After.
+func RateLimiter(limit RateLimit) func(http.Handler) http.Handler {
+	if limit.Requests <= 0 || limit.Window <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	limiter := newRateLimiter(limit, time.Now)
+	policy := strconv.Itoa(limit.Requests) + ";w=" + strconv.Itoa(ceilSeconds(limit.Window))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, remaining, reset, retryAfter := limiter.take(rateLimitKey(r))
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+			w.Header().Set("RateLimit-Policy", policy)
+
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(retryAfter), 1)))
+				respondTooManyRequests(w, "Too many requests, try again later")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey identifies the caller of a request
+func rateLimitKey(r *http.Request) string {
+	if claims, ok := GetClaims(r.Context()); ok {
+		return "user:" + claims.UserID.String()
+	}
+	return "ip:" + util.ClientIP(r)
+}
+
+// tokenBucket holds the tokens of one caller as of updated
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimiter keeps the token buckets of one limit
+type rateLimiter struct {
+	limit     RateLimit
+	perSecond float64
+	now       func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(limit RateLimit, now func() time.Time) *rateLimiter {
+	return &rateLimiter{
+		limit:     limit,
+		perSecond: float64(limit.Requests) / limit.Window.Seconds(),
+		now:       now,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: now(),
+	}
+}
+
+// take spends a token of the caller's bucket when one is left. It returns whether the
+// request is allowed, the whole tokens left, how long until the bucket is full again
+// and, for refused requests, how long until the next token.
+func (l *rateLimiter) take(key string) (bool, int, time.Duration, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.limit.Requests), updated: now}
+		l.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.updated).Seconds()
+	bucket.tokens = min(float64(l.limit.Requests), bucket.tokens+elapsed*l.perSecond)
+	bucket.updated = now
+
+	var retryAfter time.Duration
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	} else {
+		retryAfter = l.refillTime(1 - bucket.tokens)
+	}
+
+	reset := l.refillTime(float64(l.limit.Requests) - bucket.tokens)
+	return allowed, int(bucket.tokens), reset, retryAfter
+}
+
+// refillTime returns how long the bucket takes to gain tokens
+func (l *rateLimiter) refillTime(tokens float64) time.Duration {
+	return time.Duration(tokens / l.perSecond * float64(time.Second))
+}
+
+// sweep drops the buckets that refilled completely, at most once per window, so
+// callers that went away don't pile up. The caller must hold mu.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.limit.Window {
+		return
+	}
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.updated) >= l.limit.Window {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// respondTooManyRequests sends a 429 response with the standard format
+func respondTooManyRequests(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	w.Write([]byte(`{"meta":{"success":false,"message":"` + message + `"},"data":null}`))
+}
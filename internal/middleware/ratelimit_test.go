@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/auth"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/util"
+	"github.com/google/uuid"
+)
+
+func TestRateLimiter_TokenBucket(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter := newRateLimiter(RateLimit{Requests: 3, Window: 30 * time.Second}, func() time.Time { return now })
+
+	for i := 0; i < 3; i++ {
+		allowed, remaining, _, _ := limiter.take("ip:203.0.113.7")
+		if !allowed || remaining != 2-i {
+			t.Fatalf("request %d: expected allowed with %d left, got %v with %d", i+1, 2-i, allowed, remaining)
+		}
+	}
+
+	allowed, remaining, reset, retryAfter := limiter.take("ip:203.0.113.7")
+	if allowed || remaining != 0 {
+		t.Fatalf("expected empty bucket to refuse, got %v with %d left", allowed, remaining)
+	}
+	if retryAfter != 10*time.Second || reset != 30*time.Second {
+		t.Errorf("expected retry after 10s and reset after 30s, got %v and %v", retryAfter, reset)
+	}
+
+	// Other callers have their own bucket
+	if allowed, _, _, _ := limiter.take("ip:203.0.113.8"); !allowed {
+		t.Error("expected another caller to be allowed")
+	}
+
+	// A token refills every 10 seconds
+	now = now.Add(10 * time.Second)
+	if allowed, remaining, _, _ := limiter.take("ip:203.0.113.7"); !allowed || remaining != 0 {
+		t.Errorf("expected a refilled token, got %v with %d left", allowed, remaining)
+	}
+}
+
+func TestRateLimiter_SweepsFullBuckets(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter := newRateLimiter(RateLimit{Requests: 2, Window: time.Minute}, func() time.Time { return now })
+
+	limiter.take("ip:203.0.113.7")
+	now = now.Add(time.Minute)
+	limiter.take("ip:203.0.113.8")
+
+	if _, ok := limiter.buckets["ip:203.0.113.7"]; ok {
+		t.Error("expected the refilled bucket to be dropped")
+	}
+	if len(limiter.buckets) != 1 {
+		t.Errorf("expected 1 bucket, got %d", len(limiter.buckets))
+	}
+}
+
+func TestRateLimiter_Middleware(t *testing.T) {
+	handler := RateLimiter(RateLimit{Requests: 1, Window: time.Minute})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }))
+
+	serve := func(userID uuid.UUID) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/markers/code/ABC", nil)
+		if userID != uuid.Nil {
+			req = req.WithContext(context.WithValue(req.Context(), ClaimsKey, &auth.Claims{UserID: userID}))
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve(uuid.Nil)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rr.Code)
+	}
+	expected := map[string]string{
+		"RateLimit-Limit":     "1",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "60",
+		"RateLimit-Policy":    "1;w=60",
+	}
+	for header, value := range expected {
+		if got := rr.Header().Get(header); got != value {
+			t.Errorf("expected %s %q, got %q", header, value, got)
+		}
+	}
+
+	rr = serve(uuid.Nil)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected Retry-After 60, got %q", rr.Header().Get("Retry-After"))
+	}
+
+	// Signed in callers are limited per user, not per IP
+	if rr := serve(uuid.New()); rr.Code != http.StatusNoContent {
+		t.Errorf("expected a signed in caller from the same IP to be allowed, got %d", rr.Code)
+	}
+}
+
+func TestRateLimiter_Disabled(t *testing.T) {
+	handler := RateLimiter(RateLimit{})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }))
+
+	for i := 0; i < 100; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rr.Code != http.StatusNoContent || rr.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("expected a disabled limit to pass every request untouched")
+		}
+	}
+}
+
+func TestRateLimiter_IgnoresSpoofedForwardedFor(t *testing.T) {
+	proxies, err := util.ParseTrustedProxies([]string{"10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := RealIP(proxies)(RateLimiter(RateLimit{Requests: 1, Window: time.Minute})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })))
+
+	serve := func(remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/markers/code/ABC", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// A direct caller can't get a fresh bucket by changing the header
+	serve("198.51.100.1:1234", "203.0.113.1")
+	if code := serve("198.51.100.1:1234", "203.0.113.2"); code != http.StatusTooManyRequests {
+		t.Errorf("expected spoofed header to share the bucket, got %d", code)
+	}
+
+	// Behind the trusted proxy, clients are told apart and forged entries are skipped
+	if code := serve("10.0.0.1:1234", "203.0.113.7"); code != http.StatusNoContent {
+		t.Errorf("expected first proxied client to be allowed, got %d", code)
+	}
+	if code := serve("10.0.0.1:1234", "1.2.3.4, 203.0.113.7"); code != http.StatusTooManyRequests {
+		t.Errorf("expected forged left entry to be ignored, got %d", code)
+	}
+	if code := serve("10.0.0.1:1234", "203.0.113.8"); code != http.StatusNoContent {
+		t.Errorf("expected another proxied client to be allowed, got %d", code)
+	}
+}
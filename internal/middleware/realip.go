@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/util"
+)
+
+// RealIP creates a middleware that replaces RemoteAddr with the client address resolved
+// through the trusted proxies, so util.ClientIP returns the client everywhere after it.
+// Forwarding headers of requests that didn't come through a trusted proxy are ignored.
+func RealIP(proxies util.TrustedProxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.RemoteAddr = proxies.ResolveClientIP(r)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package model
 
-import "strings"
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // LoginRequest represents the login request body
 type LoginRequest struct {
@@ -57,3 +62,107 @@ type RefreshResponse struct {
 	TokenType    string `json:"token_type"`
 	ExpiresIn    int64  `json:"expires_in"`
 }
+
+// SessionResponse is the API response for a signed in device
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  *string   `json:"user_agent"`
+	IPAddress  *string   `json:"ip_address"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current marks the session of the access token used for the request
+	Current bool `json:"current"`
+}
+
+// LoginLockoutResponse is the API response for a locked out email or client IP
+type LoginLockoutResponse struct {
+	Scope         string    `json:"scope"`
+	Subject       string    `json:"subject"`
+	Failures      int32     `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+	LockedUntil   time.Time `json:"locked_until"`
+}
+
+// ForgotPasswordRequest represents the forgot password request body
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// Validate checks if the forgot password request is valid
+func (r *ForgotPasswordRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	r.Email = strings.TrimSpace(r.Email)
+
+	if r.Email == "" {
+		errors["email"] = "email is required"
+	} else if !isValidEmail(r.Email) {
+		errors["email"] = "invalid email format"
+	}
+
+	return errors
+}
+
+// ResetPasswordRequest represents the reset password request body
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// Validate checks if the reset password request is valid
+func (r *ResetPasswordRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	r.Token = strings.TrimSpace(r.Token)
+
+	if r.Token == "" {
+		errors["token"] = "token is required"
+	}
+
+	if msg := validatePassword("password", r.Password); msg != "" {
+		errors["password"] = msg
+	}
+
+	return errors
+}
+
+// VerifyEmailRequest represents the verify email request body
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// Validate checks if the verify email request is valid
+func (r *VerifyEmailRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	r.Token = strings.TrimSpace(r.Token)
+
+	if r.Token == "" {
+		errors["token"] = "token is required"
+	}
+
+	return errors
+}
+
+// ChangePasswordRequest represents the change password request body
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// Validate checks if the change password request is valid
+func (r *ChangePasswordRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	if r.CurrentPassword == "" {
+		errors["current_password"] = "current_password is required"
+	}
+
+	if msg := validatePassword("new_password", r.NewPassword); msg != "" {
+		errors["new_password"] = msg
+	} else if r.NewPassword == r.CurrentPassword {
+		errors["new_password"] = "new_password must differ from current_password"
+	}
+
+	return errors
+}
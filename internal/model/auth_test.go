@@ -0,0 +1,132 @@
+package model
+
+import "testing"
+
+func TestForgotPasswordRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name          string
+		email         string
+		expectedError string
+	}{
+		{"valid email", " user@example.com ", ""},
+		{"empty email", "", "email is required"},
+		{"invalid email", "user@", "invalid email format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := ForgotPasswordRequest{Email: tt.email}
+			errors := req.Validate()
+			if errors["email"] != tt.expectedError {
+				t.Errorf("expected error %q, got %q", tt.expectedError, errors["email"])
+			}
+		})
+	}
+}
+
+func TestResetPasswordRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name           string
+		request        ResetPasswordRequest
+		expectedErrors map[string]string
+	}{
+		{
+			name:           "valid request",
+			request:        ResetPasswordRequest{Token: "abc", Password: "password123"},
+			expectedErrors: map[string]string{},
+		},
+		{
+			name:    "missing token",
+			request: ResetPasswordRequest{Token: "  ", Password: "password123"},
+			expectedErrors: map[string]string{
+				"token": "token is required",
+			},
+		},
+		{
+			name:    "short password",
+			request: ResetPasswordRequest{Token: "abc", Password: "short"},
+			expectedErrors: map[string]string{
+				"password": "password must be at least 8 characters",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := tt.request.Validate()
+			if len(errors) != len(tt.expectedErrors) {
+				t.Fatalf("expected %d errors, got %d: %v", len(tt.expectedErrors), len(errors), errors)
+			}
+			for field, msg := range tt.expectedErrors {
+				if errors[field] != msg {
+					t.Errorf("expected %s error %q, got %q", field, msg, errors[field])
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyEmailRequest_Validate(t *testing.T) {
+	req := VerifyEmailRequest{Token: " abc "}
+	if errors := req.Validate(); len(errors) != 0 {
+		t.Errorf("expected no errors, got %v", errors)
+	}
+	if req.Token != "abc" {
+		t.Errorf("expected token to be trimmed, got %q", req.Token)
+	}
+
+	req = VerifyEmailRequest{}
+	if errors := req.Validate(); errors["token"] != "token is required" {
+		t.Errorf("expected token error, got %v", errors)
+	}
+}
+
+func TestChangePasswordRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name           string
+		request        ChangePasswordRequest
+		expectedErrors map[string]string
+	}{
+		{
+			name:           "valid request",
+			request:        ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "newpassword456"},
+			expectedErrors: map[string]string{},
+		},
+		{
+			name:    "missing passwords",
+			request: ChangePasswordRequest{},
+			expectedErrors: map[string]string{
+				"current_password": "current_password is required",
+				"new_password":     "new_password is required",
+			},
+		},
+		{
+			name:    "short new password",
+			request: ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "short"},
+			expectedErrors: map[string]string{
+				"new_password": "new_password must be at least 8 characters",
+			},
+		},
+		{
+			name:    "unchanged password",
+			request: ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "password123"},
+			expectedErrors: map[string]string{
+				"new_password": "new_password must differ from current_password",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := tt.request.Validate()
+			if len(errors) != len(tt.expectedErrors) {
+				t.Fatalf("expected %d errors, got %d: %v", len(tt.expectedErrors), len(errors), errors)
+			}
+			for field, msg := range tt.expectedErrors {
+				if errors[field] != msg {
+					t.Errorf("expected %s error %q, got %q", field, msg, errors[field])
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,21 +17,90 @@ type MarkerListItem struct {
 }
 
 // MarkerResponse represents full marker details
+// ImageURL points to the full-size image; the thumbnail and medium variants are resized copies.
+// ImageStatus is "pending" while an upload is processed, then "ready" or "failed" (null without an image).
 type MarkerResponse struct {
-	ID           uuid.UUID `json:"id"`
-	ShortCode    string    `json:"short_code"`
-	CreatorID    uuid.UUID `json:"creator_id"`
-	Name         string    `json:"name"`
-	Description  *string   `json:"description"`
-	Strain       *string   `json:"strain"`
-	Quantity     *int32    `json:"quantity"`
-	Latitude     string    `json:"latitude"`
-	Longitude    string    `json:"longitude"`
-	ImageURL     *string   `json:"image_url"`
-	OwnerName    *string   `json:"owner_name"`
-	OwnerContact *string   `json:"owner_contact"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID                uuid.UUID  `json:"id"`
+	ShortCode         string     `json:"short_code"`
+	CreatorID         uuid.UUID  `json:"creator_id"`
+	Name              string     `json:"name"`
+	Description       *string    `json:"description"`
+	Strain            *string    `json:"strain"`
+	Quantity          *int32     `json:"quantity"`
+	Latitude          string     `json:"latitude"`
+	Longitude         string     `json:"longitude"`
+	ImageURL          *string    `json:"image_url"`
+	ImageThumbnailURL *string    `json:"image_thumbnail_url"`
+	ImageMediumURL    *string    `json:"image_medium_url"`
+	OwnerName         *string    `json:"owner_name"`
+	OwnerContact      *string    `json:"owner_contact"`
+	ImageCapturedAt   *time.Time `json:"image_captured_at"`
+	ImageStatus       *string    `json:"image_status"`
+	Visibility        string     `json:"visibility"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// Marker visibility controls what unauthenticated callers see of a marker
+const (
+	// VisibilityPublic shows every public field, including the owner's name and contact
+	VisibilityPublic = "public"
+	// VisibilityContactHidden hides the owner's name and contact
+	VisibilityContactHidden = "contact_hidden"
+	// VisibilityPrivate hides the marker entirely
+	VisibilityPrivate = "private"
+)
+
+// DefaultVisibility is used for markers created without a visibility
+const DefaultVisibility = VisibilityContactHidden
+
+// ValidVisibility reports whether v is a known marker visibility
+func ValidVisibility(v string) bool {
+	return v == VisibilityPublic || v == VisibilityContactHidden || v == VisibilityPrivate
+}
+
+// PublicMarkerResponse represents the marker details shown to unauthenticated callers.
+// It never includes the creator; owner details are only set for public markers.
+type PublicMarkerResponse struct {
+	ID                uuid.UUID  `json:"id"`
+	ShortCode         string     `json:"short_code"`
+	Name              string     `json:"name"`
+	Description       *string    `json:"description"`
+	Strain            *string    `json:"strain"`
+	Quantity          *int32     `json:"quantity"`
+	Latitude          string     `json:"latitude"`
+	Longitude         string     `json:"longitude"`
+	ImageURL          *string    `json:"image_url"`
+	ImageThumbnailURL *string    `json:"image_thumbnail_url"`
+	ImageMediumURL    *string    `json:"image_medium_url"`
+	OwnerName         *string    `json:"owner_name"`
+	OwnerContact      *string    `json:"owner_contact"`
+	ImageCapturedAt   *time.Time `json:"image_captured_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// PublicView returns the details of a marker that unauthenticated callers may see
+func (m MarkerResponse) PublicView() PublicMarkerResponse {
+	public := PublicMarkerResponse{
+		ID:                m.ID,
+		ShortCode:         m.ShortCode,
+		Name:              m.Name,
+		Description:       m.Description,
+		Strain:            m.Strain,
+		Quantity:          m.Quantity,
+		Latitude:          m.Latitude,
+		Longitude:         m.Longitude,
+		ImageURL:          m.ImageURL,
+		ImageThumbnailURL: m.ImageThumbnailURL,
+		ImageMediumURL:    m.ImageMediumURL,
+		ImageCapturedAt:   m.ImageCapturedAt,
+		CreatedAt:         m.CreatedAt,
+	}
+	if m.Visibility == VisibilityPublic {
+		public.OwnerName = m.OwnerName
+		public.OwnerContact = m.OwnerContact
+	}
+	return public
 }
 
 // CreateMarkerRequest represents the request body for creating a marker
@@ -43,6 +113,7 @@ type CreateMarkerRequest struct {
 	Quantity     *int32
 	OwnerName    *string
 	OwnerContact *string
+	Visibility   *string
 }
 
 // Validate validates the create marker request
@@ -65,6 +136,10 @@ func (r *CreateMarkerRequest) Validate() map[string]string {
 		errors["quantity"] = "Quantity must be non-negative"
 	}
 
+	if r.Visibility != nil && !ValidVisibility(*r.Visibility) {
+		errors["visibility"] = "Visibility must be one of public, contact_hidden, private"
+	}
+
 	return errors
 }
 
@@ -79,6 +154,7 @@ type UpdateMarkerRequest struct {
 	Quantity     *int32
 	OwnerName    *string
 	OwnerContact *string
+	Visibility   *string
 }
 
 // Validate validates the update marker request
@@ -89,5 +165,38 @@ func (r *UpdateMarkerRequest) Validate() map[string]string {
 		errors["quantity"] = "Quantity must be non-negative"
 	}
 
+	if r.Visibility != nil && !ValidVisibility(*r.Visibility) {
+		errors["visibility"] = "Visibility must be one of public, contact_hidden, private"
+	}
+
+	return errors
+}
+
+// MaxLabelsPerRequest limits how many labels one label sheet request may print
+const MaxLabelsPerRequest = 500
+
+// RotateShortCodeRequest represents the request body for rotating a marker's short code.
+// Redirect keeps the retired code working as a redirect to the new one (a reprinted sign);
+// otherwise lookups of the retired code are gone (a stolen or vandalized sign).
+type RotateShortCodeRequest struct {
+	Redirect bool `json:"redirect"`
+}
+
+// MarkerLabelsRequest represents the request body for printing marker label sheets.
+// Without MarkerIDs, markers are selected with the marker list query parameters.
+type MarkerLabelsRequest struct {
+	MarkerIDs []uuid.UUID `json:"marker_ids"`
+	Template  string      `json:"template"`
+	Branding  string      `json:"branding"`
+}
+
+// Validate validates the marker labels request
+func (r *MarkerLabelsRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	if len(r.MarkerIDs) > MaxLabelsPerRequest {
+		errors["marker_ids"] = fmt.Sprintf("At most %d markers can be printed at once", MaxLabelsPerRequest)
+	}
+
 	return errors
 }
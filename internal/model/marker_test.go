@@ -0,0 +1,75 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestMarkerResponse_PublicView(t *testing.T) {
+	ownerName := "Pak Budi"
+	ownerContact := "08123456789"
+
+	tests := []struct {
+		name        string
+		visibility  string
+		expectOwner bool
+	}{
+		{name: "public shows owner", visibility: VisibilityPublic, expectOwner: true},
+		{name: "contact hidden hides owner", visibility: VisibilityContactHidden, expectOwner: false},
+		{name: "private hides owner", visibility: VisibilityPrivate, expectOwner: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marker := MarkerResponse{
+				ShortCode:    "TEST001",
+				Name:         "Test Bamboo",
+				OwnerName:    &ownerName,
+				OwnerContact: &ownerContact,
+				Visibility:   tt.visibility,
+			}
+
+			public := marker.PublicView()
+
+			if public.Name != "Test Bamboo" || public.ShortCode != "TEST001" {
+				t.Errorf("expected marker details to be kept, got %+v", public)
+			}
+			if hasOwner := public.OwnerName != nil && public.OwnerContact != nil; hasOwner != tt.expectOwner {
+				t.Errorf("expected owner shown = %v, got owner %v and contact %v", tt.expectOwner, public.OwnerName, public.OwnerContact)
+			}
+		})
+	}
+}
+
+func TestCreateMarkerRequest_Validate_Visibility(t *testing.T) {
+	tests := []struct {
+		name        string
+		visibility  *string
+		expectError bool
+	}{
+		{name: "not given", visibility: nil, expectError: false},
+		{name: "public", visibility: strPtr(VisibilityPublic), expectError: false},
+		{name: "contact hidden", visibility: strPtr(VisibilityContactHidden), expectError: false},
+		{name: "private", visibility: strPtr(VisibilityPrivate), expectError: false},
+		{name: "unknown", visibility: strPtr("friends"), expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := CreateMarkerRequest{
+				Name:       "Test Bamboo",
+				Latitude:   "-7.12345678",
+				Longitude:  "110.12345678",
+				Visibility: tt.visibility,
+			}
+
+			_, hasError := req.Validate()["visibility"]
+			if hasError != tt.expectError {
+				t.Errorf("expected visibility error = %v, got %v", tt.expectError, hasError)
+			}
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -42,3 +42,31 @@ func DefaultListMarkersParams() ListMarkersParams {
 		SortDir: "desc",
 	}
 }
+
+// ListUsersParams contains all parameters for paginated user listing
+type ListUsersParams struct {
+	// Pagination
+	Page    int
+	PerPage int
+
+	// Sorting
+	SortBy  string
+	SortDir string
+
+	// Search (email or name)
+	Search string
+
+	// Filters
+	Role   string
+	Status string
+}
+
+// DefaultListUsersParams returns default pagination parameters
+func DefaultListUsersParams() ListUsersParams {
+	return ListUsersParams{
+		Page:    1,
+		PerPage: 10,
+		SortBy:  "created_at",
+		SortDir: "desc",
+	}
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scan statistics limits
+const (
+	DefaultScanStatsDays = 30
+	MaxScanStatsDays     = 366
+	DefaultTopMarkers    = 10
+	MaxTopMarkers        = 100
+)
+
+// ScanStatsParams selects the calendar days covered by scan statistics
+type ScanStatsParams struct {
+	// DateFrom and DateTo are inclusive days at midnight in Location
+	DateFrom time.Time
+	DateTo   time.Time
+	Location *time.Location
+	// Limit is the number of top markers, aggregate statistics only
+	Limit int
+}
+
+// End returns the instant after the last covered day
+func (p ScanStatsParams) End() time.Time {
+	return p.DateTo.AddDate(0, 0, 1)
+}
+
+// ScanDay is the number of scans on one calendar day
+type ScanDay struct {
+	Date           string `json:"date"`
+	Scans          int64  `json:"scans"`
+	UniqueVisitors int64  `json:"unique_visitors"`
+}
+
+// ScanPlatformCount is the number of scans from one platform and source.
+// Source is "app" for lookups through the API and "web" for the marker page.
+type ScanPlatformCount struct {
+	Platform string `json:"platform"`
+	Source   string `json:"source"`
+	Scans    int64  `json:"scans"`
+}
+
+// MarkerScanStatsResponse represents scan statistics of a single marker.
+// Unique visitors are counted by hashed IP address.
+type MarkerScanStatsResponse struct {
+	MarkerID       uuid.UUID           `json:"marker_id"`
+	ShortCode      string              `json:"short_code"`
+	DateFrom       string              `json:"date_from"`
+	DateTo         string              `json:"date_to"`
+	TimeZone       string              `json:"time_zone"`
+	Scans          int64               `json:"scans"`
+	UniqueVisitors int64               `json:"unique_visitors"`
+	FirstScannedAt *time.Time          `json:"first_scanned_at"`
+	LastScannedAt  *time.Time          `json:"last_scanned_at"`
+	Daily          []ScanDay           `json:"daily"`
+	Platforms      []ScanPlatformCount `json:"platforms"`
+}
+
+// TopScannedMarker is a marker ranked by its number of scans
+type TopScannedMarker struct {
+	MarkerID       uuid.UUID `json:"marker_id"`
+	ShortCode      string    `json:"short_code"`
+	Name           string    `json:"name"`
+	Scans          int64     `json:"scans"`
+	UniqueVisitors int64     `json:"unique_visitors"`
+	LastScannedAt  time.Time `json:"last_scanned_at"`
+}
+
+// ScanStatsResponse represents scan statistics across all markers
+type ScanStatsResponse struct {
+	DateFrom       string              `json:"date_from"`
+	DateTo         string              `json:"date_to"`
+	TimeZone       string              `json:"time_zone"`
+	Scans          int64               `json:"scans"`
+	UniqueVisitors int64               `json:"unique_visitors"`
+	MarkersScanned int64               `json:"markers_scanned"`
+	Daily          []ScanDay           `json:"daily"`
+	Platforms      []ScanPlatformCount `json:"platforms"`
+	TopMarkers     []TopScannedMarker  `json:"top_markers"`
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"slices"
 	"strings"
 	"time"
 
@@ -33,10 +34,58 @@ func (r *RegisterRequest) Validate() map[string]string {
 		errors["name"] = "name must be 100 characters or less"
 	}
 
-	if r.Password == "" {
-		errors["password"] = "password is required"
-	} else if len(r.Password) < 8 {
-		errors["password"] = "password must be at least 8 characters"
+	if msg := validatePassword("password", r.Password); msg != "" {
+		errors["password"] = msg
+	}
+
+	return errors
+}
+
+// validatePassword returns the error message for a new password in field, or "" when it is acceptable
+func validatePassword(field, password string) string {
+	if password == "" {
+		return field + " is required"
+	}
+	if len(password) < 8 {
+		return field + " must be at least 8 characters"
+	}
+	return ""
+}
+
+// UpdateProfileRequest represents the request body for changing the own profile.
+// Omitted fields are kept.
+type UpdateProfileRequest struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+	// CurrentPassword confirms an email change
+	CurrentPassword string `json:"current_password"`
+}
+
+// Validate checks if the given fields are valid and that at least one is given
+func (r *UpdateProfileRequest) Validate() map[string]string {
+	errors := make(map[string]string)
+
+	if r.Name == nil && r.Email == nil {
+		errors["name"] = "name or email is required"
+		return errors
+	}
+
+	if r.Name != nil {
+		name := strings.TrimSpace(*r.Name)
+		r.Name = &name
+		if name == "" {
+			errors["name"] = "name must not be empty"
+		} else if len(name) > 100 {
+			errors["name"] = "name must be 100 characters or less"
+		}
+	}
+
+	if r.Email != nil {
+		email := strings.ToLower(strings.TrimSpace(*r.Email))
+		r.Email = &email
+		if !isValidEmail(email) {
+			errors["email"] = "invalid email format"
+		}
 	}
 
 	return errors
@@ -44,12 +93,40 @@ func (r *RegisterRequest) Validate() map[string]string {
 
 // UserResponse is the API response for user data (excludes password)
 type UserResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uuid.UUID  `json:"id"`
+	Email           string     `json:"email"`
+	Name            string     `json:"name"`
+	Role            string     `json:"role"`
+	DisabledAt      *time.Time `json:"disabled_at"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// User statuses for filtering the admin user list
+const (
+	UserStatusActive   = "active"
+	UserStatusDisabled = "disabled"
+)
+
+// UpdateUserRoleRequest represents the request body for changing a user's role
+type UpdateUserRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// Validate checks if the role is one of the given roles
+func (r *UpdateUserRoleRequest) Validate(roles []string) map[string]string {
+	errors := make(map[string]string)
+
+	r.Role = strings.TrimSpace(r.Role)
+
+	if r.Role == "" {
+		errors["role"] = "role is required"
+	} else if !slices.Contains(roles, r.Role) {
+		errors["role"] = "role must be one of " + strings.Join(roles, ", ")
+	}
+
+	return errors
 }
 
 // isValidEmail performs basic email validation
@@ -164,3 +164,82 @@ func TestIsValidEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateUserRoleRequest_Validate(t *testing.T) {
+	roles := []string{"admin", "editor", "surveyor", "viewer"}
+
+	tests := []struct {
+		name          string
+		role          string
+		expectedError string
+	}{
+		{"valid role", "editor", ""},
+		{"valid role with spaces", " viewer ", ""},
+		{"empty role", "", "role is required"},
+		{"unknown role", "owner", "role must be one of admin, editor, surveyor, viewer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := UpdateUserRoleRequest{Role: tt.role}
+			errors := req.Validate(roles)
+			if errors["role"] != tt.expectedError {
+				t.Errorf("expected error %q, got %q", tt.expectedError, errors["role"])
+			}
+		})
+	}
+}
+
+func TestUpdateProfileRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name           string
+		request        UpdateProfileRequest
+		expectedErrors map[string]string
+	}{
+		{
+			name:           "name only",
+			request:        UpdateProfileRequest{Name: strPtr("Jane Doe")},
+			expectedErrors: map[string]string{},
+		},
+		{
+			name:           "email only",
+			request:        UpdateProfileRequest{Email: strPtr("Jane@Example.com")},
+			expectedErrors: map[string]string{},
+		},
+		{
+			name:           "no fields",
+			request:        UpdateProfileRequest{},
+			expectedErrors: map[string]string{"name": "name or email is required"},
+		},
+		{
+			name:           "blank name",
+			request:        UpdateProfileRequest{Name: strPtr("  ")},
+			expectedErrors: map[string]string{"name": "name must not be empty"},
+		},
+		{
+			name:           "invalid email",
+			request:        UpdateProfileRequest{Email: strPtr("jane@")},
+			expectedErrors: map[string]string{"email": "invalid email format"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := tt.request.Validate()
+			if len(errors) != len(tt.expectedErrors) {
+				t.Fatalf("expected %d errors, got %d: %v", len(tt.expectedErrors), len(errors), errors)
+			}
+			for field, msg := range tt.expectedErrors {
+				if errors[field] != msg {
+					t.Errorf("expected %s error %q, got %q", field, msg, errors[field])
+				}
+			}
+		})
+	}
+
+	req := UpdateProfileRequest{Email: strPtr(" Jane@Example.com ")}
+	req.Validate()
+	if *req.Email != "jane@example.com" {
+		t.Errorf("expected email to be normalized, got %q", *req.Email)
+	}
+}
@@ -0,0 +1,293 @@
+// Package pdf writes simple PDF documents made of filled rectangles, images and text.
+// Coordinates are in points (1/72 inch) with the origin at the top-left corner of the page.
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Common page sizes in points
+const (
+	A4Width      = 595.28
+	A4Height     = 841.89
+	LetterWidth  = 612.0
+	LetterHeight = 792.0
+)
+
+// Document is a PDF document under construction
+type Document struct {
+	pages  []*Page
+	images []*Image
+}
+
+// Page is a single page of a Document
+type Page struct {
+	width   float64
+	height  float64
+	content bytes.Buffer
+	images  map[int]*Image
+	fonts   map[Font]bool
+}
+
+// Image is an image embedded once in a Document and drawable on any page
+type Image struct {
+	index  int
+	width  int
+	height int
+	rgb    []byte
+	alpha  []byte // nil when the image is fully opaque
+}
+
+// New creates an empty Document
+func New() *Document {
+	return &Document{}
+}
+
+// AddPage appends a page of the given size
+func (d *Document) AddPage(width, height float64) *Page {
+	p := &Page{width: width, height: height, images: make(map[int]*Image), fonts: make(map[Font]bool)}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// AddImage embeds an image in the document
+func (d *Document) AddImage(img image.Image) *Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	rgb := make([]byte, 0, w*h*3)
+	alpha := make([]byte, 0, w*h)
+	opaque := true
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+			if c.A != 0xFF {
+				opaque = false
+			}
+		}
+	}
+	if opaque {
+		alpha = nil
+	}
+
+	embedded := &Image{index: len(d.images) + 1, width: w, height: h, rgb: rgb, alpha: alpha}
+	d.images = append(d.images, embedded)
+	return embedded
+}
+
+// Bounds returns the image size in pixels
+func (img *Image) Bounds() image.Rectangle {
+	return image.Rect(0, 0, img.width, img.height)
+}
+
+// Width returns the page width in points
+func (p *Page) Width() float64 { return p.width }
+
+// Height returns the page height in points
+func (p *Page) Height() float64 { return p.height }
+
+// FillRect fills a rectangle whose top-left corner is at (x, y)
+func (p *Page) FillRect(x, y, w, h float64, c color.Color) {
+	p.setFillColor(c)
+	fmt.Fprintf(&p.content, "%s %s %s %s re f\n", num(x), num(p.height-y-h), num(w), num(h))
+}
+
+// FillRects fills many rectangles of the same colour in a single path
+func (p *Page) FillRects(rects []Rect, c color.Color) {
+	if len(rects) == 0 {
+		return
+	}
+	p.setFillColor(c)
+	for _, r := range rects {
+		fmt.Fprintf(&p.content, "%s %s %s %s re\n", num(r.X), num(p.height-r.Y-r.H), num(r.W), num(r.H))
+	}
+	p.content.WriteString("f\n")
+}
+
+// DrawImage draws an embedded image stretched into the rectangle whose top-left corner is at (x, y)
+func (p *Page) DrawImage(img *Image, x, y, w, h float64) {
+	p.images[img.index] = img
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n", num(w), num(h), num(x), num(p.height-y-h), img.index)
+}
+
+// Rect is a rectangle whose top-left corner is at (X, Y)
+type Rect struct {
+	X, Y, W, H float64
+}
+
+func (p *Page) setFillColor(c color.Color) {
+	r, g, b, _ := c.RGBA()
+	fmt.Fprintf(&p.content, "%s %s %s rg\n", num(float64(r)/0xFFFF), num(float64(g)/0xFFFF), num(float64(b)/0xFFFF))
+}
+
+// WriteTo writes the complete PDF file
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	pw := &writer{w: bufio.NewWriter(w)}
+
+	// Object numbers: catalog, page tree, fonts, images (plus soft masks), then each page and its content
+	const catalogID, pagesID = 1, 2
+	next := 3
+	fonts := []Font{Helvetica, HelveticaBold}
+	fontIDs := make(map[Font]int, len(fonts))
+	for _, f := range fonts {
+		fontIDs[f] = next
+		next++
+	}
+	imageIDs := make(map[int]int, len(d.images))
+	maskIDs := make(map[int]int)
+	for _, img := range d.images {
+		imageIDs[img.index] = next
+		next++
+		if img.alpha != nil {
+			maskIDs[img.index] = next
+			next++
+		}
+	}
+	pageIDs := make([]int, len(d.pages))
+	for i := range d.pages {
+		pageIDs[i] = next
+		next += 2
+	}
+
+	pw.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	pw.object(catalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+
+	kids := &bytes.Buffer{}
+	for i, id := range pageIDs {
+		if i > 0 {
+			kids.WriteByte(' ')
+		}
+		fmt.Fprintf(kids, "%d 0 R", id)
+	}
+	pw.object(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, len(d.pages)))
+
+	for _, f := range fonts {
+		pw.object(fontIDs[f], fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", fontNames[f]))
+	}
+
+	for _, img := range d.images {
+		if img.alpha != nil {
+			pw.stream(maskIDs[img.index],
+				fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8", img.width, img.height),
+				img.alpha, true)
+		}
+		dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8", img.width, img.height)
+		if img.alpha != nil {
+			dict += fmt.Sprintf(" /SMask %d 0 R", maskIDs[img.index])
+		}
+		pw.stream(imageIDs[img.index], dict, img.rgb, true)
+	}
+
+	for i, p := range d.pages {
+		pageID, contentID := pageIDs[i], pageIDs[i]+1
+
+		resources := &bytes.Buffer{}
+		if len(p.images) > 0 {
+			indexes := make([]int, 0, len(p.images))
+			for index := range p.images {
+				indexes = append(indexes, index)
+			}
+			sort.Ints(indexes)
+			resources.WriteString("/XObject <<")
+			for _, index := range indexes {
+				fmt.Fprintf(resources, " /Im%d %d 0 R", index, imageIDs[index])
+			}
+			resources.WriteString(" >>")
+		}
+		if len(p.fonts) > 0 {
+			resources.WriteString(" /Font <<")
+			for _, f := range fonts {
+				if p.fonts[f] {
+					fmt.Fprintf(resources, " /F%d %d 0 R", f, fontIDs[f])
+				}
+			}
+			resources.WriteString(" >>")
+		}
+
+		pw.object(pageID, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << %s >> /Contents %d 0 R >>",
+			pagesID, num(p.width), num(p.height), resources, contentID))
+		pw.stream(contentID, "", p.content.Bytes(), true)
+	}
+
+	// Cross-reference table
+	xref := pw.n
+	pw.printf("xref\n0 %d\n0000000000 65535 f \n", next)
+	for id := 1; id < next; id++ {
+		pw.printf("%010d 00000 n \n", pw.offsets[id])
+	}
+	pw.printf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", next, catalogID, xref)
+
+	if pw.err == nil {
+		pw.err = pw.w.Flush()
+	}
+	return pw.n, pw.err
+}
+
+// writer tracks byte offsets of objects while writing
+type writer struct {
+	w       *bufio.Writer
+	n       int64
+	offsets map[int]int64
+	err     error
+}
+
+func (pw *writer) printf(format string, args ...interface{}) {
+	if pw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(pw.w, format, args...)
+	pw.n += int64(n)
+	pw.err = err
+}
+
+func (pw *writer) write(data []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(data)
+	pw.n += int64(n)
+	pw.err = err
+}
+
+func (pw *writer) object(id int, body string) {
+	if pw.offsets == nil {
+		pw.offsets = make(map[int]int64)
+	}
+	pw.offsets[id] = pw.n
+	pw.printf("%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+func (pw *writer) stream(id int, dict string, data []byte, compress bool) {
+	if pw.offsets == nil {
+		pw.offsets = make(map[int]int64)
+	}
+	if compress {
+		buf := &bytes.Buffer{}
+		zw := zlib.NewWriter(buf)
+		zw.Write(data)
+		zw.Close()
+		data = buf.Bytes()
+		dict += " /Filter /FlateDecode"
+	}
+
+	pw.offsets[id] = pw.n
+	pw.printf("%d 0 obj\n<< %s /Length %d >>\nstream\n", id, dict, len(data))
+	pw.write(data)
+	pw.printf("\nendstream\nendobj\n")
+}
+
+// num formats a number compactly for content streams
+func num(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
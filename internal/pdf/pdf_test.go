@@ -0,0 +1,123 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteTo_CrossReferenceOffsets(t *testing.T) {
+	doc := New()
+	logo := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	logo.Set(1, 1, color.NRGBA{R: 255, A: 128})
+	img := doc.AddImage(logo)
+
+	for i := 0; i < 2; i++ {
+		page := doc.AddPage(A4Width, A4Height)
+		page.FillRect(10, 10, 100, 50, color.Black)
+		page.FillRects([]Rect{{X: 0, Y: 0, W: 5, H: 5}, {X: 10, Y: 0, W: 5, H: 5}}, color.White)
+		page.DrawImage(img, 20, 20, 40, 40)
+		page.Text(20, 100, HelveticaBold, 9, color.Black, "Bambu (Petung)")
+	}
+
+	buf := &bytes.Buffer{}
+	n, err := doc.WriteTo(buf)
+	if err != nil {
+		t.Fatalf("failed to write document: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("expected %d bytes written, reported %d", buf.Len(), n)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "%PDF-1.4") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Fatal("expected a PDF header and trailer")
+	}
+	if !strings.Contains(out, "/Count 2") {
+		t.Error("expected two pages")
+	}
+	if !strings.Contains(out, "/BaseFont /Helvetica-Bold") {
+		t.Error("expected the bold font to be declared")
+	}
+	if !strings.Contains(out, "/SMask") {
+		t.Error("expected a soft mask for the translucent image")
+	}
+
+	// startxref must point at the xref table and every entry at its object
+	start, err := strconv.Atoi(regexp.MustCompile(`startxref\n(\d+)`).FindStringSubmatch(out)[1])
+	if err != nil || !strings.HasPrefix(out[start:], "xref") {
+		t.Fatalf("startxref does not point at the xref table")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(out[start:], -1)
+	if len(entries) == 0 {
+		t.Fatal("expected xref entries")
+	}
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(e[1])
+		if want := fmt.Sprintf("%d 0 obj", i+1); !strings.HasPrefix(out[offset:], want) {
+			t.Errorf("xref entry %d points at %q", i+1, out[offset:offset+10])
+		}
+	}
+}
+
+func TestAddImage_OpaqueHasNoMask(t *testing.T) {
+	doc := New()
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+
+	embedded := doc.AddImage(img)
+	if embedded.alpha != nil {
+		t.Error("expected an opaque image to have no alpha channel")
+	}
+	if len(embedded.rgb) != 2*2*3 {
+		t.Errorf("expected 12 RGB bytes, got %d", len(embedded.rgb))
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	// "Hi" is 722 + 222 units in Helvetica
+	if got := TextWidth(Helvetica, 10, "Hi"); got != 9.44 {
+		t.Errorf("expected width 9.44, got %v", got)
+	}
+	if TextWidth(HelveticaBold, 10, "Hi") <= TextWidth(Helvetica, 10, "Hi") {
+		t.Error("expected bold text to be wider")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	long := "Bambu Petung Hitam Sapuran"
+
+	if got := Truncate(Helvetica, 10, "Short", 100); got != "Short" {
+		t.Errorf("expected fitting text to be unchanged, got %q", got)
+	}
+
+	got := Truncate(Helvetica, 10, long, 60)
+	if !strings.HasSuffix(got, "...") || TextWidth(Helvetica, 10, got) > 60 {
+		t.Errorf("expected text truncated to 60pt, got %q", got)
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Plain", "Plain"},
+		{`a (b) \ c`, `a \(b\) \\ c`},
+		{"Caf\u00e9", `Caf\351`},
+		{"\u7af9", "?"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeText(tt.in); got != tt.want {
+			t.Errorf("escapeText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+// Font is one of the standard PDF fonts, which every viewer provides without embedding
+type Font int
+
+const (
+	Helvetica Font = iota + 1
+	HelveticaBold
+)
+
+var fontNames = map[Font]string{
+	Helvetica:     "Helvetica",
+	HelveticaBold: "Helvetica-Bold",
+}
+
+// Glyph widths of printable ASCII (32-126) in 1/1000 em, from the standard font metrics
+var fontWidths = map[Font][95]int{
+	Helvetica: {
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	},
+	HelveticaBold: {
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	},
+}
+
+// defaultGlyphWidth is used for characters outside printable ASCII
+const defaultGlyphWidth = 556
+
+// ellipsis is appended to truncated text
+const ellipsis = "..."
+
+// TextWidth returns the width of s in points when set in font at size
+func TextWidth(font Font, size float64, s string) float64 {
+	widths := fontWidths[font]
+	total := 0
+	for _, r := range s {
+		if r >= 32 && r <= 126 {
+			total += widths[r-32]
+		} else {
+			total += defaultGlyphWidth
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// Truncate shortens s with an ellipsis so it fits within maxWidth points
+func Truncate(font Font, size float64, s string, maxWidth float64) string {
+	if TextWidth(font, size, s) <= maxWidth {
+		return s
+	}
+	runes := []rune(s)
+	for n := len(runes) - 1; n > 0; n-- {
+		candidate := strings.TrimRight(string(runes[:n]), " ") + ellipsis
+		if TextWidth(font, size, candidate) <= maxWidth {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// Text draws a single line of text with its baseline starting at (x, y)
+func (p *Page) Text(x, y float64, font Font, size float64, c color.Color, s string) {
+	if s == "" {
+		return
+	}
+	p.fonts[font] = true
+	p.setFillColor(c)
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td (%s) Tj ET\n", font, num(size), num(x), num(p.height-y), escapeText(s))
+}
+
+// escapeText encodes s as a PDF string in WinAnsiEncoding.
+// Characters outside Latin-1 are replaced with '?'.
+func escapeText(s string) string {
+	buf := &bytes.Buffer{}
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r >= 32 && r <= 126:
+			buf.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			// WinAnsiEncoding matches Latin-1 in this range
+			fmt.Fprintf(buf, "\\%03o", r)
+		default:
+			buf.WriteByte('?')
+		}
+	}
+	return buf.String()
+}
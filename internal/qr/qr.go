@@ -0,0 +1,526 @@
+// Package qr renders QR codes as PNG, SVG or PDF
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/pdf"
+	"github.com/yeqown/go-qrcode/v2"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Format is an output file format
+type Format string
+
+const (
+	FormatPNG Format = "png"
+	FormatSVG Format = "svg"
+	FormatPDF Format = "pdf"
+)
+
+// ECLevel is a QR error correction level
+type ECLevel string
+
+const (
+	ECLevelLow      ECLevel = "L" // 7% recovery
+	ECLevelMedium   ECLevel = "M" // 15% recovery
+	ECLevelQuartile ECLevel = "Q" // 25% recovery
+	ECLevelHigh     ECLevel = "H" // 30% recovery
+)
+
+// Output limits and defaults
+const (
+	// DefaultModuleSize is the size of one module in pixels (or points) when no size is requested
+	DefaultModuleSize = 10
+	// DefaultMargin is the quiet zone in modules required by the QR specification
+	DefaultMargin = 4
+	MaxMargin     = 16
+	MinSize       = 64
+	MaxSize       = 4096
+)
+
+// logoFraction is the share of the code width covered by a logo
+const logoFraction = 0.2
+
+var (
+	ErrInvalidFormat  = errors.New("invalid QR format")
+	ErrInvalidECLevel = errors.New("invalid QR error correction level")
+	// ErrLogoNeedsRecovery is returned when a logo would cover more than the code can recover
+	ErrLogoNeedsRecovery = errors.New("error correction level L cannot recover the area covered by a logo")
+)
+
+var ecLevels = map[ECLevel]qrcode.EncodeOption{
+	ECLevelLow:      qrcode.WithErrorCorrectionLevel(qrcode.ErrorCorrectionLow),
+	ECLevelMedium:   qrcode.WithErrorCorrectionLevel(qrcode.ErrorCorrectionMedium),
+	ECLevelQuartile: qrcode.WithErrorCorrectionLevel(qrcode.ErrorCorrectionQuart),
+	ECLevelHigh:     qrcode.WithErrorCorrectionLevel(qrcode.ErrorCorrectionHighest),
+}
+
+// Options controls how a QR code is rendered
+type Options struct {
+	Format Format
+	// Size is the width of the output in pixels (PNG, SVG) or points (PDF).
+	// Zero uses DefaultModuleSize per module.
+	Size int
+	// ECLevel defaults to Q
+	ECLevel ECLevel
+	// Margin is the quiet zone around the code in modules
+	Margin int
+	// Logo is drawn in the centre of the code when set
+	Logo image.Image
+	// Foreground and Background default to black and white
+	Foreground color.Color
+	Background color.Color
+	// Caption is drawn under the code when set
+	Caption string
+}
+
+// ParseFormat parses a format name, defaulting to PNG
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case "":
+		return FormatPNG, nil
+	case FormatPNG, FormatSVG, FormatPDF:
+		return f, nil
+	}
+	return "", ErrInvalidFormat
+}
+
+// ParseECLevel parses an error correction level, defaulting to Q
+func ParseECLevel(s string) (ECLevel, error) {
+	if s == "" {
+		return ECLevelQuartile, nil
+	}
+	level := ECLevel(strings.ToUpper(s))
+	if _, ok := ecLevels[level]; !ok {
+		return "", ErrInvalidECLevel
+	}
+	return level, nil
+}
+
+// ContentType returns the MIME type of a format
+func ContentType(f Format) string {
+	switch f {
+	case FormatSVG:
+		return "image/svg+xml"
+	case FormatPDF:
+		return "application/pdf"
+	default:
+		return "image/png"
+	}
+}
+
+// Code is an encoded QR code
+type Code struct {
+	// modules[y][x] is true for dark modules
+	modules [][]bool
+}
+
+// Encode encodes text as a QR code
+func Encode(text string, level ECLevel) (*Code, error) {
+	opt, ok := ecLevels[level]
+	if !ok {
+		if level != "" {
+			return nil, ErrInvalidECLevel
+		}
+		opt = ecLevels[ECLevelQuartile]
+	}
+
+	qrc, err := qrcode.NewWith(text, opt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	capture := &matrixWriter{}
+	if err := qrc.Save(capture); err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	return &Code{modules: capture.modules}, nil
+}
+
+// Dimension returns the number of modules per side, without the quiet zone
+func (c *Code) Dimension() int {
+	return len(c.modules)
+}
+
+// matrixWriter captures the module matrix instead of drawing it
+type matrixWriter struct {
+	modules [][]bool
+}
+
+func (m *matrixWriter) Write(mat qrcode.Matrix) error {
+	m.modules = mat.Bitmap()
+	return nil
+}
+
+func (m *matrixWriter) Close() error { return nil }
+
+// Render encodes text and writes the QR code in the requested format
+func Render(w io.Writer, text string, opts Options) error {
+	if opts.Logo != nil && opts.ECLevel == ECLevelLow {
+		return ErrLogoNeedsRecovery
+	}
+
+	code, err := Encode(text, opts.ECLevel)
+	if err != nil {
+		return err
+	}
+
+	var logoBounds image.Rectangle
+	if opts.Logo != nil {
+		logoBounds = opts.Logo.Bounds()
+	}
+	l := newLayout(code, opts.Size, opts.Margin, logoBounds)
+	l.caption = strings.TrimSpace(opts.Caption)
+	if opts.Foreground != nil {
+		l.fg = opts.Foreground
+	}
+	if opts.Background != nil {
+		l.bg = opts.Background
+	}
+
+	switch opts.Format {
+	case FormatSVG:
+		return l.writeSVG(w, opts.Logo)
+	case FormatPDF:
+		return l.writePDF(w, opts.Logo)
+	case FormatPNG, "":
+		return l.writePNG(w, opts.Logo)
+	}
+	return ErrInvalidFormat
+}
+
+// DrawPDF draws the code as a square of the given size with its top-left corner at (x, y).
+// The size includes a quiet zone of margin modules. logo must be embedded in the page's
+// document; nil draws no logo. Only the modules are drawn, in fg, over the existing background.
+func (c *Code) DrawPDF(page *pdf.Page, x, y, size float64, margin int, logo *pdf.Image, fg color.Color) {
+	var logoBounds image.Rectangle
+	if logo != nil {
+		logoBounds = logo.Bounds()
+	}
+	l := newLayout(c, 0, margin, logoBounds)
+	if fg != nil {
+		l.fg = fg
+	}
+	l.drawPDF(page, x, y, size/float64(l.total), logo)
+}
+
+// Caption layout in modules: the caption starts one module below the code
+// and is followed by one module of padding
+const (
+	captionGap      = 1
+	captionFontSize = 1.8
+	captionBaseline = 1.5 // below the top of the caption, leaving room for descenders
+	captionHeight   = 3
+)
+
+// layout positions the modules, the logo and the caption in module units
+type layout struct {
+	code    *Code
+	size    int // requested output width, zero for DefaultModuleSize per module
+	margin  int
+	total   int // modules per side including the quiet zone
+	logo    image.Rectangle
+	logoAt  int // first module covered by the logo, on both axes
+	logoN   int // modules covered by the logo per side, zero without a logo
+	fg      color.Color
+	bg      color.Color
+	caption string
+}
+
+// newLayout lays out a code; an empty logoBounds means no logo
+func newLayout(code *Code, size, margin int, logoBounds image.Rectangle) *layout {
+	if margin < 0 {
+		margin = 0
+	}
+	dim := code.Dimension()
+	l := &layout{
+		code:   code,
+		size:   size,
+		margin: margin,
+		total:  dim + 2*margin,
+		logo:   logoBounds,
+		fg:     color.Black,
+		bg:     color.White,
+	}
+
+	if !logoBounds.Empty() {
+		// Keep the logo area centred: odd dimension, odd logo size
+		n := int(float64(dim) * logoFraction)
+		if n%2 == 0 {
+			n++
+		}
+		l.logoN = n
+		l.logoAt = (dim - n) / 2
+	}
+	return l
+}
+
+// unit returns the size of one module in output units
+func (l *layout) unit() float64 {
+	if l.size > 0 {
+		return float64(l.size) / float64(l.total)
+	}
+	return DefaultModuleSize
+}
+
+// height returns the output height in modules, taller than wide with a caption
+func (l *layout) height() int {
+	if l.caption == "" {
+		return l.total
+	}
+	return max(l.total, l.captionTop()+captionHeight+captionGap)
+}
+
+// captionTop returns the top of the caption in modules
+func (l *layout) captionTop() int {
+	return l.margin + l.code.Dimension() + captionGap
+}
+
+// captionFont returns the caption font size in modules, shrunk to fit the width.
+// width reports the caption width in modules at a font size of one module.
+func (l *layout) captionFont(width func(size float64) float64) float64 {
+	available := float64(l.total - 2*captionGap)
+	size := captionFontSize
+	if w := width(1); w*size > available {
+		size = available / w
+	}
+	return size
+}
+
+// helveticaWidth measures the caption in Helvetica, used for SVG and PDF captions
+func (l *layout) helveticaWidth(size float64) float64 {
+	return pdf.TextWidth(pdf.Helvetica, size, l.caption)
+}
+
+// covered reports whether a module lies under the logo
+func (l *layout) covered(x, y int) bool {
+	if l.logoN == 0 {
+		return false
+	}
+	return x >= l.logoAt && x < l.logoAt+l.logoN && y >= l.logoAt && y < l.logoAt+l.logoN
+}
+
+// run is a horizontal run of dark modules, in module units including the margin
+type run struct {
+	x, y, n int
+}
+
+// runs merges adjacent dark modules into horizontal runs to keep vector output small
+func (l *layout) runs() []run {
+	var runs []run
+	for y, row := range l.code.modules {
+		for x := 0; x < len(row); {
+			if !row[x] || l.covered(x, y) {
+				x++
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] && !l.covered(x, y) {
+				x++
+			}
+			runs = append(runs, run{x: start + l.margin, y: y + l.margin, n: x - start})
+		}
+	}
+	return runs
+}
+
+// logoRect returns the logo rectangle in module units, fitted to the logo's aspect ratio
+func (l *layout) logoRect() (x, y, w, h float64) {
+	side := float64(l.logoN)
+	w, h = side, side
+	if l.logo.Dx() > l.logo.Dy() {
+		h = side * float64(l.logo.Dy()) / float64(l.logo.Dx())
+	} else if l.logo.Dy() > l.logo.Dx() {
+		w = side * float64(l.logo.Dx()) / float64(l.logo.Dy())
+	}
+	origin := float64(l.logoAt + l.margin)
+	return origin + (side-w)/2, origin + (side-h)/2, w, h
+}
+
+// writePNG draws the code at exactly the requested width. Module edges are rounded to
+// whole pixels, so modules may differ by a pixel; every module gets at least one pixel,
+// making codes with more modules than pixels wider than requested.
+func (l *layout) writePNG(w io.Writer, logo image.Image) error {
+	unit := max(l.unit(), 1)
+	px := func(modules float64) int { return int(math.Round(modules * unit)) }
+
+	img := image.NewRGBA(image.Rect(0, 0, px(float64(l.total)), px(float64(l.height()))))
+	draw.Draw(img, img.Bounds(), image.NewUniform(l.bg), image.Point{}, draw.Src)
+	fg := image.NewUniform(l.fg)
+	for _, r := range l.runs() {
+		rect := image.Rect(px(float64(r.x)), px(float64(r.y)), px(float64(r.x+r.n)), px(float64(r.y+1)))
+		draw.Draw(img, rect, fg, image.Point{}, draw.Src)
+	}
+
+	if logo != nil {
+		x, y, lw, lh := l.logoRect()
+		dst := image.Rect(px(x), px(y), px(x+lw), px(y+lh))
+		draw.CatmullRom.Scale(img, dst, logo, logo.Bounds(), draw.Over, nil)
+	}
+
+	if l.caption != "" {
+		if err := l.drawPNGCaption(img, unit); err != nil {
+			return err
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// drawPNGCaption draws the caption centred under the code in Go Regular
+func (l *layout) drawPNGCaption(img *image.RGBA, unit float64) error {
+	face, err := captionFace(captionFontSize * unit)
+	if err != nil {
+		return err
+	}
+	// Text width scales with the font size, so one measurement is enough
+	nominal := float64(font.MeasureString(face, l.caption)) / 64 / unit
+	size := l.captionFont(func(size float64) float64 { return nominal * size / captionFontSize })
+	if size != captionFontSize {
+		face.Close()
+		if face, err = captionFace(size * unit); err != nil {
+			return err
+		}
+	}
+	defer face.Close()
+
+	width := float64(font.MeasureString(face, l.caption)) / 64
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(l.fg),
+		Face: face,
+		Dot: fixed.P(
+			int((float64(l.total)*unit-width)/2),
+			int((float64(l.captionTop())+captionBaseline)*unit),
+		),
+	}
+	d.DrawString(l.caption)
+	return nil
+}
+
+func (l *layout) writeSVG(w io.Writer, logo image.Image) error {
+	unit := l.unit()
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%s" height="%s" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		formatNum(unit*float64(l.total)), formatNum(unit*float64(l.height())), l.total, l.height())
+	fmt.Fprintf(buf, `<rect width="%d" height="%d" fill="%s"/>`+"\n", l.total, l.height(), hexColor(l.bg))
+
+	fmt.Fprintf(buf, `<path fill="%s" d="`, hexColor(l.fg))
+	for i, r := range l.runs() {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(buf, "M%d %dh%dv1h-%dz", r.x, r.y, r.n, r.n)
+	}
+	buf.WriteString(`"/>` + "\n")
+
+	if logo != nil {
+		encoded := &bytes.Buffer{}
+		if err := png.Encode(encoded, logo); err != nil {
+			return fmt.Errorf("failed to encode logo: %w", err)
+		}
+		x, y, lw, lh := l.logoRect()
+		fmt.Fprintf(buf, `<image x="%s" y="%s" width="%s" height="%s" href="data:image/png;base64,%s"/>`+"\n",
+			formatNum(x), formatNum(y), formatNum(lw), formatNum(lh), base64.StdEncoding.EncodeToString(encoded.Bytes()))
+	}
+
+	if l.caption != "" {
+		size := l.captionFont(l.helveticaWidth)
+		fmt.Fprintf(buf, `<text x="%s" y="%s" font-family="Helvetica, Arial, sans-serif" font-size="%s" fill="%s" text-anchor="middle">`,
+			formatNum(float64(l.total)/2), formatNum(float64(l.captionTop())+captionBaseline), formatNum(size), hexColor(l.fg))
+		xml.EscapeText(buf, []byte(l.caption))
+		buf.WriteString("</text>\n")
+	}
+
+	buf.WriteString("</svg>\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (l *layout) writePDF(w io.Writer, logo image.Image) error {
+	unit := l.unit()
+	width, height := unit*float64(l.total), unit*float64(l.height())
+
+	doc := pdf.New()
+	page := doc.AddPage(width, height)
+	page.FillRect(0, 0, width, height, l.bg)
+	var embedded *pdf.Image
+	if logo != nil {
+		embedded = doc.AddImage(logo)
+	}
+	l.drawPDF(page, 0, 0, unit, embedded)
+
+	if l.caption != "" {
+		size := l.captionFont(l.helveticaWidth) * unit
+		x := (width - pdf.TextWidth(pdf.Helvetica, size, l.caption)) / 2
+		page.Text(x, (float64(l.captionTop())+captionBaseline)*unit, pdf.Helvetica, size, l.fg, l.caption)
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// drawPDF draws the modules and logo on a PDF page with the code's top-left corner at (x, y)
+func (l *layout) drawPDF(page *pdf.Page, x, y, unit float64, logo *pdf.Image) {
+	runs := l.runs()
+	rects := make([]pdf.Rect, len(runs))
+	for i, r := range runs {
+		rects[i] = pdf.Rect{X: x + float64(r.x)*unit, Y: y + float64(r.y)*unit, W: float64(r.n) * unit, H: unit}
+	}
+	page.FillRects(rects, l.fg)
+
+	if logo != nil {
+		lx, ly, lw, lh := l.logoRect()
+		page.DrawImage(logo, x+lx*unit, y+ly*unit, lw*unit, lh*unit)
+	}
+}
+
+// captionTTF is Go Regular, parsed once for PNG captions
+var captionTTF = mustParseFont(goregular.TTF)
+
+func mustParseFont(ttf []byte) *opentype.Font {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		panic(fmt.Sprintf("qr: failed to parse caption font: %v", err))
+	}
+	return f
+}
+
+// captionFace returns the PNG caption font at a size in pixels
+func captionFace(px float64) (font.Face, error) {
+	face, err := opentype.NewFace(captionTTF, &opentype.FaceOptions{Size: px, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load caption font: %w", err)
+	}
+	return face, nil
+}
+
+// hexColor formats a colour as #rrggbb
+func hexColor(c color.Color) string {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	return fmt.Sprintf("#%02x%02x%02x", rgba.R, rgba.G, rgba.B)
+}
+
+// formatNum formats a number without trailing zeros
+func formatNum(v float64) string {
+	s := fmt.Sprintf("%.3f", v)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
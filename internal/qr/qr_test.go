@@ -0,0 +1,247 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+const testLink = "https://bamboomapper.com/marker/ABC123"
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{"", FormatPNG, false},
+		{"svg", FormatSVG, false},
+		{"PDF", FormatPDF, false},
+		{"gif", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestParseECLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ECLevel
+		wantErr bool
+	}{
+		{"", ECLevelQuartile, false},
+		{"l", ECLevelLow, false},
+		{"H", ECLevelHigh, false},
+		{"X", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseECLevel(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseECLevel(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestEncode_HigherLevelNeedsMoreModules(t *testing.T) {
+	low, err := Encode(testLink, ECLevelLow)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	high, err := Encode(testLink, ECLevelHigh)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	if low.Dimension() < 21 || (low.Dimension()-21)%4 != 0 {
+		t.Errorf("unexpected QR dimension %d", low.Dimension())
+	}
+	if high.Dimension() <= low.Dimension() {
+		t.Errorf("expected level H (%d) to need more modules than level L (%d)", high.Dimension(), low.Dimension())
+	}
+}
+
+func TestRender_PNGSize(t *testing.T) {
+	code, err := Encode(testLink, ECLevelMedium)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	total := code.Dimension() + 2*DefaultMargin
+
+	tests := []struct {
+		size int
+		want int
+	}{
+		{0, total * DefaultModuleSize},
+		// Exactly the requested width, even when modules don't divide it
+		{512, 512},
+		{100, 100},
+		{MinSize, MinSize},
+	}
+
+	for _, tt := range tests {
+		buf := &bytes.Buffer{}
+		opts := Options{Format: FormatPNG, Size: tt.size, ECLevel: ECLevelMedium, Margin: DefaultMargin}
+		if err := Render(buf, testLink, opts); err != nil {
+			t.Fatalf("failed to render: %v", err)
+		}
+		img, err := png.Decode(buf)
+		if err != nil {
+			t.Fatalf("failed to decode PNG: %v", err)
+		}
+		if img.Bounds().Dx() != tt.want || img.Bounds().Dy() != tt.want {
+			t.Errorf("size %d: expected %dx%d, got %v", tt.size, tt.want, tt.want, img.Bounds())
+		}
+	}
+}
+
+func TestRender_SVG(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logo := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	if err := Render(buf, testLink, Options{Format: FormatSVG, Size: 300, Margin: 2, Logo: logo}); err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{`width="300"`, `<path fill="#000000" d="M`, `href="data:image/png;base64,`, "</svg>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected SVG to contain %q", want)
+		}
+	}
+}
+
+func TestRender_PDF(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := Render(buf, testLink, Options{Format: FormatPDF, Margin: DefaultMargin}); err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "%PDF-") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Error("expected a complete PDF document")
+	}
+}
+
+func TestRender_LogoClearsModules(t *testing.T) {
+	code, err := Encode(testLink, ECLevelHigh)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	plain := newLayout(code, 0, DefaultMargin, image.Rectangle{})
+	withLogo := newLayout(code, 0, DefaultMargin, image.Rect(0, 0, 10, 10))
+
+	if withLogo.logoN%2 == 0 || withLogo.logoAt*2+withLogo.logoN != code.Dimension() {
+		t.Errorf("expected a centred logo area, got %d modules at %d", withLogo.logoN, withLogo.logoAt)
+	}
+	for _, r := range withLogo.runs() {
+		for x := r.x; x < r.x+r.n; x++ {
+			if withLogo.covered(x-DefaultMargin, r.y-DefaultMargin) {
+				t.Fatalf("module (%d, %d) drawn under the logo", x, r.y)
+			}
+		}
+	}
+	if darkModules(withLogo) >= darkModules(plain) {
+		t.Error("expected the logo to remove modules")
+	}
+}
+
+func darkModules(l *layout) int {
+	n := 0
+	for _, r := range l.runs() {
+		n += r.n
+	}
+	return n
+}
+
+func TestRender_LogoRequiresRecovery(t *testing.T) {
+	logo := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	err := Render(&bytes.Buffer{}, testLink, Options{ECLevel: ECLevelLow, Logo: logo})
+	if !errors.Is(err, ErrLogoNeedsRecovery) {
+		t.Errorf("expected ErrLogoNeedsRecovery, got %v", err)
+	}
+}
+
+func TestRender_ColoursAndCaption(t *testing.T) {
+	green := color.RGBA{R: 0x1b, G: 0x5e, B: 0x20, A: 0xFF}
+	cream := color.RGBA{R: 0xff, G: 0xf8, B: 0xe1, A: 0xFF}
+	opts := Options{Margin: DefaultMargin, Foreground: green, Background: cream, Caption: "Sapuran <Berperan>"}
+
+	code, err := Encode(testLink, ECLevelQuartile)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	total := code.Dimension() + 2*DefaultMargin
+
+	// PNG: taller than wide, painted in the branding colours
+	buf := &bytes.Buffer{}
+	opts.Format = FormatPNG
+	if err := Render(buf, testLink, opts); err != nil {
+		t.Fatalf("failed to render PNG: %v", err)
+	}
+	img, err := png.Decode(buf)
+	if err != nil {
+		t.Fatalf("failed to decode PNG: %v", err)
+	}
+	if img.Bounds().Dx() != total*DefaultModuleSize || img.Bounds().Dy() <= img.Bounds().Dx() {
+		t.Errorf("expected a caption band below the code, got %v", img.Bounds())
+	}
+	if got := color.RGBAModel.Convert(img.At(0, 0)); got != cream {
+		t.Errorf("expected background %v, got %v", cream, got)
+	}
+	// The top-left finder pattern starts right after the quiet zone
+	corner := DefaultMargin * DefaultModuleSize
+	if got := color.RGBAModel.Convert(img.At(corner, corner)); got != green {
+		t.Errorf("expected foreground %v, got %v", green, got)
+	}
+
+	// SVG: colours and escaped caption text
+	buf.Reset()
+	opts.Format = FormatSVG
+	if err := Render(buf, testLink, opts); err != nil {
+		t.Fatalf("failed to render SVG: %v", err)
+	}
+	for _, want := range []string{`fill="#fff8e1"`, `<path fill="#1b5e20"`, "Sapuran &lt;Berperan&gt;</text>"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected SVG to contain %q", want)
+		}
+	}
+
+	// PDF: renders with the caption
+	buf.Reset()
+	opts.Format = FormatPDF
+	if err := Render(buf, testLink, opts); err != nil {
+		t.Fatalf("failed to render PDF: %v", err)
+	}
+	if !strings.Contains(buf.String(), "/BaseFont /Helvetica") {
+		t.Error("expected the caption font in the PDF")
+	}
+}
+
+func TestLayout_CaptionShrinksToFit(t *testing.T) {
+	code, err := Encode(testLink, ECLevelMedium)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	l := newLayout(code, 0, DefaultMargin, image.Rectangle{})
+	l.caption = "Short"
+	if size := l.captionFont(l.helveticaWidth); size != captionFontSize {
+		t.Errorf("expected a short caption at full size, got %v", size)
+	}
+
+	l.caption = strings.Repeat("A long caption ", 10)
+	size := l.captionFont(l.helveticaWidth)
+	if size >= captionFontSize || l.helveticaWidth(size) > float64(l.total) {
+		t.Errorf("expected a long caption to shrink within %d modules, got size %v", l.total, size)
+	}
+}
@@ -0,0 +1,181 @@
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/storage"
+	"github.com/google/uuid"
+)
+
+// Storage is the subset of the storage backend used by reconciliation
+type Storage interface {
+	ListFiles() ([]storage.File, error)
+	FileExists(fileID string) (bool, error)
+	DeleteFile(fileID string) error
+	MakePrivate(fileID string) error
+}
+
+// Repository is the subset of the queries used by reconciliation
+type Repository interface {
+	ListMarkerImageRefs(ctx context.Context) ([]repository.ListMarkerImageRefsRow, error)
+	ClearMarkerImageURLs(ctx context.Context, arg repository.ClearMarkerImageURLsParams) error
+}
+
+// Options controls a reconciliation run
+type Options struct {
+	// DryRun reports orphans and dangling references without changing anything
+	DryRun bool
+	// MinAge skips files younger than this, so uploads whose marker write is
+	// still in flight are not mistaken for orphans
+	MinAge time.Duration
+	// Now returns the current time, time.Now when nil
+	Now func() time.Time
+}
+
+// DanglingRef is a marker image URL whose file no longer exists in storage
+type DanglingRef struct {
+	MarkerID uuid.UUID
+	URL      string
+}
+
+// Report summarizes a reconciliation run
+type Report struct {
+	FilesScanned int
+	RefsScanned  int
+	// Orphans are stored files no marker refers to
+	Orphans []storage.File
+	// SkippedRecent counts unreferenced files younger than MinAge
+	SkippedRecent int
+	// Dangling are marker references to missing files
+	Dangling []DanglingRef
+	// Public are kept files still readable by anyone with the link, left over
+	// from before images were served through the API
+	Public []storage.File
+	// Deleted, Cleared and MadePrivate count the changes applied (always 0 on a dry run)
+	Deleted     int
+	Cleared     int
+	MadePrivate int
+	// Errors lists failures that did not stop the run
+	Errors []error
+}
+
+// Run compares the files in storage against the image URLs stored on markers,
+// then deletes orphaned files, clears dangling references and removes public
+// access from the remaining files unless DryRun is set.
+func Run(ctx context.Context, store Storage, repo Repository, opts Options) (*Report, error) {
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+
+	// List files before references: a file uploaded in between is then either
+	// absent from the listing or protected by MinAge
+	files, err := store.ListFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stored files: %w", err)
+	}
+
+	refs, err := repo.ListMarkerImageRefs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list marker image references: %w", err)
+	}
+
+	report := &Report{FilesScanned: len(files)}
+
+	stored := make(map[string]bool, len(files))
+	for _, f := range files {
+		stored[f.ID] = true
+	}
+
+	referenced := make(map[string]bool)
+	dangling := make(map[uuid.UUID][]string)
+	for _, ref := range refs {
+		for _, url := range []sql.NullString{ref.ImageUrl, ref.ImageMediumUrl, ref.ImageThumbnailUrl} {
+			if !url.Valid {
+				continue
+			}
+			report.RefsScanned++
+
+			fileID := storage.ExtractFileID(url.String)
+			if fileID == "" {
+				continue
+			}
+			referenced[fileID] = true
+			if stored[fileID] {
+				continue
+			}
+
+			// The file may have been uploaded after the listing; confirm before reporting it
+			exists, err := store.FileExists(fileID)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Errorf("marker %s: %w", ref.ID, err))
+				continue
+			}
+			if !exists {
+				report.Dangling = append(report.Dangling, DanglingRef{MarkerID: ref.ID, URL: url.String})
+				dangling[ref.ID] = append(dangling[ref.ID], url.String)
+			}
+		}
+	}
+
+	cutoff := now().Add(-opts.MinAge)
+	for _, f := range files {
+		if referenced[f.ID] {
+			continue
+		}
+		if f.CreatedTime.After(cutoff) {
+			report.SkippedRecent++
+			continue
+		}
+		report.Orphans = append(report.Orphans, f)
+	}
+
+	// Orphans are deleted anyway, so only the kept files need their access fixed
+	orphaned := make(map[string]bool, len(report.Orphans))
+	for _, f := range report.Orphans {
+		orphaned[f.ID] = true
+	}
+	for _, f := range files {
+		if f.Public && !orphaned[f.ID] {
+			report.Public = append(report.Public, f)
+		}
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+
+	for _, f := range report.Orphans {
+		if err := store.DeleteFile(f.ID); err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("file %s: %w", f.ID, err))
+			continue
+		}
+		report.Deleted++
+	}
+
+	for markerID, urls := range dangling {
+		err := repo.ClearMarkerImageURLs(ctx, repository.ClearMarkerImageURLsParams{
+			Urls: urls,
+			ID:   markerID,
+		})
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("marker %s: %w", markerID, err))
+			continue
+		}
+		report.Cleared += len(urls)
+	}
+
+	for _, f := range report.Public {
+		if err := store.MakePrivate(f.ID); err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("file %s: %w", f.ID, err))
+			continue
+		}
+		report.MadePrivate++
+	}
+
+	return report, nil
+}
@@ -0,0 +1,186 @@
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/repository"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/storage"
+	"github.com/google/uuid"
+)
+
+type fakeStorage struct {
+	files     []storage.File
+	late      map[string]bool // files created after the listing
+	deleted   []string
+	deleteErr error
+	private   []string
+}
+
+func (f *fakeStorage) ListFiles() ([]storage.File, error) {
+	return f.files, nil
+}
+
+func (f *fakeStorage) FileExists(fileID string) (bool, error) {
+	return f.late[fileID], nil
+}
+
+func (f *fakeStorage) DeleteFile(fileID string) error {
+	if f.deleteErr != nil {
+		return f.deleteErr
+	}
+	f.deleted = append(f.deleted, fileID)
+	return nil
+}
+
+func (f *fakeStorage) MakePrivate(fileID string) error {
+	f.private = append(f.private, fileID)
+	return nil
+}
+
+type fakeRepository struct {
+	refs    []repository.ListMarkerImageRefsRow
+	cleared []repository.ClearMarkerImageURLsParams
+}
+
+func (f *fakeRepository) ListMarkerImageRefs(ctx context.Context) ([]repository.ListMarkerImageRefsRow, error) {
+	return f.refs, nil
+}
+
+func (f *fakeRepository) ClearMarkerImageURLs(ctx context.Context, arg repository.ClearMarkerImageURLsParams) error {
+	f.cleared = append(f.cleared, arg)
+	return nil
+}
+
+func nullURL(fileID string) sql.NullString {
+	return sql.NullString{String: storage.FileURL(fileID), Valid: true}
+}
+
+var testNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newFixture() (*fakeStorage, *fakeRepository, uuid.UUID) {
+	old := testNow.Add(-48 * time.Hour)
+	markerID := uuid.New()
+
+	store := &fakeStorage{
+		files: []storage.File{
+			{ID: "orig", CreatedTime: old, Public: true},
+			{ID: "med", CreatedTime: old},
+			{ID: "orphan", CreatedTime: old, Public: true},
+			{ID: "fresh", CreatedTime: testNow.Add(-time.Minute)},
+		},
+		late: map[string]bool{"late": true},
+	}
+	repo := &fakeRepository{
+		refs: []repository.ListMarkerImageRefsRow{
+			{
+				ID:                markerID,
+				ImageUrl:          nullURL("orig"),
+				ImageMediumUrl:    nullURL("med"),
+				ImageThumbnailUrl: nullURL("gone"),
+			},
+			{
+				ID:       uuid.New(),
+				ImageUrl: nullURL("late"),
+			},
+		},
+	}
+	return store, repo, markerID
+}
+
+func TestRun_DryRun(t *testing.T) {
+	store, repo, markerID := newFixture()
+
+	report, err := Run(context.Background(), store, repo, Options{
+		DryRun: true,
+		MinAge: time.Hour,
+		Now:    func() time.Time { return testNow },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if report.FilesScanned != 4 || report.RefsScanned != 4 {
+		t.Errorf("expected 4 files and 4 refs scanned, got %d and %d", report.FilesScanned, report.RefsScanned)
+	}
+	if len(report.Orphans) != 1 || report.Orphans[0].ID != "orphan" {
+		t.Errorf("expected only 'orphan' to be orphaned, got %+v", report.Orphans)
+	}
+	if report.SkippedRecent != 1 {
+		t.Errorf("expected the recent upload to be skipped, got %d", report.SkippedRecent)
+	}
+	if len(report.Dangling) != 1 || report.Dangling[0].MarkerID != markerID || report.Dangling[0].URL != storage.FileURL("gone") {
+		t.Errorf("expected one dangling thumbnail reference, got %+v", report.Dangling)
+	}
+
+	if len(report.Public) != 1 || report.Public[0].ID != "orig" {
+		t.Errorf("expected only 'orig' to be reported public, got %+v", report.Public)
+	}
+
+	if len(store.deleted) != 0 || len(repo.cleared) != 0 || len(store.private) != 0 {
+		t.Error("expected dry run to change nothing")
+	}
+	if report.Deleted != 0 || report.Cleared != 0 || report.MadePrivate != 0 {
+		t.Errorf("expected no changes reported, got deleted=%d cleared=%d private=%d",
+			report.Deleted, report.Cleared, report.MadePrivate)
+	}
+}
+
+func TestRun_Apply(t *testing.T) {
+	store, repo, markerID := newFixture()
+
+	report, err := Run(context.Background(), store, repo, Options{
+		MinAge: time.Hour,
+		Now:    func() time.Time { return testNow },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sort.Strings(store.deleted)
+	if len(store.deleted) != 1 || store.deleted[0] != "orphan" {
+		t.Errorf("expected only 'orphan' to be deleted, got %v", store.deleted)
+	}
+	if len(repo.cleared) != 1 {
+		t.Fatalf("expected one marker to be cleared, got %d", len(repo.cleared))
+	}
+	cleared := repo.cleared[0]
+	if cleared.ID != markerID || len(cleared.Urls) != 1 || cleared.Urls[0] != storage.FileURL("gone") {
+		t.Errorf("unexpected clear params: %+v", cleared)
+	}
+	if report.Deleted != 1 || report.Cleared != 1 {
+		t.Errorf("expected deleted=1 cleared=1, got deleted=%d cleared=%d", report.Deleted, report.Cleared)
+	}
+	// The public orphan is deleted rather than made private
+	if len(store.private) != 1 || store.private[0] != "orig" || report.MadePrivate != 1 {
+		t.Errorf("expected only 'orig' to be made private, got %v (%d)", store.private, report.MadePrivate)
+	}
+}
+
+func TestRun_DeleteErrorsAreReported(t *testing.T) {
+	store, repo, _ := newFixture()
+	store.deleteErr = errors.New("boom")
+
+	report, err := Run(context.Background(), store, repo, Options{
+		MinAge: time.Hour,
+		Now:    func() time.Time { return testNow },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if report.Deleted != 0 {
+		t.Errorf("expected no deletions, got %d", report.Deleted)
+	}
+	if len(report.Errors) != 1 {
+		t.Errorf("expected one error, got %v", report.Errors)
+	}
+	// References are still cleared when a file deletion fails
+	if report.Cleared != 1 {
+		t.Errorf("expected cleared=1, got %d", report.Cleared)
+	}
+}
@@ -17,42 +17,21 @@ type ListMarkersPaginatedResult struct {
 	TotalCount int64
 }
 
+// markerColumns lists the marker columns in the order scanMarkers reads them
+var markerColumns = []string{
+	"id", "short_code", "creator_id", "name", "description",
+	"strain", "quantity", "latitude", "longitude", "image_url",
+	"owner_name", "owner_contact", "created_at", "updated_at",
+	"image_thumbnail_url", "image_medium_url", "image_captured_at",
+	"image_status", "image_job_id", "visibility",
+}
+
 // ListMarkersPaginated retrieves markers with pagination, sorting, search, and filters
 func (q *Queries) ListMarkersPaginated(ctx context.Context, params model.ListMarkersParams) (*ListMarkersPaginatedResult, error) {
 	// Use PostgreSQL placeholder format ($1, $2, etc.)
 	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
-	// Build base WHERE conditions
-	conditions := sq.And{}
-
-	// Add search condition
-	if params.Search != "" {
-		searchPattern := "%" + params.Search + "%"
-		searchCondition := sq.Or{
-			sq.ILike{"name": searchPattern},
-			sq.ILike{"description": searchPattern},
-			sq.ILike{"strain": searchPattern},
-			sq.ILike{"short_code": searchPattern},
-			sq.ILike{"owner_name": searchPattern},
-			sq.ILike{"owner_contact": searchPattern},
-		}
-		conditions = append(conditions, searchCondition)
-	}
-
-	// Add date_from filter
-	if params.DateFrom != nil {
-		conditions = append(conditions, sq.GtOrEq{"created_at": params.DateFrom})
-	}
-
-	// Add date_to filter
-	if params.DateTo != nil {
-		conditions = append(conditions, sq.LtOrEq{"created_at": params.DateTo})
-	}
-
-	// Add creator_id filter
-	if params.CreatorID != nil {
-		conditions = append(conditions, sq.Eq{"creator_id": params.CreatorID})
-	}
+	conditions := markerFilterConditions(params)
 
 	// Get total count first
 	countQuery := psql.Select("COUNT(*)").From("markers")
@@ -80,30 +59,93 @@ func (q *Queries) ListMarkersPaginated(ctx context.Context, params model.ListMar
 	}
 
 	// Build select query
-	selectQuery := psql.Select(
-		"id", "short_code", "creator_id", "name", "description",
-		"strain", "quantity", "latitude", "longitude", "image_url",
-		"owner_name", "owner_contact", "created_at", "updated_at",
-	).From("markers")
+	selectQuery := psql.Select(markerColumns...).From("markers")
 
 	if len(conditions) > 0 {
 		selectQuery = selectQuery.Where(conditions)
 	}
 
 	// Add ordering
+	selectQuery = selectQuery.OrderBy(markerOrderBy(params))
+
+	// Add pagination
+	offset := (params.Page - 1) * params.PerPage
+	selectQuery = selectQuery.Limit(uint64(params.PerPage)).Offset(uint64(offset))
+
+	markers, err := q.queryMarkers(ctx, selectQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListMarkersPaginatedResult{
+		Markers:    markers,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// ListMarkersFiltered retrieves up to limit markers matching the search and filters,
+// in the requested order. Pagination parameters are ignored.
+func (q *Queries) ListMarkersFiltered(ctx context.Context, params model.ListMarkersParams, limit uint64) ([]Marker, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	selectQuery := psql.Select(markerColumns...).From("markers")
+	if conditions := markerFilterConditions(params); len(conditions) > 0 {
+		selectQuery = selectQuery.Where(conditions)
+	}
+	selectQuery = selectQuery.OrderBy(markerOrderBy(params)).Limit(limit)
+
+	return q.queryMarkers(ctx, selectQuery)
+}
+
+// markerFilterConditions builds the WHERE conditions for the search and filters
+func markerFilterConditions(params model.ListMarkersParams) sq.And {
+	conditions := sq.And{}
+
+	// Add search condition
+	if params.Search != "" {
+		searchPattern := "%" + params.Search + "%"
+		searchCondition := sq.Or{
+			sq.ILike{"name": searchPattern},
+			sq.ILike{"description": searchPattern},
+			sq.ILike{"strain": searchPattern},
+			sq.ILike{"short_code": searchPattern},
+			sq.ILike{"owner_name": searchPattern},
+			sq.ILike{"owner_contact": searchPattern},
+		}
+		conditions = append(conditions, searchCondition)
+	}
+
+	// Add date_from filter
+	if params.DateFrom != nil {
+		conditions = append(conditions, sq.GtOrEq{"created_at": params.DateFrom})
+	}
+
+	// Add date_to filter
+	if params.DateTo != nil {
+		conditions = append(conditions, sq.LtOrEq{"created_at": params.DateTo})
+	}
+
+	// Add creator_id filter
+	if params.CreatorID != nil {
+		conditions = append(conditions, sq.Eq{"creator_id": params.CreatorID})
+	}
+
+	return conditions
+}
+
+// markerOrderBy returns the ORDER BY clause for the requested sort
+func markerOrderBy(params model.ListMarkersParams) string {
 	orderColumn := sanitizeSortColumn(params.SortBy)
 	orderDir := strings.ToUpper(params.SortDir)
 	if orderDir != "ASC" && orderDir != "DESC" {
 		orderDir = "DESC"
 	}
-	selectQuery = selectQuery.OrderBy(fmt.Sprintf("%s %s", orderColumn, orderDir))
-
-	// Add pagination
-	offset := (params.Page - 1) * params.PerPage
-	selectQuery = selectQuery.Limit(uint64(params.PerPage)).Offset(uint64(offset))
+	return fmt.Sprintf("%s %s", orderColumn, orderDir)
+}
 
-	// Execute query
-	selectSQL, selectArgs, err := selectQuery.ToSql()
+// queryMarkers executes a select of markerColumns and scans the rows
+func (q *Queries) queryMarkers(ctx context.Context, query sq.SelectBuilder) ([]Marker, error) {
+	selectSQL, selectArgs, err := query.ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("failed to build select query: %w", err)
 	}
@@ -114,7 +156,7 @@ func (q *Queries) ListMarkersPaginated(ctx context.Context, params model.ListMar
 	}
 	defer rows.Close()
 
-	var markers []Marker
+	markers := []Marker{}
 	for rows.Next() {
 		var m Marker
 		err := rows.Scan(
@@ -132,6 +174,12 @@ func (q *Queries) ListMarkersPaginated(ctx context.Context, params model.ListMar
 			&m.OwnerContact,
 			&m.CreatedAt,
 			&m.UpdatedAt,
+			&m.ImageThumbnailUrl,
+			&m.ImageMediumUrl,
+			&m.ImageCapturedAt,
+			&m.ImageStatus,
+			&m.ImageJobID,
+			&m.Visibility,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan marker row: %w", err)
@@ -143,10 +191,7 @@ func (q *Queries) ListMarkersPaginated(ctx context.Context, params model.ListMar
 		return nil, fmt.Errorf("error iterating marker rows: %w", err)
 	}
 
-	return &ListMarkersPaginatedResult{
-		Markers:    markers,
-		TotalCount: totalCount,
-	}, nil
+	return markers, nil
 }
 
 // sanitizeSortColumn ensures only allowed columns are used for sorting
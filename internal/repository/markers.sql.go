@@ -10,28 +10,105 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const clearMarkerImageURLs = `-- name: ClearMarkerImageURLs :exec
+UPDATE markers SET
+    image_url = CASE WHEN image_url = ANY($1::text[]) THEN NULL ELSE image_url END,
+    image_medium_url = CASE WHEN image_medium_url = ANY($1::text[]) THEN NULL ELSE image_medium_url END,
+    image_thumbnail_url = CASE WHEN image_thumbnail_url = ANY($1::text[]) THEN NULL ELSE image_thumbnail_url END,
+    image_captured_at = CASE WHEN image_url = ANY($1::text[]) THEN NULL ELSE image_captured_at END
+WHERE id = $2
+`
+
+type ClearMarkerImageURLsParams struct {
+	Urls []string  `json:"urls"`
+	ID   uuid.UUID `json:"id"`
+}
+
+// Clears image columns still pointing at one of the given URLs (used by image reconciliation)
+func (q *Queries) ClearMarkerImageURLs(ctx context.Context, arg ClearMarkerImageURLsParams) error {
+	_, err := q.db.ExecContext(ctx, clearMarkerImageURLs, pq.Array(arg.Urls), arg.ID)
+	return err
+}
+
+const completeMarkerImage = `-- name: CompleteMarkerImage :one
+WITH old AS (
+    SELECT id, image_url, image_medium_url, image_thumbnail_url
+    FROM markers
+    WHERE markers.id = $1 AND image_job_id = $2
+    FOR UPDATE
+)
+UPDATE markers SET
+    image_url = $3,
+    image_medium_url = $4,
+    image_thumbnail_url = $5,
+    image_status = 'ready',
+    image_job_id = NULL,
+    updated_at = NOW()
+FROM old
+WHERE markers.id = old.id
+RETURNING old.image_url, old.image_medium_url, old.image_thumbnail_url
+`
+
+type CompleteMarkerImageParams struct {
+	ID                uuid.UUID      `json:"id"`
+	ImageJobID        uuid.NullUUID  `json:"image_job_id"`
+	ImageUrl          sql.NullString `json:"image_url"`
+	ImageMediumUrl    sql.NullString `json:"image_medium_url"`
+	ImageThumbnailUrl sql.NullString `json:"image_thumbnail_url"`
+}
+
+type CompleteMarkerImageRow struct {
+	ImageUrl          sql.NullString `json:"image_url"`
+	ImageMediumUrl    sql.NullString `json:"image_medium_url"`
+	ImageThumbnailUrl sql.NullString `json:"image_thumbnail_url"`
+}
+
+// Stores the uploaded image URLs of a queued image job and returns the replaced URLs.
+// Matches no row when the marker was deleted or a newer job superseded this one.
+func (q *Queries) CompleteMarkerImage(ctx context.Context, arg CompleteMarkerImageParams) (CompleteMarkerImageRow, error) {
+	row := q.db.QueryRowContext(ctx, completeMarkerImage,
+		arg.ID,
+		arg.ImageJobID,
+		arg.ImageUrl,
+		arg.ImageMediumUrl,
+		arg.ImageThumbnailUrl,
+	)
+	var i CompleteMarkerImageRow
+	err := row.Scan(&i.ImageUrl, &i.ImageMediumUrl, &i.ImageThumbnailUrl)
+	return i, err
+}
+
 const createMarker = `-- name: CreateMarker :one
 INSERT INTO markers (
     short_code, creator_id, name, description, strain,
-    quantity, latitude, longitude, image_url, owner_name, owner_contact
-) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-RETURNING id, short_code, creator_id, name, description, strain, quantity, latitude, longitude, image_url, owner_name, owner_contact, created_at, updated_at
+    quantity, latitude, longitude, image_url, owner_name, owner_contact,
+    image_thumbnail_url, image_medium_url, image_captured_at,
+    image_status, image_job_id, visibility
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+RETURNING id, short_code, creator_id, name, description, strain, quantity, latitude, longitude, image_url, owner_name, owner_contact, created_at, updated_at, image_thumbnail_url, image_medium_url, image_captured_at, image_status, image_job_id, visibility
 `
 
 type CreateMarkerParams struct {
-	ShortCode    string         `json:"short_code"`
-	CreatorID    uuid.UUID      `json:"creator_id"`
-	Name         string         `json:"name"`
-	Description  sql.NullString `json:"description"`
-	Strain       sql.NullString `json:"strain"`
-	Quantity     sql.NullInt32  `json:"quantity"`
-	Latitude     string         `json:"latitude"`
-	Longitude    string         `json:"longitude"`
-	ImageUrl     sql.NullString `json:"image_url"`
-	OwnerName    sql.NullString `json:"owner_name"`
-	OwnerContact sql.NullString `json:"owner_contact"`
+	ShortCode         string         `json:"short_code"`
+	CreatorID         uuid.UUID      `json:"creator_id"`
+	Name              string         `json:"name"`
+	Description       sql.NullString `json:"description"`
+	Strain            sql.NullString `json:"strain"`
+	Quantity          sql.NullInt32  `json:"quantity"`
+	Latitude          string         `json:"latitude"`
+	Longitude         string         `json:"longitude"`
+	ImageUrl          sql.NullString `json:"image_url"`
+	OwnerName         sql.NullString `json:"owner_name"`
+	OwnerContact      sql.NullString `json:"owner_contact"`
+	ImageThumbnailUrl sql.NullString `json:"image_thumbnail_url"`
+	ImageMediumUrl    sql.NullString `json:"image_medium_url"`
+	ImageCapturedAt   sql.NullTime   `json:"image_captured_at"`
+	ImageStatus       sql.NullString `json:"image_status"`
+	ImageJobID        uuid.NullUUID  `json:"image_job_id"`
+	Visibility        string         `json:"visibility"`
 }
 
 // Creates a new marker and returns the created record
@@ -48,6 +125,12 @@ func (q *Queries) CreateMarker(ctx context.Context, arg CreateMarkerParams) (Mar
 		arg.ImageUrl,
 		arg.OwnerName,
 		arg.OwnerContact,
+		arg.ImageThumbnailUrl,
+		arg.ImageMediumUrl,
+		arg.ImageCapturedAt,
+		arg.ImageStatus,
+		arg.ImageJobID,
+		arg.Visibility,
 	)
 	var i Marker
 	err := row.Scan(
@@ -65,6 +148,12 @@ func (q *Queries) CreateMarker(ctx context.Context, arg CreateMarkerParams) (Mar
 		&i.OwnerContact,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ImageThumbnailUrl,
+		&i.ImageMediumUrl,
+		&i.ImageCapturedAt,
+		&i.ImageStatus,
+		&i.ImageJobID,
+		&i.Visibility,
 	)
 	return i, err
 }
@@ -79,8 +168,27 @@ func (q *Queries) DeleteMarker(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const failMarkerImage = `-- name: FailMarkerImage :exec
+UPDATE markers SET
+    image_status = 'failed',
+    image_job_id = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND image_job_id = $2
+`
+
+type FailMarkerImageParams struct {
+	ID         uuid.UUID     `json:"id"`
+	ImageJobID uuid.NullUUID `json:"image_job_id"`
+}
+
+// Marks a queued image job as failed, keeping any previous image
+func (q *Queries) FailMarkerImage(ctx context.Context, arg FailMarkerImageParams) error {
+	_, err := q.db.ExecContext(ctx, failMarkerImage, arg.ID, arg.ImageJobID)
+	return err
+}
+
 const getMarkerByID = `-- name: GetMarkerByID :one
-SELECT id, short_code, creator_id, name, description, strain, quantity, latitude, longitude, image_url, owner_name, owner_contact, created_at, updated_at FROM markers WHERE id = $1
+SELECT id, short_code, creator_id, name, description, strain, quantity, latitude, longitude, image_url, owner_name, owner_contact, created_at, updated_at, image_thumbnail_url, image_medium_url, image_captured_at, image_status, image_job_id, visibility FROM markers WHERE id = $1
 `
 
 // Returns full marker details by ID
@@ -102,12 +210,18 @@ func (q *Queries) GetMarkerByID(ctx context.Context, id uuid.UUID) (Marker, erro
 		&i.OwnerContact,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ImageThumbnailUrl,
+		&i.ImageMediumUrl,
+		&i.ImageCapturedAt,
+		&i.ImageStatus,
+		&i.ImageJobID,
+		&i.Visibility,
 	)
 	return i, err
 }
 
 const getMarkerByShortCode = `-- name: GetMarkerByShortCode :one
-SELECT id, short_code, creator_id, name, description, strain, quantity, latitude, longitude, image_url, owner_name, owner_contact, created_at, updated_at FROM markers WHERE short_code = $1
+SELECT id, short_code, creator_id, name, description, strain, quantity, latitude, longitude, image_url, owner_name, owner_contact, created_at, updated_at, image_thumbnail_url, image_medium_url, image_captured_at, image_status, image_job_id, visibility FROM markers WHERE short_code = $1
 `
 
 // Returns full marker details by short_code (for QR code scanning)
@@ -129,10 +243,107 @@ func (q *Queries) GetMarkerByShortCode(ctx context.Context, shortCode string) (M
 		&i.OwnerContact,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ImageThumbnailUrl,
+		&i.ImageMediumUrl,
+		&i.ImageCapturedAt,
+		&i.ImageStatus,
+		&i.ImageJobID,
+		&i.Visibility,
 	)
 	return i, err
 }
 
+const getMarkersByIDs = `-- name: GetMarkersByIDs :many
+SELECT id, short_code, creator_id, name, description, strain, quantity, latitude, longitude, image_url, owner_name, owner_contact, created_at, updated_at, image_thumbnail_url, image_medium_url, image_captured_at, image_status, image_job_id, visibility FROM markers WHERE id = ANY($1::uuid[])
+`
+
+// Returns full marker details for a set of IDs (for label sheets)
+func (q *Queries) GetMarkersByIDs(ctx context.Context, ids []uuid.UUID) ([]Marker, error) {
+	rows, err := q.db.QueryContext(ctx, getMarkersByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Marker{}
+	for rows.Next() {
+		var i Marker
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortCode,
+			&i.CreatorID,
+			&i.Name,
+			&i.Description,
+			&i.Strain,
+			&i.Quantity,
+			&i.Latitude,
+			&i.Longitude,
+			&i.ImageUrl,
+			&i.OwnerName,
+			&i.OwnerContact,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ImageThumbnailUrl,
+			&i.ImageMediumUrl,
+			&i.ImageCapturedAt,
+			&i.ImageStatus,
+			&i.ImageJobID,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMarkerImageRefs = `-- name: ListMarkerImageRefs :many
+SELECT id, image_url, image_medium_url, image_thumbnail_url
+FROM markers
+WHERE image_url IS NOT NULL OR image_medium_url IS NOT NULL OR image_thumbnail_url IS NOT NULL
+`
+
+type ListMarkerImageRefsRow struct {
+	ID                uuid.UUID      `json:"id"`
+	ImageUrl          sql.NullString `json:"image_url"`
+	ImageMediumUrl    sql.NullString `json:"image_medium_url"`
+	ImageThumbnailUrl sql.NullString `json:"image_thumbnail_url"`
+}
+
+// Returns the stored image URLs of every marker with an image (used by image reconciliation)
+func (q *Queries) ListMarkerImageRefs(ctx context.Context) ([]ListMarkerImageRefsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listMarkerImageRefs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListMarkerImageRefsRow{}
+	for rows.Next() {
+		var i ListMarkerImageRefsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ImageUrl,
+			&i.ImageMediumUrl,
+			&i.ImageThumbnailUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listMarkersLightweight = `-- name: ListMarkersLightweight :many
 SELECT id, short_code, name, latitude, longitude
 FROM markers
@@ -177,6 +388,86 @@ func (q *Queries) ListMarkersLightweight(ctx context.Context) ([]ListMarkersLigh
 	return items, nil
 }
 
+const listPendingMarkerImages = `-- name: ListPendingMarkerImages :many
+SELECT id, image_job_id FROM markers WHERE image_status = 'pending'
+`
+
+type ListPendingMarkerImagesRow struct {
+	ID         uuid.UUID     `json:"id"`
+	ImageJobID uuid.NullUUID `json:"image_job_id"`
+}
+
+// Returns the markers whose image job hasn't finished (used by image queue recovery)
+func (q *Queries) ListPendingMarkerImages(ctx context.Context) ([]ListPendingMarkerImagesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPendingMarkerImages)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPendingMarkerImagesRow{}
+	for rows.Next() {
+		var i ListPendingMarkerImagesRow
+		if err := rows.Scan(&i.ID, &i.ImageJobID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const rotateMarkerShortCode = `-- name: RotateMarkerShortCode :one
+WITH retired AS (
+    INSERT INTO marker_short_code_aliases (short_code, marker_id, redirect)
+    SELECT short_code, id, $1::boolean FROM markers WHERE id = $2
+)
+UPDATE markers SET
+    short_code = $3,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING id, short_code, creator_id, name, description, strain, quantity, latitude, longitude, image_url, owner_name, owner_contact, created_at, updated_at, image_thumbnail_url, image_medium_url, image_captured_at, image_status, image_job_id, visibility
+`
+
+type RotateMarkerShortCodeParams struct {
+	Redirect  bool      `json:"redirect"`
+	ID        uuid.UUID `json:"id"`
+	ShortCode string    `json:"short_code"`
+}
+
+// Replaces the short code of a marker, keeping the old code as a retired alias
+func (q *Queries) RotateMarkerShortCode(ctx context.Context, arg RotateMarkerShortCodeParams) (Marker, error) {
+	row := q.db.QueryRowContext(ctx, rotateMarkerShortCode, arg.Redirect, arg.ID, arg.ShortCode)
+	var i Marker
+	err := row.Scan(
+		&i.ID,
+		&i.ShortCode,
+		&i.CreatorID,
+		&i.Name,
+		&i.Description,
+		&i.Strain,
+		&i.Quantity,
+		&i.Latitude,
+		&i.Longitude,
+		&i.ImageUrl,
+		&i.OwnerName,
+		&i.OwnerContact,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ImageThumbnailUrl,
+		&i.ImageMediumUrl,
+		&i.ImageCapturedAt,
+		&i.ImageStatus,
+		&i.ImageJobID,
+		&i.Visibility,
+	)
+	return i, err
+}
+
 const updateMarker = `-- name: UpdateMarker :one
 UPDATE markers SET
     name = $2,
@@ -188,22 +479,34 @@ UPDATE markers SET
     image_url = $8,
     owner_name = $9,
     owner_contact = $10,
+    image_thumbnail_url = $11,
+    image_medium_url = $12,
+    image_captured_at = $13,
+    image_status = $14,
+    image_job_id = $15,
+    visibility = $16,
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, short_code, creator_id, name, description, strain, quantity, latitude, longitude, image_url, owner_name, owner_contact, created_at, updated_at
+RETURNING id, short_code, creator_id, name, description, strain, quantity, latitude, longitude, image_url, owner_name, owner_contact, created_at, updated_at, image_thumbnail_url, image_medium_url, image_captured_at, image_status, image_job_id, visibility
 `
 
 type UpdateMarkerParams struct {
-	ID           uuid.UUID      `json:"id"`
-	Name         string         `json:"name"`
-	Description  sql.NullString `json:"description"`
-	Strain       sql.NullString `json:"strain"`
-	Quantity     sql.NullInt32  `json:"quantity"`
-	Latitude     string         `json:"latitude"`
-	Longitude    string         `json:"longitude"`
-	ImageUrl     sql.NullString `json:"image_url"`
-	OwnerName    sql.NullString `json:"owner_name"`
-	OwnerContact sql.NullString `json:"owner_contact"`
+	ID                uuid.UUID      `json:"id"`
+	Name              string         `json:"name"`
+	Description       sql.NullString `json:"description"`
+	Strain            sql.NullString `json:"strain"`
+	Quantity          sql.NullInt32  `json:"quantity"`
+	Latitude          string         `json:"latitude"`
+	Longitude         string         `json:"longitude"`
+	ImageUrl          sql.NullString `json:"image_url"`
+	OwnerName         sql.NullString `json:"owner_name"`
+	OwnerContact      sql.NullString `json:"owner_contact"`
+	ImageThumbnailUrl sql.NullString `json:"image_thumbnail_url"`
+	ImageMediumUrl    sql.NullString `json:"image_medium_url"`
+	ImageCapturedAt   sql.NullTime   `json:"image_captured_at"`
+	ImageStatus       sql.NullString `json:"image_status"`
+	ImageJobID        uuid.NullUUID  `json:"image_job_id"`
+	Visibility        string         `json:"visibility"`
 }
 
 // Updates an existing marker and returns the updated record
@@ -219,6 +522,12 @@ func (q *Queries) UpdateMarker(ctx context.Context, arg UpdateMarkerParams) (Mar
 		arg.ImageUrl,
 		arg.OwnerName,
 		arg.OwnerContact,
+		arg.ImageThumbnailUrl,
+		arg.ImageMediumUrl,
+		arg.ImageCapturedAt,
+		arg.ImageStatus,
+		arg.ImageJobID,
+		arg.Visibility,
 	)
 	var i Marker
 	err := row.Scan(
@@ -236,6 +545,12 @@ func (q *Queries) UpdateMarker(ctx context.Context, arg UpdateMarkerParams) (Mar
 		&i.OwnerContact,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ImageThumbnailUrl,
+		&i.ImageMediumUrl,
+		&i.ImageCapturedAt,
+		&i.ImageStatus,
+		&i.ImageJobID,
+		&i.Visibility,
 	)
 	return i, err
 }
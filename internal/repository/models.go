@@ -11,21 +11,72 @@ import (
 	"github.com/google/uuid"
 )
 
+type EmailVerificationToken struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	Email     string       `json:"email"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	Scope         string       `json:"scope"`
+	Subject       string       `json:"subject"`
+	Failures      int32        `json:"failures"`
+	LastFailureAt time.Time    `json:"last_failure_at"`
+	LockedUntil   sql.NullTime `json:"locked_until"`
+}
+
 type Marker struct {
-	ID           uuid.UUID      `json:"id"`
-	ShortCode    string         `json:"short_code"`
-	CreatorID    uuid.UUID      `json:"creator_id"`
-	Name         string         `json:"name"`
-	Description  sql.NullString `json:"description"`
-	Strain       sql.NullString `json:"strain"`
-	Quantity     sql.NullInt32  `json:"quantity"`
-	Latitude     string         `json:"latitude"`
-	Longitude    string         `json:"longitude"`
-	ImageUrl     sql.NullString `json:"image_url"`
-	OwnerName    sql.NullString `json:"owner_name"`
-	OwnerContact sql.NullString `json:"owner_contact"`
-	CreatedAt    sql.NullTime   `json:"created_at"`
-	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	ID                uuid.UUID      `json:"id"`
+	ShortCode         string         `json:"short_code"`
+	CreatorID         uuid.UUID      `json:"creator_id"`
+	Name              string         `json:"name"`
+	Description       sql.NullString `json:"description"`
+	Strain            sql.NullString `json:"strain"`
+	Quantity          sql.NullInt32  `json:"quantity"`
+	Latitude          string         `json:"latitude"`
+	Longitude         string         `json:"longitude"`
+	ImageUrl          sql.NullString `json:"image_url"`
+	OwnerName         sql.NullString `json:"owner_name"`
+	OwnerContact      sql.NullString `json:"owner_contact"`
+	CreatedAt         sql.NullTime   `json:"created_at"`
+	UpdatedAt         sql.NullTime   `json:"updated_at"`
+	ImageThumbnailUrl sql.NullString `json:"image_thumbnail_url"`
+	ImageMediumUrl    sql.NullString `json:"image_medium_url"`
+	ImageCapturedAt   sql.NullTime   `json:"image_captured_at"`
+	ImageStatus       sql.NullString `json:"image_status"`
+	ImageJobID        uuid.NullUUID  `json:"image_job_id"`
+	Visibility        string         `json:"visibility"`
+}
+
+type MarkerScan struct {
+	ID        int64          `json:"id"`
+	MarkerID  uuid.UUID      `json:"marker_id"`
+	ScannedAt time.Time      `json:"scanned_at"`
+	Source    string         `json:"source"`
+	Platform  string         `json:"platform"`
+	IpHash    sql.NullString `json:"ip_hash"`
+	Latitude  sql.NullString `json:"latitude"`
+	Longitude sql.NullString `json:"longitude"`
+}
+
+type MarkerShortCodeAlias struct {
+	ShortCode string    `json:"short_code"`
+	MarkerID  uuid.UUID `json:"marker_id"`
+	Redirect  bool      `json:"redirect"`
+	RetiredAt time.Time `json:"retired_at"`
+}
+
+type PasswordResetToken struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
 }
 
 type RefreshToken struct {
@@ -37,14 +88,17 @@ type RefreshToken struct {
 	CreatedAt sql.NullTime   `json:"created_at"`
 	UserAgent sql.NullString `json:"user_agent"`
 	IpAddress sql.NullString `json:"ip_address"`
+	FamilyID  uuid.UUID      `json:"family_id"`
 }
 
 type User struct {
-	ID           uuid.UUID      `json:"id"`
-	Email        string         `json:"email"`
-	PasswordHash string         `json:"password_hash"`
-	Name         string         `json:"name"`
-	Role         sql.NullString `json:"role"`
-	CreatedAt    sql.NullTime   `json:"created_at"`
-	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	ID              uuid.UUID      `json:"id"`
+	Email           string         `json:"email"`
+	PasswordHash    string         `json:"password_hash"`
+	Name            string         `json:"name"`
+	Role            sql.NullString `json:"role"`
+	CreatedAt       sql.NullTime   `json:"created_at"`
+	UpdatedAt       sql.NullTime   `json:"updated_at"`
+	DisabledAt      sql.NullTime   `json:"disabled_at"`
+	EmailVerifiedAt sql.NullTime   `json:"email_verified_at"`
 }
@@ -6,31 +6,114 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
+	// Clears image columns still pointing at one of the given URLs (used by image reconciliation)
+	ClearMarkerImageURLs(ctx context.Context, arg ClearMarkerImageURLsParams) error
+	// Stores the uploaded image URLs of a queued image job and returns the replaced URLs.
+	// Matches no row when the marker was deleted or a newer job superseded this one.
+	CompleteMarkerImage(ctx context.Context, arg CompleteMarkerImageParams) (CompleteMarkerImageRow, error)
+	// Stores the hash of a new email verification token for the given email
+	CreateEmailVerificationToken(ctx context.Context, arg CreateEmailVerificationTokenParams) error
 	// Creates a new marker and returns the created record
 	CreateMarker(ctx context.Context, arg CreateMarkerParams) (Marker, error)
+	// Records a lookup of a marker by its short code
+	CreateMarkerScan(ctx context.Context, arg CreateMarkerScanParams) error
+	// Stores the hash of a new password reset token
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) error
 	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (CreateRefreshTokenRow, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error)
+	DeleteExpiredEmailVerificationTokens(ctx context.Context) error
+	// Deletes the throttles whose failures are forgotten and whose lockout has ended
+	DeleteExpiredLoginThrottles(ctx context.Context, resetBefore time.Time) error
+	DeleteExpiredPasswordResetTokens(ctx context.Context) error
 	DeleteExpiredRefreshTokens(ctx context.Context) error
+	// Forgets the failures and lockout of an email or client IP
+	DeleteLoginThrottle(ctx context.Context, arg DeleteLoginThrottleParams) (string, error)
 	// Deletes a marker by ID
 	DeleteMarker(ctx context.Context, id uuid.UUID) error
+	// Marks a queued image job as failed, keeping any previous image
+	FailMarkerImage(ctx context.Context, arg FailMarkerImageParams) error
+	// Returns the lockouts in force for an email or a client IP
+	GetLoginLockouts(ctx context.Context, arg GetLoginLockoutsParams) ([]LoginThrottle, error)
 	// Returns full marker details by ID
 	GetMarkerByID(ctx context.Context, id uuid.UUID) (Marker, error)
 	// Returns full marker details by short_code (for QR code scanning)
 	GetMarkerByShortCode(ctx context.Context, shortCode string) (Marker, error)
+	// Returns full marker details for a set of IDs (for label sheets)
+	GetMarkersByIDs(ctx context.Context, ids []uuid.UUID) ([]Marker, error)
 	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error)
+	// Locks a refresh token for rotation, including revoked and expired ones so replays can be detected
+	GetRefreshTokenForRotation(ctx context.Context, tokenHash string) (RefreshToken, error)
+	// Returns scan totals in a time range, for one marker or all markers when marker_id is NULL
+	GetScanSummary(ctx context.Context, arg GetScanSummaryParams) (GetScanSummaryRow, error)
+	// Returns a retired short code with the current short code and visibility of its marker
+	GetShortCodeAlias(ctx context.Context, shortCode string) (GetShortCodeAliasRow, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (GetUserByIDRow, error)
+	// Returns the password hash of a user, for confirming the current password
+	GetUserPasswordHash(ctx context.Context, id uuid.UUID) (string, error)
+	// Marks every unused token of a user as used
+	InvalidateUserEmailVerificationTokens(ctx context.Context, userID uuid.UUID) error
+	// Marks every unused token of a user as used
+	InvalidateUserPasswordResetTokens(ctx context.Context, userID uuid.UUID) error
+	// Returns every lockout in force, the longest first
+	ListLoginLockouts(ctx context.Context) ([]LoginThrottle, error)
+	// Returns the stored image URLs of every marker with an image (used by image reconciliation)
+	ListMarkerImageRefs(ctx context.Context) ([]ListMarkerImageRefsRow, error)
 	// Returns lightweight marker data for map display
 	ListMarkersLightweight(ctx context.Context) ([]ListMarkersLightweightRow, error)
+	// Returns the markers whose image job hasn't finished (used by image queue recovery)
+	ListPendingMarkerImages(ctx context.Context) ([]ListPendingMarkerImagesRow, error)
+	// Returns scan counts per calendar day in a time zone, only for days with scans
+	ListScansByDay(ctx context.Context, arg ListScansByDayParams) ([]ListScansByDayRow, error)
+	// Returns scan counts per client platform and source
+	ListScansByPlatform(ctx context.Context, arg ListScansByPlatformParams) ([]ListScansByPlatformRow, error)
+	// Returns the most scanned markers in a time range
+	ListTopScannedMarkers(ctx context.Context, arg ListTopScannedMarkersParams) ([]ListTopScannedMarkersRow, error)
+	// Lists the active refresh tokens of a user, newest first. Tokens are rotated on
+	// every refresh, so created_at is when the session was last used.
+	ListUserSessions(ctx context.Context, userID uuid.UUID) ([]ListUserSessionsRow, error)
+	LockLogin(ctx context.Context, arg LockLoginParams) error
+	// Makes the verified users with the given lowercase emails admins, returning the emails
+	// that changed (ADMIN_EMAILS)
+	PromoteUsersToAdmin(ctx context.Context, emails []string) ([]string, error)
+	// Counts a failed login, restarting the count when the last failure was before reset_before
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (int32, error)
 	RevokeAllUserRefreshTokens(ctx context.Context, userID uuid.UUID) error
+	// Revokes every refresh token of a user except one, keeping the current session
+	RevokeOtherUserRefreshTokens(ctx context.Context, arg RevokeOtherUserRefreshTokensParams) error
 	RevokeRefreshToken(ctx context.Context, tokenHash string) error
+	// Revokes the active tokens of a refresh token family, returning the ones revoked
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) ([]uuid.UUID, error)
+	// Revokes a single active refresh token of a user
+	RevokeUserRefreshToken(ctx context.Context, arg RevokeUserRefreshTokenParams) (uuid.UUID, error)
+	// Replaces the short code of a marker, keeping the old code as a retired alias
+	RotateMarkerShortCode(ctx context.Context, arg RotateMarkerShortCodeParams) (Marker, error)
+	// Disables a user, keeping the original time when already disabled, or enables them again
+	SetUserDisabled(ctx context.Context, arg SetUserDisabledParams) (SetUserDisabledRow, error)
+	// Reports whether a short code belongs to a marker or was retired, so it is never reissued
+	ShortCodeInUse(ctx context.Context, shortCode string) (bool, error)
 	// Updates an existing marker and returns the updated record
 	UpdateMarker(ctx context.Context, arg UpdateMarkerParams) (Marker, error)
+	// Replaces the password hash of a user
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
+	// Updates the name and email of a user, keeping fields given as NULL.
+	// A changed email has to be verified again.
+	UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (UpdateUserProfileRow, error)
+	// Changes the role of a user (admin user management)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (UpdateUserRoleRow, error)
+	// Marks an unused, unexpired token as used and returns its user and email; matches no row otherwise
+	UseEmailVerificationToken(ctx context.Context, tokenHash string) (UseEmailVerificationTokenRow, error)
+	// Marks an unused, unexpired token as used and returns its user; matches no row otherwise,
+	// so a token can be used only once even by concurrent requests
+	UsePasswordResetToken(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	// Marks the email of a user as verified, only while it is still the given email
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (VerifyUserEmailRow, error)
 }
 
 var _ Querier = (*Queries)(nil)
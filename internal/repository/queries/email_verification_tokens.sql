@@ -0,0 +1,21 @@
+-- name: CreateEmailVerificationToken :exec
+-- Stores the hash of a new email verification token for the given email
+INSERT INTO email_verification_tokens (user_id, email, token_hash, expires_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: UseEmailVerificationToken :one
+-- Marks an unused, unexpired token as used and returns its user and email; matches no row otherwise
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id, email;
+
+-- name: InvalidateUserEmailVerificationTokens :exec
+-- Marks every unused token of a user as used
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: DeleteExpiredEmailVerificationTokens :exec
+DELETE FROM email_verification_tokens
+WHERE expires_at < NOW() OR used_at IS NOT NULL;
@@ -0,0 +1,42 @@
+-- name: RecordLoginFailure :one
+-- Counts a failed login, restarting the count when the last failure was before reset_before
+INSERT INTO login_throttles (scope, subject, failures, last_failure_at)
+VALUES (sqlc.arg(scope), sqlc.arg(subject), 1, NOW())
+ON CONFLICT (scope, subject) DO UPDATE
+SET failures = CASE
+        WHEN login_throttles.last_failure_at < sqlc.arg(reset_before) THEN 1
+        ELSE login_throttles.failures + 1
+    END,
+    last_failure_at = NOW()
+RETURNING failures;
+
+-- name: LockLogin :exec
+UPDATE login_throttles
+SET locked_until = $3
+WHERE scope = $1 AND subject = $2;
+
+-- name: GetLoginLockouts :many
+-- Returns the lockouts in force for an email or a client IP
+SELECT scope, subject, failures, last_failure_at, locked_until
+FROM login_throttles
+WHERE ((scope = 'email' AND subject = sqlc.arg(email)) OR (scope = 'ip' AND subject = sqlc.arg(ip)))
+  AND locked_until > NOW();
+
+-- name: ListLoginLockouts :many
+-- Returns every lockout in force, the longest first
+SELECT scope, subject, failures, last_failure_at, locked_until
+FROM login_throttles
+WHERE locked_until > NOW()
+ORDER BY locked_until DESC;
+
+-- name: DeleteLoginThrottle :one
+-- Forgets the failures and lockout of an email or client IP
+DELETE FROM login_throttles
+WHERE scope = $1 AND subject = $2
+RETURNING scope;
+
+-- name: DeleteExpiredLoginThrottles :exec
+-- Deletes the throttles whose failures are forgotten and whose lockout has ended
+DELETE FROM login_throttles
+WHERE last_failure_at < sqlc.arg(reset_before)
+  AND (locked_until IS NULL OR locked_until < NOW());
@@ -0,0 +1,51 @@
+-- name: CreateMarkerScan :exec
+-- Records a lookup of a marker by its short code
+INSERT INTO marker_scans (marker_id, source, platform, ip_hash, latitude, longitude)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: GetScanSummary :one
+-- Returns scan totals in a time range, for one marker or all markers when marker_id is NULL
+SELECT
+    COUNT(*)::bigint AS scans,
+    COUNT(DISTINCT ip_hash)::bigint AS unique_visitors,
+    COUNT(DISTINCT marker_id)::bigint AS markers_scanned,
+    MIN(scanned_at)::timestamptz AS first_scanned_at,
+    MAX(scanned_at)::timestamptz AS last_scanned_at
+FROM marker_scans
+WHERE (sqlc.narg(marker_id)::uuid IS NULL OR marker_id = sqlc.narg(marker_id))
+  AND scanned_at >= sqlc.arg(scanned_from) AND scanned_at < sqlc.arg(scanned_to);
+
+-- name: ListScansByDay :many
+-- Returns scan counts per calendar day in a time zone, only for days with scans
+SELECT
+    (scanned_at AT TIME ZONE sqlc.arg(time_zone)::text)::date AS day,
+    COUNT(*)::bigint AS scans,
+    COUNT(DISTINCT ip_hash)::bigint AS unique_visitors
+FROM marker_scans
+WHERE (sqlc.narg(marker_id)::uuid IS NULL OR marker_id = sqlc.narg(marker_id))
+  AND scanned_at >= sqlc.arg(scanned_from) AND scanned_at < sqlc.arg(scanned_to)
+GROUP BY day
+ORDER BY day;
+
+-- name: ListScansByPlatform :many
+-- Returns scan counts per client platform and source
+SELECT platform, source, COUNT(*)::bigint AS scans
+FROM marker_scans
+WHERE (sqlc.narg(marker_id)::uuid IS NULL OR marker_id = sqlc.narg(marker_id))
+  AND scanned_at >= sqlc.arg(scanned_from) AND scanned_at < sqlc.arg(scanned_to)
+GROUP BY platform, source
+ORDER BY scans DESC, platform, source;
+
+-- name: ListTopScannedMarkers :many
+-- Returns the most scanned markers in a time range
+SELECT
+    m.id, m.short_code, m.name,
+    COUNT(*)::bigint AS scans,
+    COUNT(DISTINCT s.ip_hash)::bigint AS unique_visitors,
+    MAX(s.scanned_at)::timestamptz AS last_scanned_at
+FROM marker_scans s
+JOIN markers m ON m.id = s.marker_id
+WHERE s.scanned_at >= sqlc.arg(scanned_from) AND s.scanned_at < sqlc.arg(scanned_to)
+GROUP BY m.id, m.short_code, m.name
+ORDER BY scans DESC, last_scanned_at DESC
+LIMIT sqlc.arg(row_limit);
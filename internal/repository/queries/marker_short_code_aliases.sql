@@ -0,0 +1,12 @@
+-- name: GetShortCodeAlias :one
+-- Returns a retired short code with the current short code and visibility of its marker
+SELECT a.short_code, a.marker_id, a.redirect, a.retired_at,
+    m.short_code AS current_short_code, m.visibility
+FROM marker_short_code_aliases a
+JOIN markers m ON m.id = a.marker_id
+WHERE a.short_code = $1;
+
+-- name: ShortCodeInUse :one
+-- Reports whether a short code belongs to a marker or was retired, so it is never reissued
+SELECT EXISTS (SELECT 1 FROM markers WHERE short_code = $1)
+    OR EXISTS (SELECT 1 FROM marker_short_code_aliases WHERE short_code = $1) AS in_use;
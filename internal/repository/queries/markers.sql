@@ -12,12 +12,18 @@ SELECT * FROM markers WHERE id = $1;
 -- Returns full marker details by short_code (for QR code scanning)
 SELECT * FROM markers WHERE short_code = $1;
 
+-- name: GetMarkersByIDs :many
+-- Returns full marker details for a set of IDs (for label sheets)
+SELECT * FROM markers WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
 -- name: CreateMarker :one
 -- Creates a new marker and returns the created record
 INSERT INTO markers (
     short_code, creator_id, name, description, strain,
-    quantity, latitude, longitude, image_url, owner_name, owner_contact
-) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    quantity, latitude, longitude, image_url, owner_name, owner_contact,
+    image_thumbnail_url, image_medium_url, image_captured_at,
+    image_status, image_job_id, visibility
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 RETURNING *;
 
 -- name: UpdateMarker :one
@@ -32,6 +38,12 @@ UPDATE markers SET
     image_url = $8,
     owner_name = $9,
     owner_contact = $10,
+    image_thumbnail_url = $11,
+    image_medium_url = $12,
+    image_captured_at = $13,
+    image_status = $14,
+    image_job_id = $15,
+    visibility = $16,
     updated_at = NOW()
 WHERE id = $1
 RETURNING *;
@@ -39,3 +51,62 @@ RETURNING *;
 -- name: DeleteMarker :exec
 -- Deletes a marker by ID
 DELETE FROM markers WHERE id = $1;
+
+-- name: ListMarkerImageRefs :many
+-- Returns the stored image URLs of every marker with an image (used by image reconciliation)
+SELECT id, image_url, image_medium_url, image_thumbnail_url
+FROM markers
+WHERE image_url IS NOT NULL OR image_medium_url IS NOT NULL OR image_thumbnail_url IS NOT NULL;
+
+-- name: ListPendingMarkerImages :many
+-- Returns the markers whose image job hasn't finished (used by image queue recovery)
+SELECT id, image_job_id FROM markers WHERE image_status = 'pending';
+
+-- name: ClearMarkerImageURLs :exec
+-- Clears image columns still pointing at one of the given URLs (used by image reconciliation)
+UPDATE markers SET
+    image_url = CASE WHEN image_url = ANY(sqlc.arg(urls)::text[]) THEN NULL ELSE image_url END,
+    image_medium_url = CASE WHEN image_medium_url = ANY(sqlc.arg(urls)::text[]) THEN NULL ELSE image_medium_url END,
+    image_thumbnail_url = CASE WHEN image_thumbnail_url = ANY(sqlc.arg(urls)::text[]) THEN NULL ELSE image_thumbnail_url END,
+    image_captured_at = CASE WHEN image_url = ANY(sqlc.arg(urls)::text[]) THEN NULL ELSE image_captured_at END
+WHERE id = sqlc.arg(id);
+
+-- name: CompleteMarkerImage :one
+-- Stores the uploaded image URLs of a queued image job and returns the replaced URLs.
+-- Matches no row when the marker was deleted or a newer job superseded this one.
+WITH old AS (
+    SELECT id, image_url, image_medium_url, image_thumbnail_url
+    FROM markers
+    WHERE markers.id = sqlc.arg(id) AND image_job_id = sqlc.arg(image_job_id)
+    FOR UPDATE
+)
+UPDATE markers SET
+    image_url = sqlc.arg(image_url),
+    image_medium_url = sqlc.arg(image_medium_url),
+    image_thumbnail_url = sqlc.arg(image_thumbnail_url),
+    image_status = 'ready',
+    image_job_id = NULL,
+    updated_at = NOW()
+FROM old
+WHERE markers.id = old.id
+RETURNING old.image_url, old.image_medium_url, old.image_thumbnail_url;
+
+-- name: FailMarkerImage :exec
+-- Marks a queued image job as failed, keeping any previous image
+UPDATE markers SET
+    image_status = 'failed',
+    image_job_id = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND image_job_id = $2;
+
+-- name: RotateMarkerShortCode :one
+-- Replaces the short code of a marker, keeping the old code as a retired alias
+WITH retired AS (
+    INSERT INTO marker_short_code_aliases (short_code, marker_id, redirect)
+    SELECT short_code, id, sqlc.arg(redirect)::boolean FROM markers WHERE id = sqlc.arg(id)
+)
+UPDATE markers SET
+    short_code = sqlc.arg(short_code),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
@@ -0,0 +1,22 @@
+-- name: CreatePasswordResetToken :exec
+-- Stores the hash of a new password reset token
+INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: UsePasswordResetToken :one
+-- Marks an unused, unexpired token as used and returns its user; matches no row otherwise,
+-- so a token can be used only once even by concurrent requests
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: InvalidateUserPasswordResetTokens :exec
+-- Marks every unused token of a user as used
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: DeleteExpiredPasswordResetTokens :exec
+DELETE FROM password_reset_tokens
+WHERE expires_at < NOW() OR used_at IS NOT NULL;
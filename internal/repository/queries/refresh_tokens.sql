@@ -1,23 +1,58 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (user_id, token_hash, expires_at, user_agent, ip_address)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, user_id, token_hash, expires_at, created_at, user_agent, ip_address;
+INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, user_agent, ip_address)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, user_id, token_hash, expires_at, created_at, user_agent, ip_address, family_id;
 
 -- name: GetRefreshTokenByHash :one
-SELECT id, user_id, token_hash, expires_at, revoked_at, created_at, user_agent, ip_address
+SELECT id, user_id, token_hash, expires_at, revoked_at, created_at, user_agent, ip_address, family_id
 FROM refresh_tokens
 WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW();
 
+-- name: GetRefreshTokenForRotation :one
+-- Locks a refresh token for rotation, including revoked and expired ones so replays can be detected
+SELECT id, user_id, token_hash, expires_at, revoked_at, created_at, user_agent, ip_address, family_id
+FROM refresh_tokens
+WHERE token_hash = $1
+FOR UPDATE;
+
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW()
 WHERE token_hash = $1;
 
+-- name: ListUserSessions :many
+-- Lists the active refresh tokens of a user, newest first. Tokens are rotated on
+-- every refresh, so created_at is when the session was last used.
+SELECT id, user_agent, ip_address, created_at, expires_at
+FROM refresh_tokens
+WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+ORDER BY created_at DESC;
+
+-- name: RevokeUserRefreshToken :one
+-- Revokes a single active refresh token of a user
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+RETURNING id;
+
 -- name: RevokeAllUserRefreshTokens :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW()
 WHERE user_id = $1 AND revoked_at IS NULL;
 
+-- name: RevokeRefreshTokenFamily :many
+-- Revokes the active tokens of a refresh token family, returning the ones revoked
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL
+RETURNING id;
+
+-- name: RevokeOtherUserRefreshTokens :exec
+-- Revokes every refresh token of a user except one, keeping the current session
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL;
+
 -- name: DeleteExpiredRefreshTokens :exec
 DELETE FROM refresh_tokens
 WHERE expires_at < NOW() OR revoked_at IS NOT NULL;
@@ -7,5 +7,54 @@ RETURNING id, email, name, role, created_at, updated_at;
 SELECT * FROM users WHERE email = $1;
 
 -- name: GetUserByID :one
-SELECT id, email, name, role, created_at, updated_at
+SELECT id, email, name, role, created_at, updated_at, disabled_at, email_verified_at
 FROM users WHERE id = $1;
+
+-- name: GetUserPasswordHash :one
+-- Returns the password hash of a user, for confirming the current password
+SELECT password_hash FROM users WHERE id = $1;
+
+-- name: UpdateUserProfile :one
+-- Updates the name and email of a user, keeping fields given as NULL.
+-- A changed email has to be verified again.
+UPDATE users SET
+    name = COALESCE(sqlc.narg(name), name),
+    email = COALESCE(sqlc.narg(email), email),
+    email_verified_at = CASE WHEN sqlc.narg(email) IS NOT NULL AND sqlc.narg(email) <> email THEN NULL ELSE email_verified_at END,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING id, email, name, role, created_at, updated_at, disabled_at, email_verified_at;
+
+-- name: UpdateUserRole :one
+-- Changes the role of a user (admin user management)
+UPDATE users SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, name, role, created_at, updated_at, disabled_at, email_verified_at;
+
+-- name: PromoteUsersToAdmin :many
+-- Makes the verified users with the given lowercase emails admins, returning the emails
+-- that changed (ADMIN_EMAILS)
+UPDATE users SET role = 'admin', updated_at = NOW()
+WHERE LOWER(email) = ANY(sqlc.arg(emails)::text[])
+    AND email_verified_at IS NOT NULL
+    AND role IS DISTINCT FROM 'admin'
+RETURNING email;
+
+-- name: SetUserDisabled :one
+-- Disables a user, keeping the original time when already disabled, or enables them again
+UPDATE users SET
+    disabled_at = CASE WHEN sqlc.arg(disabled)::boolean THEN COALESCE(disabled_at, NOW()) END,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING id, email, name, role, created_at, updated_at, disabled_at, email_verified_at;
+
+-- name: UpdateUserPassword :exec
+-- Replaces the password hash of a user
+UPDATE users SET password_hash = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: VerifyUserEmail :one
+-- Marks the email of a user as verified, only while it is still the given email
+UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1 AND email = $2
+RETURNING id, email, name, role, created_at, updated_at, disabled_at, email_verified_at;
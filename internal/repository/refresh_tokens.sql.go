@@ -14,13 +14,14 @@ import (
 )
 
 const createRefreshToken = `-- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (user_id, token_hash, expires_at, user_agent, ip_address)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, user_id, token_hash, expires_at, created_at, user_agent, ip_address
+INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, user_agent, ip_address)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, user_id, token_hash, expires_at, created_at, user_agent, ip_address, family_id
 `
 
 type CreateRefreshTokenParams struct {
 	UserID    uuid.UUID      `json:"user_id"`
+	FamilyID  uuid.UUID      `json:"family_id"`
 	TokenHash string         `json:"token_hash"`
 	ExpiresAt time.Time      `json:"expires_at"`
 	UserAgent sql.NullString `json:"user_agent"`
@@ -35,11 +36,13 @@ type CreateRefreshTokenRow struct {
 	CreatedAt sql.NullTime   `json:"created_at"`
 	UserAgent sql.NullString `json:"user_agent"`
 	IpAddress sql.NullString `json:"ip_address"`
+	FamilyID  uuid.UUID      `json:"family_id"`
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (CreateRefreshTokenRow, error) {
 	row := q.db.QueryRowContext(ctx, createRefreshToken,
 		arg.UserID,
+		arg.FamilyID,
 		arg.TokenHash,
 		arg.ExpiresAt,
 		arg.UserAgent,
@@ -54,6 +57,7 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		&i.CreatedAt,
 		&i.UserAgent,
 		&i.IpAddress,
+		&i.FamilyID,
 	)
 	return i, err
 }
@@ -69,7 +73,7 @@ func (q *Queries) DeleteExpiredRefreshTokens(ctx context.Context) error {
 }
 
 const getRefreshTokenByHash = `-- name: GetRefreshTokenByHash :one
-SELECT id, user_id, token_hash, expires_at, revoked_at, created_at, user_agent, ip_address
+SELECT id, user_id, token_hash, expires_at, revoked_at, created_at, user_agent, ip_address, family_id
 FROM refresh_tokens
 WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
 `
@@ -86,10 +90,82 @@ func (q *Queries) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (
 		&i.CreatedAt,
 		&i.UserAgent,
 		&i.IpAddress,
+		&i.FamilyID,
 	)
 	return i, err
 }
 
+const getRefreshTokenForRotation = `-- name: GetRefreshTokenForRotation :one
+SELECT id, user_id, token_hash, expires_at, revoked_at, created_at, user_agent, ip_address, family_id
+FROM refresh_tokens
+WHERE token_hash = $1
+FOR UPDATE
+`
+
+// Locks a refresh token for rotation, including revoked and expired ones so replays can be detected
+func (q *Queries) GetRefreshTokenForRotation(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshTokenForRotation, tokenHash)
+	var i RefreshToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.FamilyID,
+	)
+	return i, err
+}
+
+const listUserSessions = `-- name: ListUserSessions :many
+SELECT id, user_agent, ip_address, created_at, expires_at
+FROM refresh_tokens
+WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+ORDER BY created_at DESC
+`
+
+type ListUserSessionsRow struct {
+	ID        uuid.UUID      `json:"id"`
+	UserAgent sql.NullString `json:"user_agent"`
+	IpAddress sql.NullString `json:"ip_address"`
+	CreatedAt sql.NullTime   `json:"created_at"`
+	ExpiresAt time.Time      `json:"expires_at"`
+}
+
+// Lists the active refresh tokens of a user, newest first. Tokens are rotated on
+// every refresh, so created_at is when the session was last used.
+func (q *Queries) ListUserSessions(ctx context.Context, userID uuid.UUID) ([]ListUserSessionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUserSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUserSessionsRow{}
+	for rows.Next() {
+		var i ListUserSessionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const revokeAllUserRefreshTokens = `-- name: RevokeAllUserRefreshTokens :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW()
@@ -101,6 +177,23 @@ func (q *Queries) RevokeAllUserRefreshTokens(ctx context.Context, userID uuid.UU
 	return err
 }
 
+const revokeOtherUserRefreshTokens = `-- name: RevokeOtherUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+`
+
+type RevokeOtherUserRefreshTokensParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	ID     uuid.UUID `json:"id"`
+}
+
+// Revokes every refresh token of a user except one, keeping the current session
+func (q *Queries) RevokeOtherUserRefreshTokens(ctx context.Context, arg RevokeOtherUserRefreshTokensParams) error {
+	_, err := q.db.ExecContext(ctx, revokeOtherUserRefreshTokens, arg.UserID, arg.ID)
+	return err
+}
+
 const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW()
@@ -111,3 +204,54 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, tokenHash string) erro
 	_, err := q.db.ExecContext(ctx, revokeRefreshToken, tokenHash)
 	return err
 }
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :many
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL
+RETURNING id
+`
+
+// Revokes the active tokens of a refresh token family, returning the ones revoked
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, revokeRefreshTokenFamily, familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeUserRefreshToken = `-- name: RevokeUserRefreshToken :one
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+RETURNING id
+`
+
+type RevokeUserRefreshTokenParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// Revokes a single active refresh token of a user
+func (q *Queries) RevokeUserRefreshToken(ctx context.Context, arg RevokeUserRefreshTokenParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, revokeUserRefreshToken, arg.ID, arg.UserID)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
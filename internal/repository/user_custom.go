@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/Sapuran-Berperan/bamboo-mapper-backend/internal/model"
+)
+
+// ListUsersPaginatedResult contains the paginated users and total count
+type ListUsersPaginatedResult struct {
+	Users      []GetUserByIDRow
+	TotalCount int64
+}
+
+// userColumns lists the user columns in the order ListUsersPaginated reads them, without the password hash
+var userColumns = []string{"id", "email", "name", "role", "created_at", "updated_at", "disabled_at", "email_verified_at"}
+
+// ListUsersPaginated retrieves users with pagination, sorting, search, and filters
+func (q *Queries) ListUsersPaginated(ctx context.Context, params model.ListUsersParams) (*ListUsersPaginatedResult, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	conditions := userFilterConditions(params)
+
+	// Get total count first
+	countQuery := psql.Select("COUNT(*)").From("users")
+	if len(conditions) > 0 {
+		countQuery = countQuery.Where(conditions)
+	}
+
+	countSQL, countArgs, err := countQuery.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build count query: %w", err)
+	}
+
+	var totalCount int64
+	if err := q.db.QueryRowContext(ctx, countSQL, countArgs...).Scan(&totalCount); err != nil {
+		return nil, fmt.Errorf("failed to execute count query: %w", err)
+	}
+
+	if totalCount == 0 {
+		return &ListUsersPaginatedResult{Users: []GetUserByIDRow{}, TotalCount: 0}, nil
+	}
+
+	selectQuery := psql.Select(userColumns...).From("users")
+	if len(conditions) > 0 {
+		selectQuery = selectQuery.Where(conditions)
+	}
+
+	// Sort by id last, so pages are stable when the sort column has duplicates
+	selectQuery = selectQuery.OrderBy(userOrderBy(params), "id")
+
+	offset := (params.Page - 1) * params.PerPage
+	selectQuery = selectQuery.Limit(uint64(params.PerPage)).Offset(uint64(offset))
+
+	selectSQL, selectArgs, err := selectQuery.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	rows, err := q.db.QueryContext(ctx, selectSQL, selectArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute select query: %w", err)
+	}
+	defer rows.Close()
+
+	users := []GetUserByIDRow{}
+	for rows.Next() {
+		var u GetUserByIDRow
+		if err := rows.Scan(&u.ID, &u.Email, &u.Name, &u.Role, &u.CreatedAt, &u.UpdatedAt, &u.DisabledAt, &u.EmailVerifiedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user row: %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user rows: %w", err)
+	}
+
+	return &ListUsersPaginatedResult{Users: users, TotalCount: totalCount}, nil
+}
+
+// userFilterConditions builds the WHERE conditions for the search and filters
+func userFilterConditions(params model.ListUsersParams) sq.And {
+	conditions := sq.And{}
+
+	if params.Search != "" {
+		searchPattern := "%" + params.Search + "%"
+		conditions = append(conditions, sq.Or{
+			sq.ILike{"email": searchPattern},
+			sq.ILike{"name": searchPattern},
+		})
+	}
+
+	if params.Role != "" {
+		conditions = append(conditions, sq.Eq{"role": params.Role})
+	}
+
+	switch params.Status {
+	case model.UserStatusActive:
+		conditions = append(conditions, sq.Eq{"disabled_at": nil})
+	case model.UserStatusDisabled:
+		conditions = append(conditions, sq.NotEq{"disabled_at": nil})
+	}
+
+	return conditions
+}
+
+// userOrderBy returns the ORDER BY clause for the requested sort
+func userOrderBy(params model.ListUsersParams) string {
+	orderColumn := params.SortBy
+	switch orderColumn {
+	case "name", "email", "role", "created_at":
+	default:
+		orderColumn = "created_at"
+	}
+	orderDir := strings.ToUpper(params.SortDir)
+	if orderDir != "ASC" && orderDir != "DESC" {
+		orderDir = "DESC"
+	}
+	return fmt.Sprintf("%s %s", orderColumn, orderDir)
+}
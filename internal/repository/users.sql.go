@@ -10,6 +10,7 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -54,7 +55,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password_hash, name, role, created_at, updated_at FROM users WHERE email = $1
+SELECT id, email, password_hash, name, role, created_at, updated_at, disabled_at, email_verified_at FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -68,22 +69,26 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Role,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, name, role, created_at, updated_at
+SELECT id, email, name, role, created_at, updated_at, disabled_at, email_verified_at
 FROM users WHERE id = $1
 `
 
 type GetUserByIDRow struct {
-	ID        uuid.UUID      `json:"id"`
-	Email     string         `json:"email"`
-	Name      string         `json:"name"`
-	Role      sql.NullString `json:"role"`
-	CreatedAt sql.NullTime   `json:"created_at"`
-	UpdatedAt sql.NullTime   `json:"updated_at"`
+	ID              uuid.UUID      `json:"id"`
+	Email           string         `json:"email"`
+	Name            string         `json:"name"`
+	Role            sql.NullString `json:"role"`
+	CreatedAt       sql.NullTime   `json:"created_at"`
+	UpdatedAt       sql.NullTime   `json:"updated_at"`
+	DisabledAt      sql.NullTime   `json:"disabled_at"`
+	EmailVerifiedAt sql.NullTime   `json:"email_verified_at"`
 }
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (GetUserByIDRow, error) {
@@ -96,6 +101,233 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (GetUserByIDRow
 		&i.Role,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getUserPasswordHash = `-- name: GetUserPasswordHash :one
+SELECT password_hash FROM users WHERE id = $1
+`
+
+// Returns the password hash of a user, for confirming the current password
+func (q *Queries) GetUserPasswordHash(ctx context.Context, id uuid.UUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, getUserPasswordHash, id)
+	var password_hash string
+	err := row.Scan(&password_hash)
+	return password_hash, err
+}
+
+const promoteUsersToAdmin = `-- name: PromoteUsersToAdmin :many
+UPDATE users SET role = 'admin', updated_at = NOW()
+WHERE LOWER(email) = ANY($1::text[])
+    AND email_verified_at IS NOT NULL
+    AND role IS DISTINCT FROM 'admin'
+RETURNING email
+`
+
+// Makes the verified users with the given lowercase emails admins, returning the emails
+// that changed (ADMIN_EMAILS)
+func (q *Queries) PromoteUsersToAdmin(ctx context.Context, emails []string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, promoteUsersToAdmin, pq.Array(emails))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		items = append(items, email)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setUserDisabled = `-- name: SetUserDisabled :one
+UPDATE users SET
+    disabled_at = CASE WHEN $1::boolean THEN COALESCE(disabled_at, NOW()) END,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING id, email, name, role, created_at, updated_at, disabled_at, email_verified_at
+`
+
+type SetUserDisabledParams struct {
+	Disabled bool      `json:"disabled"`
+	ID       uuid.UUID `json:"id"`
+}
+
+type SetUserDisabledRow struct {
+	ID              uuid.UUID      `json:"id"`
+	Email           string         `json:"email"`
+	Name            string         `json:"name"`
+	Role            sql.NullString `json:"role"`
+	CreatedAt       sql.NullTime   `json:"created_at"`
+	UpdatedAt       sql.NullTime   `json:"updated_at"`
+	DisabledAt      sql.NullTime   `json:"disabled_at"`
+	EmailVerifiedAt sql.NullTime   `json:"email_verified_at"`
+}
+
+// Disables a user, keeping the original time when already disabled, or enables them again
+func (q *Queries) SetUserDisabled(ctx context.Context, arg SetUserDisabledParams) (SetUserDisabledRow, error) {
+	row := q.db.QueryRowContext(ctx, setUserDisabled, arg.Disabled, arg.ID)
+	var i SetUserDisabledRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users SET password_hash = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID           uuid.UUID `json:"id"`
+	PasswordHash string    `json:"password_hash"`
+}
+
+// Replaces the password hash of a user
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.PasswordHash)
+	return err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users SET
+    name = COALESCE($1, name),
+    email = COALESCE($2, email),
+    email_verified_at = CASE WHEN $2 IS NOT NULL AND $2 <> email THEN NULL ELSE email_verified_at END,
+    updated_at = NOW()
+WHERE id = $3
+RETURNING id, email, name, role, created_at, updated_at, disabled_at, email_verified_at
+`
+
+type UpdateUserProfileParams struct {
+	Name  sql.NullString `json:"name"`
+	Email sql.NullString `json:"email"`
+	ID    uuid.UUID      `json:"id"`
+}
+
+type UpdateUserProfileRow struct {
+	ID              uuid.UUID      `json:"id"`
+	Email           string         `json:"email"`
+	Name            string         `json:"name"`
+	Role            sql.NullString `json:"role"`
+	CreatedAt       sql.NullTime   `json:"created_at"`
+	UpdatedAt       sql.NullTime   `json:"updated_at"`
+	DisabledAt      sql.NullTime   `json:"disabled_at"`
+	EmailVerifiedAt sql.NullTime   `json:"email_verified_at"`
+}
+
+// Updates the name and email of a user, keeping fields given as NULL.
+// A changed email has to be verified again.
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (UpdateUserProfileRow, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile, arg.Name, arg.Email, arg.ID)
+	var i UpdateUserProfileRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, name, role, created_at, updated_at, disabled_at, email_verified_at
+`
+
+type UpdateUserRoleParams struct {
+	ID   uuid.UUID      `json:"id"`
+	Role sql.NullString `json:"role"`
+}
+
+type UpdateUserRoleRow struct {
+	ID              uuid.UUID      `json:"id"`
+	Email           string         `json:"email"`
+	Name            string         `json:"name"`
+	Role            sql.NullString `json:"role"`
+	CreatedAt       sql.NullTime   `json:"created_at"`
+	UpdatedAt       sql.NullTime   `json:"updated_at"`
+	DisabledAt      sql.NullTime   `json:"disabled_at"`
+	EmailVerifiedAt sql.NullTime   `json:"email_verified_at"`
+}
+
+// Changes the role of a user (admin user management)
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (UpdateUserRoleRow, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.ID, arg.Role)
+	var i UpdateUserRoleRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1 AND email = $2
+RETURNING id, email, name, role, created_at, updated_at, disabled_at, email_verified_at
+`
+
+type VerifyUserEmailParams struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+}
+
+type VerifyUserEmailRow struct {
+	ID              uuid.UUID      `json:"id"`
+	Email           string         `json:"email"`
+	Name            string         `json:"name"`
+	Role            sql.NullString `json:"role"`
+	CreatedAt       sql.NullTime   `json:"created_at"`
+	UpdatedAt       sql.NullTime   `json:"updated_at"`
+	DisabledAt      sql.NullTime   `json:"disabled_at"`
+	EmailVerifiedAt sql.NullTime   `json:"email_verified_at"`
+}
+
+// Marks the email of a user as verified, only while it is still the given email
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (VerifyUserEmailRow, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.ID, arg.Email)
+	var i VerifyUserEmailRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -3,16 +3,48 @@ package storage
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 )
 
+// fileURLPrefix is the prefix of the URLs stored for uploaded files
+// Format: https://drive.google.com/uc?id=FILE_ID
+const fileURLPrefix = "https://drive.google.com/uc?id="
+
+// File describes a file stored in the Drive folder
+type File struct {
+	ID          string
+	Name        string
+	CreatedTime time.Time
+	// Public is set when anyone with the link may read the file, as uploads
+	// before image links went through the API were
+	Public bool
+}
+
+// FileURL returns the stored URL of a Drive file
+func FileURL(fileID string) string {
+	return fileURLPrefix + fileID
+}
+
+// ExtractFileID extracts the file ID from a stored Drive URL, "" when the URL is not a Drive link
+func ExtractFileID(url string) string {
+	if len(url) > len(fileURLPrefix) && strings.HasPrefix(url, fileURLPrefix) {
+		return url[len(fileURLPrefix):]
+	}
+	return ""
+}
+
 // GDriveService handles file uploads to Google Drive
 type GDriveService struct {
 	service  *drive.Service
@@ -96,36 +128,37 @@ func saveToken(path string, token *oauth2.Token) error {
 	return json.NewEncoder(f).Encode(token)
 }
 
-// UploadFile uploads a file to Google Drive and returns the shareable URL
+// UploadFile uploads a file to Google Drive and returns its URL.
+// Files are private to the service account; serve them through OpenFile.
 func (g *GDriveService) UploadFile(file io.Reader, filename, mimeType string) (string, error) {
 	// Create file metadata
 	driveFile := &drive.File{
-		Name:    filename,
-		Parents: []string{g.folderID},
+		Name:     filename,
+		Parents:  []string{g.folderID},
+		MimeType: mimeType,
 	}
 
 	// Upload file
 	createdFile, err := g.service.Files.Create(driveFile).
 		Media(file).
-		Fields("id, webViewLink, webContentLink").
+		Fields("id").
 		Do()
 	if err != nil {
 		return "", fmt.Errorf("failed to upload file: %w", err)
 	}
 
-	// Make file publicly accessible
-	permission := &drive.Permission{
-		Type: "anyone",
-		Role: "reader",
-	}
-	_, err = g.service.Permissions.Create(createdFile.Id, permission).Do()
+	return FileURL(createdFile.Id), nil
+}
+
+// OpenFile downloads a file from Google Drive by its ID.
+// The caller must close the returned reader.
+// Returns: (content, content_type, size, error) - size is -1 when unknown
+func (g *GDriveService) OpenFile(fileID string) (io.ReadCloser, string, int64, error) {
+	resp, err := g.service.Files.Get(fileID).Download()
 	if err != nil {
-		return "", fmt.Errorf("failed to set file permissions: %w", err)
+		return nil, "", 0, fmt.Errorf("failed to download file: %w", err)
 	}
-
-	// Return direct download link
-	// Format: https://drive.google.com/uc?id=FILE_ID
-	return fmt.Sprintf("https://drive.google.com/uc?id=%s", createdFile.Id), nil
+	return resp.Body, resp.Header.Get("Content-Type"), resp.ContentLength, nil
 }
 
 // DeleteFile deletes a file from Google Drive by its ID
@@ -136,3 +169,69 @@ func (g *GDriveService) DeleteFile(fileID string) error {
 	}
 	return nil
 }
+
+// ListFiles returns every file in the configured folder that is not in the trash
+func (g *GDriveService) ListFiles() ([]File, error) {
+	var files []File
+
+	query := fmt.Sprintf("'%s' in parents and trashed = false", g.folderID)
+	err := g.service.Files.List().
+		Q(query).
+		Fields("nextPageToken, files(id, name, createdTime, permissions(type))").
+		PageSize(1000).
+		Pages(context.Background(), func(page *drive.FileList) error {
+			for _, f := range page.Files {
+				created, _ := time.Parse(time.RFC3339, f.CreatedTime)
+				files = append(files, File{ID: f.Id, Name: f.Name, CreatedTime: created, Public: hasAnyonePermission(f.Permissions)})
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	return files, nil
+}
+
+// MakePrivate removes the permissions that let anyone with the link read a file
+func (g *GDriveService) MakePrivate(fileID string) error {
+	list, err := g.service.Permissions.List(fileID).Fields("permissions(id, type)").Do()
+	if err != nil {
+		return fmt.Errorf("failed to list permissions: %w", err)
+	}
+	for _, p := range list.Permissions {
+		if p.Type != anyonePermission {
+			continue
+		}
+		if err := g.service.Permissions.Delete(fileID, p.Id).Do(); err != nil {
+			return fmt.Errorf("failed to delete permission: %w", err)
+		}
+	}
+	return nil
+}
+
+// anyonePermission is the Drive permission type granting access to anyone with the link
+const anyonePermission = "anyone"
+
+// hasAnyonePermission reports whether the permissions include one for anyone with the link
+func hasAnyonePermission(permissions []*drive.Permission) bool {
+	for _, p := range permissions {
+		if p.Type == anyonePermission {
+			return true
+		}
+	}
+	return false
+}
+
+// FileExists reports whether a file exists and is not in the trash
+func (g *GDriveService) FileExists(fileID string) (bool, error) {
+	f, err := g.service.Files.Get(fileID).Fields("id, trashed").Do()
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get file: %w", err)
+	}
+	return !f.Trashed, nil
+}
@@ -0,0 +1,15 @@
+package storage
+
+import "io"
+
+// Storage is a backend that stores uploaded files
+type Storage interface {
+	// UploadFile stores a file and returns its URL
+	UploadFile(file io.Reader, filename, mimeType string) (string, error)
+	// OpenFile returns the content, content type and size (-1 when unknown) of a stored file
+	OpenFile(fileID string) (io.ReadCloser, string, int64, error)
+	// DeleteFile removes a stored file
+	DeleteFile(fileID string) error
+}
+
+var _ Storage = (*GDriveService)(nil)
@@ -0,0 +1,168 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Client platforms derived from a User-Agent
+const (
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+	PlatformOther   = "other"
+)
+
+// platformMarkers are User-Agent fragments identifying a platform, checked in order.
+// Android and iOS come first because their User-Agents also mention Linux and Mac OS.
+var platformMarkers = []struct {
+	fragment string
+	platform string
+}{
+	{"android", PlatformAndroid},
+	{"iphone", PlatformIOS},
+	{"ipad", PlatformIOS},
+	{"ipod", PlatformIOS},
+	{"darwin", PlatformIOS},
+	{"windows", PlatformWindows},
+	{"macintosh", PlatformMacOS},
+	{"mac os x", PlatformMacOS},
+	{"linux", PlatformLinux},
+}
+
+// botFragments identify crawlers and link preview fetchers
+var botFragments = []string{
+	"bot", "crawler", "spider", "slurp", "facebookexternalhit", "whatsapp",
+	"telegram", "preview", "curl/", "wget/", "python-requests", "headless",
+}
+
+// ClientPlatform returns the coarse platform of a User-Agent
+func ClientPlatform(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	for _, m := range platformMarkers {
+		if strings.Contains(ua, m.fragment) {
+			return m.platform
+		}
+	}
+	return PlatformOther
+}
+
+// IsBot reports whether a User-Agent belongs to a crawler or link preview fetcher
+func IsBot(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+	for _, fragment := range botFragments {
+		if strings.Contains(ua, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// HashIP returns a keyed SHA-256 hash of an IP address. The key keeps the
+// hash from being reversed by hashing every possible IPv4 address.
+func HashIP(key []byte, ip string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// TrustedProxies are the reverse proxies whose forwarding headers are believed
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies parses IP addresses and CIDR ranges of trusted proxies
+func ParseTrustedProxies(list []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(list))
+	for _, item := range list {
+		if strings.Contains(item, "/") {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", item, err)
+			}
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", item, err)
+		}
+		addr = addr.Unmap()
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return proxies, nil
+}
+
+// Contains reports whether an address belongs to a trusted proxy
+func (p TrustedProxies) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveClientIP returns the address of the client that sent a request. Forwarding
+// headers are only read when the request came from a trusted proxy. Every proxy appends
+// the address it received from, so the right-most untrusted X-Forwarded-For entry is the
+// client; the entries before it are whatever the client sent and may be forged.
+func (p TrustedProxies) ResolveClientIP(r *http.Request) string {
+	remote, ok := parseIP(ClientIP(r))
+	if !ok || !p.Contains(remote) {
+		return ClientIP(r)
+	}
+
+	var forwarded []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(value, ",")...)
+	}
+	if len(forwarded) == 0 {
+		if realIP, ok := parseIP(r.Header.Get("X-Real-IP")); ok {
+			return realIP.String()
+		}
+		return remote.String()
+	}
+
+	client := remote
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr, ok := parseIP(forwarded[i])
+		if !ok {
+			// A malformed entry ends the chain we can vouch for
+			break
+		}
+		client = addr
+		if !p.Contains(addr) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// ClientIP returns the IP address of the request's RemoteAddr without the port. Behind
+// proxies it is the client's only once middleware.RealIP has resolved it.
+func ClientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// parseIP parses an address as found in forwarding headers, with or without a port
+func parseIP(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return addr.Unmap(), true
+	}
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	return netip.Addr{}, false
+}
@@ -0,0 +1,134 @@
+package util
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientPlatform(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		expected  string
+	}{
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36", PlatformAndroid},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148", PlatformIOS},
+		{"BambooMapper/1.4 CFNetwork/1494 Darwin/23.4.0", PlatformIOS},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0", PlatformWindows},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 Safari/605.1.15", PlatformMacOS},
+		{"Mozilla/5.0 (X11; Linux x86_64) Gecko/20100101 Firefox/121.0", PlatformLinux},
+		{"Dart/3.2 (dart:io)", PlatformOther},
+		{"", PlatformOther},
+	}
+
+	for _, tt := range tests {
+		if got := ClientPlatform(tt.userAgent); got != tt.expected {
+			t.Errorf("ClientPlatform(%q) = %s, want %s", tt.userAgent, got, tt.expected)
+		}
+	}
+}
+
+func TestIsBot(t *testing.T) {
+	bots := []string{
+		"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+		"WhatsApp/2.23.20.0",
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		"curl/8.4.0",
+	}
+	for _, ua := range bots {
+		if !IsBot(ua) {
+			t.Errorf("expected %q to be a bot", ua)
+		}
+	}
+
+	if IsBot("Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36") {
+		t.Error("expected a phone browser not to be a bot")
+	}
+}
+
+func TestHashIP(t *testing.T) {
+	key := []byte("secret")
+
+	hash := HashIP(key, "203.0.113.7")
+	if len(hash) != 64 {
+		t.Fatalf("expected a 64 character hex hash, got %q", hash)
+	}
+	if hash != HashIP(key, "203.0.113.7") {
+		t.Error("expected the same IP to hash the same")
+	}
+	if hash == HashIP(key, "203.0.113.8") {
+		t.Error("expected different IPs to hash differently")
+	}
+	if hash == HashIP([]byte("other"), "203.0.113.7") {
+		t.Error("expected the key to change the hash")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	req.Header.Set("X-Real-IP", "203.0.113.8")
+	if got := ClientIP(req); got != "192.0.2.1" {
+		t.Errorf("expected forwarding headers to be ignored, got %s", got)
+	}
+
+	req.RemoteAddr = "[2001:db8::1]:443"
+	if got := ClientIP(req); got != "2001:db8::1" {
+		t.Errorf("expected IPv6 address without port, got %s", got)
+	}
+	req.RemoteAddr = "203.0.113.9"
+	if got := ClientIP(req); got != "203.0.113.9" {
+		t.Errorf("expected address without port to be kept, got %s", got)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	if _, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/32"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, invalid := range []string{"10.0.0.0/33", "proxy.local", ""} {
+		if _, err := ParseTrustedProxies([]string{invalid}); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestTrustedProxies_ResolveClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"192.0.2.0/24", "10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{"untrusted remote ignores headers", "198.51.100.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7", "X-Real-IP": "203.0.113.8"}, "198.51.100.1"},
+		{"trusted remote without headers", "192.0.2.1:1234", nil, "192.0.2.1"},
+		{"single forwarded address", "192.0.2.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"}, "203.0.113.7"},
+		{"spoofed entries before the client", "192.0.2.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1, 2.2.2.2, 203.0.113.7"}, "203.0.113.7"},
+		{"trusted hops are skipped", "192.0.2.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1, 203.0.113.7, 10.0.0.5"}, "203.0.113.7"},
+		{"only trusted hops", "192.0.2.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.6, 10.0.0.5"}, "10.0.0.6"},
+		{"malformed entry stops the walk", "192.0.2.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7, garbage, 10.0.0.5"}, "10.0.0.5"},
+		{"real ip from trusted remote", "192.0.2.1:1234", map[string]string{"X-Real-IP": "203.0.113.8"}, "203.0.113.8"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tt.remoteAddr
+		for key, value := range tt.headers {
+			req.Header.Set(key, value)
+		}
+		if got := proxies.ResolveClientIP(req); got != tt.expected {
+			t.Errorf("%s: ResolveClientIP() = %s, want %s", tt.name, got, tt.expected)
+		}
+	}
+
+	// Without trusted proxies the headers never count
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	if got := TrustedProxies(nil).ResolveClientIP(req); got != "192.0.2.1" {
+		t.Errorf("expected RemoteAddr without trusted proxies, got %s", got)
+	}
+}
@@ -0,0 +1,31 @@
+package util
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceMeters(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat1     float64
+		lng1     float64
+		lat2     float64
+		lng2     float64
+		expected float64
+	}{
+		{"same point", -7.4245, 110.0107, -7.4245, 110.0107, 0},
+		{"one degree of latitude", 0, 110, 1, 110, 111195},
+		{"yogyakarta to magelang", -7.797068, 110.370529, -7.470475, 110.217686, 39854},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DistanceMeters(tt.lat1, tt.lng1, tt.lat2, tt.lng2)
+			// Allow 0.5% tolerance for the spherical approximation
+			if math.Abs(got-tt.expected) > tt.expected*0.005+1 {
+				t.Errorf("expected ~%.0fm, got %.0fm", tt.expected, got)
+			}
+		})
+	}
+}
@@ -3,24 +3,107 @@ package util
 import (
 	"crypto/rand"
 	"math/big"
+	"strings"
 )
 
+// Short code lengths, including the check character. Codes of every length in the
+// range stay valid, so the length can grow with the number of markers.
 const (
-	// shortCodeLength is the total length of the short code
-	shortCodeLength = 8
-	// charset for short code characters
-	charset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	DefaultShortCodeLength = 8
+	MinShortCodeLength     = 8
+	MaxShortCodeLength     = 16
 )
 
-// GenerateShortCode generates a unique 8-character alphanumeric short code
-// Example: "1A2B3C4D"
-func GenerateShortCode() string {
-	result := make([]byte, shortCodeLength)
+const (
+	// charset is Crockford's base32 alphabet, without I, L, O and U which are easily misread
+	charset = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// confusables maps characters people type by mistake to the character they meant
+var confusables = strings.NewReplacer("O", "0", "I", "1", "L", "1", "-", "", " ", "")
 
-	for i := 0; i < shortCodeLength; i++ {
+// GenerateShortCode generates a random short code of the given length: random characters
+// followed by a check character, so a single mistyped character is detected.
+// Lengths outside MinShortCodeLength..MaxShortCodeLength are clamped to that range.
+// Example: "1A2B3C4X"
+func GenerateShortCode(length int) string {
+	length = max(MinShortCodeLength, min(length, MaxShortCodeLength))
+	result := make([]byte, length)
+
+	for i := 0; i < length-1; i++ {
 		num, _ := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
 		result[i] = charset[num.Int64()]
 	}
+	result[length-1] = checkCharacter(string(result[:length-1]))
 
 	return string(result)
 }
+
+// NormalizeShortCode converts typed input to the canonical form of a short code:
+// upper case, without hyphens or spaces, with O read as 0 and I or L read as 1
+func NormalizeShortCode(s string) string {
+	return confusables.Replace(strings.ToUpper(strings.TrimSpace(s)))
+}
+
+// ValidShortCode reports whether s is a well-formed short code with a matching check character.
+// Codes issued before check characters were introduced are not valid by this rule.
+func ValidShortCode(s string) bool {
+	if !shortCodeShape(s) {
+		return false
+	}
+	return checkCharacter(s[:len(s)-1]) == s[len(s)-1]
+}
+
+// MistypedShortCode reports whether s, once normalized, has the shape of a current short code
+// but a check character that doesn't match, which points to a typo rather than an unknown code
+func MistypedShortCode(s string) bool {
+	s = NormalizeShortCode(s)
+	return shortCodeShape(s) && !ValidShortCode(s)
+}
+
+// shortCodeShape reports whether s has a short code length and only characters of charset
+func shortCodeShape(s string) bool {
+	if len(s) < MinShortCodeLength || len(s) > MaxShortCodeLength {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(charset, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ShortCodeCandidates returns the codes to look up for typed input, most literal first:
+// the input as given, upper cased without separators (for codes issued before the
+// Crockford alphabet, which may contain O, I, L or U), then fully normalized
+func ShortCodeCandidates(s string) []string {
+	s = strings.TrimSpace(s)
+	legacy := strings.NewReplacer("-", "", " ", "").Replace(strings.ToUpper(s))
+
+	candidates := []string{s}
+	for _, c := range []string{legacy, NormalizeShortCode(s)} {
+		if c != candidates[len(candidates)-1] && c != s {
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
+
+// checkCharacter computes the Luhn mod 32 check character of a code in charset.
+// It catches every single substitution and every adjacent transposition except 0 and Z.
+func checkCharacter(code string) byte {
+	const n = len(charset)
+	factor := 2
+	sum := 0
+	for i := len(code) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(charset, code[i])
+		sum += addend/n + addend%n
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+	}
+	return charset[(n-sum%n)%n]
+}
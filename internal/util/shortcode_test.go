@@ -0,0 +1,129 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateShortCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code := GenerateShortCode(DefaultShortCodeLength)
+		if len(code) != 8 {
+			t.Fatalf("expected 8 characters, got %q", code)
+		}
+		if strings.ContainsAny(code, "ILOU") {
+			t.Fatalf("expected no ambiguous characters, got %q", code)
+		}
+		if !ValidShortCode(code) {
+			t.Fatalf("expected a valid check character, got %q", code)
+		}
+	}
+}
+
+func TestGenerateShortCode_Length(t *testing.T) {
+	tests := []struct {
+		length   int
+		expected int
+	}{
+		{length: 8, expected: 8},
+		{length: 12, expected: 12},
+		{length: 16, expected: 16},
+		{length: 4, expected: MinShortCodeLength},
+		{length: 40, expected: MaxShortCodeLength},
+	}
+
+	for _, tt := range tests {
+		code := GenerateShortCode(tt.length)
+		if len(code) != tt.expected {
+			t.Errorf("GenerateShortCode(%d) returned %q, want %d characters", tt.length, code, tt.expected)
+		}
+		if !ValidShortCode(code) {
+			t.Errorf("expected a valid check character, got %q", code)
+		}
+	}
+}
+
+func TestValidShortCode_DetectsTypos(t *testing.T) {
+	code := GenerateShortCode(DefaultShortCodeLength)
+
+	// Every single-character substitution is caught
+	for i := 0; i < len(code); i++ {
+		for j := 0; j < len(charset); j++ {
+			if charset[j] == code[i] {
+				continue
+			}
+			typo := code[:i] + string(charset[j]) + code[i+1:]
+			if ValidShortCode(typo) {
+				t.Errorf("substitution %q of %q not detected", typo, code)
+			}
+		}
+	}
+
+	// Every adjacent transposition of different characters is caught, except 0 and Z,
+	// the one pair Luhn mod 32 can't tell apart
+	for i := 0; i < len(code)-1; i++ {
+		if pair := code[i : i+2]; code[i] == code[i+1] || pair == "0Z" || pair == "Z0" {
+			continue
+		}
+		typo := code[:i] + string(code[i+1]) + string(code[i]) + code[i+2:]
+		if ValidShortCode(typo) {
+			t.Errorf("transposition %q of %q not detected", typo, code)
+		}
+	}
+}
+
+func TestNormalizeShortCode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1a2b3c4x", "1A2B3C4X"},
+		{"1A2B-3C4X", "1A2B3C4X"},
+		{" 1a2b 3c4x ", "1A2B3C4X"},
+		{"OI2L", "0121"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeShortCode(tt.input); got != tt.expected {
+			t.Errorf("NormalizeShortCode(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestMistypedShortCode(t *testing.T) {
+	code := GenerateShortCode(DefaultShortCodeLength)
+	typo := code[:7] + string(charset[(strings.IndexByte(charset, code[7])+1)%len(charset)])
+
+	if MistypedShortCode(code) || MistypedShortCode(strings.ToLower(code)) {
+		t.Errorf("expected %q to be well-formed", code)
+	}
+	if !MistypedShortCode(typo) {
+		t.Errorf("expected %q to be reported as mistyped", typo)
+	}
+	// Legacy codes and other shapes are unknown rather than mistyped
+	for _, s := range []string{"TEST001", "HOUSE123", "ABC"} {
+		if MistypedShortCode(s) {
+			t.Errorf("expected %q not to be reported as mistyped", s)
+		}
+	}
+}
+
+func TestShortCodeCandidates(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"TEST001", []string{"TEST001"}},
+		{"test001", []string{"test001", "TEST001"}},
+		{"ab-cd-ef", []string{"ab-cd-ef", "ABCDEF"}},
+		// A legacy code containing O is tried before reading O as zero
+		{"hous-e123", []string{"hous-e123", "HOUSE123", "H0USE123"}},
+	}
+
+	for _, tt := range tests {
+		got := ShortCodeCandidates(tt.input)
+		if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("ShortCodeCandidates(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+}
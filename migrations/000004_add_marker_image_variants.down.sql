@@ -0,0 +1,3 @@
+-- Remove resized image variant URLs from markers
+ALTER TABLE markers DROP COLUMN IF EXISTS image_medium_url;
+ALTER TABLE markers DROP COLUMN IF EXISTS image_thumbnail_url;
@@ -0,0 +1,3 @@
+-- Add resized image variant URLs to markers
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS image_thumbnail_url TEXT;
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS image_medium_url TEXT;
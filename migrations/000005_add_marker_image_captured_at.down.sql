@@ -0,0 +1,2 @@
+-- Remove photo capture time from markers
+ALTER TABLE markers DROP COLUMN IF EXISTS image_captured_at;
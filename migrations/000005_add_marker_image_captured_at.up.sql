@@ -0,0 +1,2 @@
+-- Store when the marker photo was taken (from EXIF)
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS image_captured_at TIMESTAMPTZ;
@@ -0,0 +1,3 @@
+-- Remove background image processing state from markers
+ALTER TABLE markers DROP COLUMN IF EXISTS image_job_id;
+ALTER TABLE markers DROP COLUMN IF EXISTS image_status;
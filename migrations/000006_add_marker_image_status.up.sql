@@ -0,0 +1,8 @@
+-- Track background image processing: NULL means no image was uploaded
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS image_status VARCHAR(20)
+    CHECK (image_status IN ('pending', 'ready', 'failed'));
+
+-- Identifies the latest queued image job, so an older job never overwrites a newer upload
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS image_job_id UUID;
+
+UPDATE markers SET image_status = 'ready' WHERE image_url IS NOT NULL;
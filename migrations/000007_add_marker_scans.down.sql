@@ -0,0 +1 @@
+DROP TABLE IF EXISTS marker_scans;
@@ -0,0 +1,18 @@
+-- Record every public lookup of a marker by its short code (QR scans)
+CREATE TABLE IF NOT EXISTS marker_scans (
+    id BIGSERIAL PRIMARY KEY,
+    marker_id UUID NOT NULL REFERENCES markers(id) ON DELETE CASCADE,
+    scanned_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- 'app' for API lookups, 'web' for the marker page opened without the app
+    source VARCHAR(10) NOT NULL CHECK (source IN ('app', 'web')),
+    -- android, ios, windows, macos, linux or other, derived from the User-Agent
+    platform VARCHAR(10) NOT NULL,
+    -- Keyed hash of the client IP, never the address itself
+    ip_hash VARCHAR(64),
+    -- Optional location reported by the scanning client
+    latitude DECIMAL(10, 8),
+    longitude DECIMAL(11, 8)
+);
+
+CREATE INDEX IF NOT EXISTS idx_marker_scans_marker_id_scanned_at ON marker_scans(marker_id, scanned_at);
+CREATE INDEX IF NOT EXISTS idx_marker_scans_scanned_at ON marker_scans(scanned_at);
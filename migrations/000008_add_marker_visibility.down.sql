@@ -0,0 +1,2 @@
+-- Remove marker visibility
+ALTER TABLE markers DROP COLUMN IF EXISTS visibility;
@@ -0,0 +1,4 @@
+-- Controls what unauthenticated viewers of a marker (QR scans, marker pages) can see:
+-- 'public' shows everything, 'contact_hidden' hides the owner, 'private' hides the marker
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS visibility VARCHAR(20) NOT NULL DEFAULT 'contact_hidden'
+    CHECK (visibility IN ('public', 'contact_hidden', 'private'));
@@ -0,0 +1,2 @@
+-- Remove retired short codes
+DROP TABLE IF EXISTS marker_short_code_aliases;
@@ -0,0 +1,11 @@
+-- Short codes retired by rotation, e.g. after a sign was stolen or reprinted.
+-- Retired codes are never reissued; lookups either redirect to the current code or are gone.
+CREATE TABLE IF NOT EXISTS marker_short_code_aliases (
+    short_code VARCHAR(8) PRIMARY KEY,
+    marker_id UUID NOT NULL REFERENCES markers(id) ON DELETE CASCADE,
+    -- TRUE redirects lookups to the current code, FALSE answers 410 Gone
+    redirect BOOLEAN NOT NULL DEFAULT FALSE,
+    retired_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_marker_short_code_aliases_marker_id ON marker_short_code_aliases(marker_id);
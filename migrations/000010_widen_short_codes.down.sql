@@ -0,0 +1,3 @@
+-- Restore 8 character short codes; fails while longer codes exist
+ALTER TABLE marker_short_code_aliases ALTER COLUMN short_code TYPE VARCHAR(8);
+ALTER TABLE markers ALTER COLUMN short_code TYPE VARCHAR(8);
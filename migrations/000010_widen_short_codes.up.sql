@@ -0,0 +1,3 @@
+-- Allow longer short codes, so SHORT_CODE_LENGTH can grow with the number of markers
+ALTER TABLE markers ALTER COLUMN short_code TYPE VARCHAR(16);
+ALTER TABLE marker_short_code_aliases ALTER COLUMN short_code TYPE VARCHAR(16);
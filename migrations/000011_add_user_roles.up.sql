@@ -0,0 +1,7 @@
+-- Roles: admin manages users, editor edits every marker, surveyor edits own markers, viewer reads.
+-- Every existing user had the role 'user', which could create and edit markers.
+UPDATE users SET role = 'surveyor' WHERE role IS NULL OR role = 'user';
+
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'surveyor';
+ALTER TABLE users ADD CONSTRAINT users_role_check
+    CHECK (role IN ('admin', 'editor', 'surveyor', 'viewer'));
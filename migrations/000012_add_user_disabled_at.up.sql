@@ -0,0 +1,2 @@
+-- Disabled accounts can't log in or refresh tokens; NULL means the account is active
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;